@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_unique_recurring_occurrence;
+ALTER TABLE transactions DROP COLUMN IF EXISTS recurring_id;
+DROP TABLE IF EXISTS recurring_transactions CASCADE;
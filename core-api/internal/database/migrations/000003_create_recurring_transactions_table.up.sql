@@ -0,0 +1,37 @@
+DROP TABLE IF EXISTS recurring_transactions CASCADE;
+CREATE TABLE recurring_transactions
+(
+    id              SERIAL PRIMARY KEY,
+    owner_id        text        NOT NULL,
+    title           TEXT        NOT NULL,
+    price           DECIMAL     NOT NULL,
+    category_id     INT,
+    type            VARCHAR(10) NOT NULL,
+    frequency       VARCHAR(10) NOT NULL,
+    repeat_interval INT         NOT NULL DEFAULT 1,
+    start_date      TIMESTAMPTZ NOT NULL,
+    end_date        TIMESTAMPTZ,
+    max_occurrences INT,
+    occurrences     INT         NOT NULL DEFAULT 0,
+    next_run        TIMESTAMPTZ,
+
+    FOREIGN KEY (owner_id) REFERENCES users (id),
+    FOREIGN KEY (category_id) REFERENCES categories (id),
+    CHECK (type in ('Expense', 'Income')),
+    CHECK (frequency in ('Daily', 'Weekly', 'Monthly', 'Yearly')),
+    CHECK (repeat_interval > 0),
+    CHECK (max_occurrences IS NULL OR max_occurrences > 0)
+);
+
+CREATE INDEX idx_recurring_transactions_next_run
+    ON recurring_transactions (next_run)
+    WHERE next_run IS NOT NULL;
+
+ALTER TABLE transactions
+    ADD COLUMN recurring_id INT REFERENCES recurring_transactions (id) ON DELETE SET NULL;
+
+-- a rule can only materialize one transaction per occurrence, which is what
+-- keeps the scheduler from creating duplicates when it catches up after downtime
+CREATE UNIQUE INDEX idx_unique_recurring_occurrence
+    ON transactions (recurring_id, date_made)
+    WHERE recurring_id IS NOT NULL;
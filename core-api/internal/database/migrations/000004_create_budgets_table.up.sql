@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS budgets CASCADE;
+CREATE TABLE budgets
+(
+    id          SERIAL PRIMARY KEY,
+    owner_id    text        NOT NULL,
+    category_id INT         NOT NULL,
+    amount      DECIMAL     NOT NULL,
+    period      VARCHAR(10) NOT NULL DEFAULT 'Monthly',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (owner_id) REFERENCES users (id),
+    FOREIGN KEY (category_id) REFERENCES categories (id),
+    UNIQUE (owner_id, category_id, period),
+    CHECK (amount > 0),
+    CHECK (period in ('Daily', 'Weekly', 'Monthly', 'Yearly'))
+);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_categories_owner_id;
+ALTER TABLE categories
+    DROP CONSTRAINT IF EXISTS category_is_not_its_own_parent,
+    DROP COLUMN IF EXISTS archived,
+    DROP COLUMN IF EXISTS icon,
+    DROP COLUMN IF EXISTS color,
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS owner_id;
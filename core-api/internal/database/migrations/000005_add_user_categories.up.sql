@@ -0,0 +1,12 @@
+-- categories without an owner are the global defaults every user sees,
+-- owned ones are the user's own and can be nested under any visible category
+ALTER TABLE categories
+    ADD COLUMN owner_id  text REFERENCES users (id),
+    ADD COLUMN parent_id INT REFERENCES categories (id),
+    ADD COLUMN color     VARCHAR(7)  NOT NULL DEFAULT '',
+    ADD COLUMN icon      VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN archived  BOOLEAN     NOT NULL DEFAULT FALSE,
+    ADD CONSTRAINT category_is_not_its_own_parent CHECK (parent_id IS NULL OR parent_id <> id);
+
+CREATE INDEX idx_categories_owner_id
+    ON categories (owner_id);
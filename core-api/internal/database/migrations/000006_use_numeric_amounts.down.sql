@@ -0,0 +1,8 @@
+ALTER TABLE budgets
+    ALTER COLUMN amount TYPE DECIMAL;
+
+ALTER TABLE recurring_transactions
+    ALTER COLUMN price TYPE DECIMAL;
+
+ALTER TABLE transactions
+    ALTER COLUMN price TYPE DECIMAL;
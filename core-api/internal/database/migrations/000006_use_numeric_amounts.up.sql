@@ -0,0 +1,8 @@
+ALTER TABLE transactions
+    ALTER COLUMN price TYPE NUMERIC(15, 2) USING ROUND(price, 2);
+
+ALTER TABLE recurring_transactions
+    ALTER COLUMN price TYPE NUMERIC(15, 2) USING ROUND(price, 2);
+
+ALTER TABLE budgets
+    ALTER COLUMN amount TYPE NUMERIC(15, 2) USING ROUND(amount, 2);
@@ -0,0 +1,10 @@
+DROP FUNCTION IF EXISTS convert_amount(NUMERIC, VARCHAR, VARCHAR, DATE);
+DROP FUNCTION IF EXISTS exchange_rate(VARCHAR, DATE);
+
+ALTER TABLE recurring_transactions
+    DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS exchange_rates;
@@ -0,0 +1,83 @@
+-- rates are stored the way the ECB publishes them: units of currency for 1 EUR
+CREATE TABLE IF NOT EXISTS exchange_rates
+(
+    currency  VARCHAR(3)      NOT NULL,
+    rate_date DATE            NOT NULL,
+    rate      NUMERIC(20, 10) NOT NULL,
+    PRIMARY KEY (currency, rate_date),
+    CHECK (rate > 0)
+);
+
+-- the ECB does not publish MKD, the denar is pegged to the euro
+INSERT INTO exchange_rates (currency, rate_date, rate)
+VALUES ('MKD', '1999-01-01', 61.4950)
+ON CONFLICT DO NOTHING;
+
+-- price keeps the amount as entered, currency is the one it was paid in
+ALTER TABLE transactions
+    ADD COLUMN currency VARCHAR(3);
+UPDATE transactions t
+SET currency = u.preferred_currency
+FROM users u
+WHERE u.id = t.owner_id;
+ALTER TABLE transactions
+    ALTER COLUMN currency SET NOT NULL;
+
+ALTER TABLE recurring_transactions
+    ADD COLUMN currency VARCHAR(3);
+UPDATE recurring_transactions r
+SET currency = u.preferred_currency
+FROM users u
+WHERE u.id = r.owner_id;
+ALTER TABLE recurring_transactions
+    ALTER COLUMN currency SET NOT NULL;
+
+-- exchange_rate returns the rate of the currency on the given day. Weekends and holidays
+-- have no rate, so the latest one before the day is used; days before the first imported
+-- rate fall back to the oldest one.
+CREATE OR REPLACE FUNCTION exchange_rate(rate_currency VARCHAR, on_date DATE) RETURNS NUMERIC AS
+$$
+DECLARE
+    result NUMERIC;
+BEGIN
+    IF rate_currency = 'EUR' THEN
+        RETURN 1;
+    END IF;
+
+    SELECT rate
+    INTO result
+    FROM exchange_rates
+    WHERE currency = rate_currency
+      AND rate_date <= on_date
+    ORDER BY rate_date DESC
+    LIMIT 1;
+
+    IF result IS NULL THEN
+        SELECT rate
+        INTO result
+        FROM exchange_rates
+        WHERE currency = rate_currency
+        ORDER BY rate_date ASC
+        LIMIT 1;
+    END IF;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'no exchange rate for %', rate_currency;
+    END IF;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- convert_amount converts through the euro at the rates of the given day
+CREATE OR REPLACE FUNCTION convert_amount(amount NUMERIC, from_currency VARCHAR, to_currency VARCHAR,
+                                          on_date DATE) RETURNS NUMERIC AS
+$$
+BEGIN
+    IF from_currency = to_currency THEN
+        RETURN amount;
+    END IF;
+
+    RETURN ROUND(amount * exchange_rate(to_currency, on_date) / exchange_rate(from_currency, on_date), 2);
+END;
+$$ LANGUAGE plpgsql STABLE;
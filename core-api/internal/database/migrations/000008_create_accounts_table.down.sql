@@ -0,0 +1,21 @@
+ALTER TABLE users
+    ADD COLUMN balance NUMERIC(15, 2) NOT NULL DEFAULT 0.00;
+
+UPDATE users u
+SET balance = COALESCE((SELECT SUM(convert_amount(a.balance, a.currency, u.preferred_currency, CURRENT_DATE))
+                        FROM accounts a
+                        WHERE a.owner_id = u.id), 0);
+
+-- transfers cannot be represented without accounts
+DELETE FROM transactions WHERE type = 'Transfer';
+
+DROP INDEX IF EXISTS idx_transactions_account_id;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transfer_between_two_accounts,
+    DROP CONSTRAINT IF EXISTS transactions_type_check,
+    ADD CONSTRAINT transactions_type_check CHECK (type IN ('Expense', 'Income')),
+    DROP COLUMN IF EXISTS transfer_account_id,
+    DROP COLUMN IF EXISTS account_id;
+
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS accounts
+(
+    id              SERIAL PRIMARY KEY,
+    owner_id        text           NOT NULL,
+    name            VARCHAR(100)   NOT NULL,
+    type            VARCHAR(10)    NOT NULL,
+    currency        VARCHAR(3)     NOT NULL,
+    opening_balance NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    balance         NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (type IN ('Cash', 'Debit', 'Credit', 'Savings'))
+);
+
+CREATE INDEX idx_accounts_owner_id
+    ON accounts (owner_id);
+
+-- every user gets a main account holding the current balance, its opening balance is what
+-- the balance was before the existing transactions
+INSERT INTO accounts (owner_id, name, type, currency, opening_balance, balance)
+SELECT u.id,
+       'Main',
+       'Cash',
+       u.preferred_currency,
+       u.balance - COALESCE((SELECT SUM(CASE t.type WHEN 'Income' THEN 1 ELSE -1 END *
+                                        convert_amount(t.price, t.currency, u.preferred_currency, t.date_made::date))
+                             FROM transactions t
+                             WHERE t.owner_id = u.id), 0),
+       u.balance
+FROM users u;
+
+ALTER TABLE transactions
+    ADD COLUMN account_id          INT REFERENCES accounts (id),
+    ADD COLUMN transfer_account_id INT REFERENCES accounts (id);
+
+UPDATE transactions t
+SET account_id = a.id
+FROM accounts a
+WHERE a.owner_id = t.owner_id;
+
+ALTER TABLE transactions
+    ALTER COLUMN account_id SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS transactions_type_check,
+    ADD CONSTRAINT transactions_type_check CHECK (type IN ('Expense', 'Income', 'Transfer')),
+    ADD CONSTRAINT transfer_between_two_accounts CHECK (
+        (type = 'Transfer') = (transfer_account_id IS NOT NULL)
+            AND transfer_account_id IS DISTINCT FROM account_id
+        );
+
+CREATE INDEX idx_transactions_account_id
+    ON transactions (account_id);
+
+-- the balance is now the total of the accounts
+ALTER TABLE users
+    DROP COLUMN balance;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS transactions_account_external_id_key;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS external_id;
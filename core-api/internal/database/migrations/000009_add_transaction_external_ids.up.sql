@@ -0,0 +1,8 @@
+-- the id the bank gave a transaction (OFX FITID, CAMT AcctSvcrRef), importing a statement
+-- twice must not book its transactions twice
+ALTER TABLE transactions
+    ADD COLUMN external_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_account_external_id_key
+    ON transactions (account_id, external_id)
+    WHERE external_id IS NOT NULL;
@@ -0,0 +1,16 @@
+-- the lines of a receipt, amounts are in the currency of their transaction
+CREATE TABLE IF NOT EXISTS transaction_items
+(
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT         NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    position       INT            NOT NULL,
+    name           TEXT           NOT NULL,
+    quantity       NUMERIC(12, 3) NOT NULL DEFAULT 1,
+    unit_price     NUMERIC(15, 2) NOT NULL,
+    total          NUMERIC(15, 2) NOT NULL,
+    vat_rate       NUMERIC(5, 2),
+    CHECK (quantity > 0),
+    CHECK (vat_rate IS NULL OR (vat_rate >= 0 AND vat_rate <= 100))
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_items_transaction_id ON transaction_items (transaction_id, position);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS transactions_receipt_id_key;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS receipt_id;
+
+DROP TABLE IF EXISTS receipts;
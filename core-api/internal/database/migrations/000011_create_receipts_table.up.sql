@@ -0,0 +1,25 @@
+-- an uploaded receipt with the transaction read from it, waiting for the user to confirm it
+CREATE TABLE IF NOT EXISTS receipts
+(
+    id               BIGSERIAL PRIMARY KEY,
+    owner_id         TEXT        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status           VARCHAR(16) NOT NULL DEFAULT 'Draft',
+    filename         TEXT        NOT NULL DEFAULT '',
+    content_type     TEXT        NOT NULL,
+    image            BYTEA       NOT NULL,
+    draft            JSONB       NOT NULL,
+    extraction_error TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT receipts_status_check CHECK (status IN ('Draft', 'Confirmed', 'Rejected'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipts_owner_status ON receipts (owner_id, status, created_at);
+
+-- a receipt is confirmed into exactly one transaction
+ALTER TABLE transactions
+    ADD COLUMN receipt_id BIGINT REFERENCES receipts (id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_receipt_id_key
+    ON transactions (receipt_id)
+    WHERE receipt_id IS NOT NULL;
@@ -0,0 +1,23 @@
+-- receipts waiting to be read in the background, claimed by the workers with SKIP LOCKED
+CREATE TABLE IF NOT EXISTS receipt_jobs
+(
+    id           BIGSERIAL PRIMARY KEY,
+    owner_id     TEXT        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status       VARCHAR(16) NOT NULL DEFAULT 'Queued',
+    filename     TEXT        NOT NULL DEFAULT '',
+    content_type TEXT        NOT NULL,
+    image        BYTEA, -- cleared once the receipt has been stored
+    attempts     INT         NOT NULL DEFAULT 0,
+    max_attempts INT         NOT NULL DEFAULT 3,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- not before, pushed back between attempts
+    locked_until TIMESTAMPTZ, -- a running job past this is taken over by another worker
+    last_error   TEXT,
+    receipt_id   BIGINT REFERENCES receipts (id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT receipt_jobs_status_check CHECK (status IN ('Queued', 'Running', 'Succeeded', 'Failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipt_jobs_pending
+    ON receipt_jobs (run_at)
+    WHERE status IN ('Queued', 'Running');
@@ -0,0 +1,2 @@
+ALTER TABLE receipt_jobs
+    DROP COLUMN IF EXISTS request_id;
@@ -0,0 +1,3 @@
+-- the X-Request-ID of the upload, sent on to the ocr-service so both logs can be followed
+ALTER TABLE receipt_jobs
+    ADD COLUMN IF NOT EXISTS request_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+package dto
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
+	"time"
+)
+
+type AccountDto struct {
+	ID             int64             `json:"id"`
+	Name           *string           `json:"name"`
+	Type           *enum.AccountType `json:"type"`
+	Currency       *enum.Currency    `json:"currency"`
+	OpeningBalance *money.Money      `json:"opening_balance"`
+	Balance        money.Money       `json:"balance"` // read only
+	CreatedAt      time.Time         `json:"created_at"`
+}
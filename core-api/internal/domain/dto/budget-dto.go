@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type BudgetDto struct {
+	ID          int64           `json:"id"`
+	CategoryId  *int64          `json:"category_id"`
+	Amount      *money.Money    `json:"amount"`
+	Period      *enum.Frequency `json:"period"`
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+	Spent       money.Money     `json:"spent"`
+	Remaining   money.Money     `json:"remaining"`
+	PercentUsed decimal.Decimal `json:"percent_used"`
+	Overspent   bool            `json:"overspent"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+type CategoryDto struct {
+	ID       int64   `json:"id"`
+	Name     *string `json:"name"`
+	ParentId *int64  `json:"parent_id"` // 0 moves the category back to the top level
+	Color    *string `json:"color"`
+	Icon     *string `json:"icon"`
+	Archived *bool   `json:"archived"`
+	Global   bool    `json:"global"`
+}
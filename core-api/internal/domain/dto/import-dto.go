@@ -0,0 +1,35 @@
+package dto
+
+import "SmartSpend/internal/domain/enum"
+
+// CSVMappingDto describes the columns of a bank's CSV export. Columns are header names or
+// 1-based positions. The account, currency and skip options apply to every statement format.
+type CSVMappingDto struct {
+	DateColumn        string         `json:"date_column"`
+	AmountColumn      string         `json:"amount_column"`
+	DescriptionColumn string         `json:"description_column"`
+	DebitColumn       string         `json:"debit_column"`
+	CreditColumn      string         `json:"credit_column"`
+	SignConvention    string         `json:"sign_convention"`   // negative_expense (default), positive_expense or debit_credit
+	DateFormat        string         `json:"date_format"`       // e.g. DD.MM.YYYY, defaults to YYYY-MM-DD
+	DecimalSeparator  string         `json:"decimal_separator"` // "." (default) or ","
+	Delimiter         string         `json:"delimiter"`         // defaults to ","
+	HasHeader         *bool          `json:"has_header"`        // defaults to true
+	Currency          *enum.Currency `json:"currency"`          // defaults to the account's currency
+	AccountId         *int64         `json:"account_id"`        // defaults to the main account
+	SkipInvalid       bool           `json:"skip_invalid"`      // commit the valid rows even if others failed
+}
+
+type ImportRowDto struct {
+	Line        int             `json:"line"`
+	Transaction *TransactionDto `json:"transaction,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+type ImportResultDto struct {
+	Committed  bool           `json:"committed"`
+	Imported   int            `json:"imported"` // rows that are, or on commit would be, saved
+	Failed     int            `json:"failed"`
+	Duplicates int            `json:"duplicates"` // imported before, only known after the commit
+	Rows       []ImportRowDto `json:"rows"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"time"
+)
+
+// ReceiptDto is an uploaded receipt with the transaction read from it. The draft is edited
+// with a TransactionDto, only the fields that are set change.
+type ReceiptDto struct {
+	ID              int64                `json:"id"`
+	Status          enum.ReceiptStatus   `json:"status"`
+	Filename        string               `json:"filename"`
+	Draft           TransactionDto       `json:"draft"`
+	Parsed          *model.ParsedReceipt `json:"parsed,omitempty"` // read only, what the rule-based parser found and how sure it is
+	ExtractionError *string              `json:"extraction_error"` // the receipt could not be read, the draft has to be filled in
+	TransactionId   *int64               `json:"transaction_id"`   // set once confirmed
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"time"
+)
+
+// ReceiptJobDto is the progress of reading an uploaded receipt. Once the job is done Receipt is
+// the draft it was read into, for a failed job a draft with nothing read but the image.
+type ReceiptJobDto struct {
+	ID          int64                 `json:"id"`
+	Status      enum.ReceiptJobStatus `json:"status"`
+	Attempts    int                   `json:"attempts"`
+	MaxAttempts int                   `json:"max_attempts"`
+	RunAt       time.Time             `json:"run_at"`
+	Error       *string               `json:"error"` // of the last attempt
+	ReceiptId   *int64                `json:"receipt_id"`
+	Receipt     *ReceiptDto           `json:"receipt,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
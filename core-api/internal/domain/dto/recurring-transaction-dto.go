@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
+	"time"
+)
+
+type RecurringTransactionDto struct {
+	ID             int64                 `json:"id"`
+	Title          *string               `json:"title"`
+	Price          *money.Money          `json:"price"`
+	CategoryId     *int64                `json:"category_id"`
+	Type           *enum.TransactionType `json:"type"`
+	Frequency      *enum.Frequency       `json:"frequency"`
+	Interval       *int32                `json:"interval"`
+	StartDate      *time.Time            `json:"start_date"`
+	EndDate        *time.Time            `json:"end_date"`
+	MaxOccurrences *int32                `json:"count"`
+	Occurrences    int32                 `json:"occurrences"`
+	NextRun        *time.Time            `json:"next_run"`
+}
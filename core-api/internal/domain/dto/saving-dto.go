@@ -1,10 +1,13 @@
 package dto
 
-import "time"
+import (
+	"SmartSpend/internal/domain/money"
+	"time"
+)
 
 type SavingDto struct {
-	ID     int64      `json:"id"`
-	Amount *float32   `json:"amount"`
-	From   *time.Time `json:"from"`
-	To     *time.Time `json:"to"`
+	ID     int64        `json:"id"`
+	Amount *money.Money `json:"amount"`
+	From   *time.Time   `json:"from"`
+	To     *time.Time   `json:"to"`
 }
@@ -2,14 +2,22 @@ package dto
 
 import (
 	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
 	"time"
 )
 
 type TransactionDto struct {
-	ID         int64                 `json:"id"`
-	Title      *string               `json:"title"`
-	Price      *float32              `json:"price"`
-	DateMade   *time.Time            `json:"date_made"`
-	CategoryId *int64                `json:"category_id"`
-	Type       *enum.TransactionType `json:"type"`
+	ID                int64                 `json:"id"`
+	Title             *string               `json:"title"`
+	Price             *money.Money          `json:"price"`
+	ConvertedPrice    *money.Money          `json:"converted_price,omitempty"` // read only, in the user's preferred currency
+	DateMade          *time.Time            `json:"date_made"`
+	CategoryId        *int64                `json:"category_id"`
+	Type              *enum.TransactionType `json:"type"`
+	RecurringId       *int64                `json:"recurring_id"`
+	AccountId         *int64                `json:"account_id"`
+	TransferAccountId *int64                `json:"transfer_account_id"`
+	ExternalId        *string               `json:"external_id,omitempty"` // read only, set by statement imports
+	ReceiptId         *int64                `json:"receipt_id,omitempty"`  // read only, set when a receipt draft is confirmed
+	Items             []TransactionItemDto  `json:"items,omitempty"`       // only on create, edited on their own afterwards
 }
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"SmartSpend/internal/domain/money"
+
+	"github.com/shopspring/decimal"
+)
+
+type TransactionItemDto struct {
+	ID        int64            `json:"id"`
+	Name      *string          `json:"name"`
+	Quantity  *decimal.Decimal `json:"quantity"`   // defaults to 1
+	UnitPrice *money.Money     `json:"unit_price"` // derived from the total if missing
+	Total     *money.Money     `json:"total"`      // derived from the unit price if missing
+	VatRate   *decimal.Decimal `json:"vat_rate"`
+}
+
+// TransactionItemsDto are the items of a transaction. When they do not add up to its price,
+// e.g. because a discount was not read, Balanced is false and Difference says by how much.
+type TransactionItemsDto struct {
+	Items      []TransactionItemDto `json:"items"`
+	ItemsTotal money.Money          `json:"items_total"`
+	Price      money.Money          `json:"price"`
+	Difference money.Money          `json:"difference"` // price minus the items
+	Balanced   bool                 `json:"balanced"`
+}
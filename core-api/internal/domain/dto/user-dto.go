@@ -2,6 +2,7 @@ package dto
 
 import (
 	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
 	"time"
 )
 
@@ -21,8 +22,8 @@ type UserDto struct {
 	AppleEmail        string        `json:"apple_email"`
 	AvatarURL         string        `json:"avatar_url"`
 	CreatedAt         time.Time     `json:"created_at"`
-	Balance           float64       `json:"balance"`
-	MonthlySavingGoal float64       `json:"monthly_saving_goal"`
+	Balance           money.Money   `json:"balance"`
+	MonthlySavingGoal money.Money   `json:"monthly_saving_goal"`
 	PreferredCurrency enum.Currency `json:"preferred_currency"`
 }
 
@@ -31,7 +32,6 @@ type UpdateUserDto struct {
 	LastName          *string        `json:"last_name"`
 	Username          *string        `json:"username"`
 	AvatarURL         *string        `json:"avatar_url"`
-	Balance           *float64       `json:"balance"`
-	MonthlySavingGoal *float64       `json:"monthly_saving_goal"`
+	MonthlySavingGoal *money.Money   `json:"monthly_saving_goal"`
 	PreferredCurrency *enum.Currency `json:"preferred_currency"`
 }
@@ -2,6 +2,10 @@ package enum
 
 type Currency string
 type TransactionType string
+type Frequency string
+type AccountType string
+type ReceiptStatus string
+type ReceiptJobStatus string
 
 const (
 	USD Currency = "USD"
@@ -10,6 +14,34 @@ const (
 )
 
 const (
-	Expense TransactionType = "Expense"
-	Income  TransactionType = "Income"
+	Expense  TransactionType = "Expense"
+	Income   TransactionType = "Income"
+	Transfer TransactionType = "Transfer" // moves money between two of the user's accounts
+)
+
+const (
+	Daily   Frequency = "Daily"
+	Weekly  Frequency = "Weekly"
+	Monthly Frequency = "Monthly"
+	Yearly  Frequency = "Yearly"
+)
+
+const (
+	Cash    AccountType = "Cash"
+	Debit   AccountType = "Debit"
+	Credit  AccountType = "Credit"
+	Savings AccountType = "Savings"
+)
+
+const (
+	Draft     ReceiptStatus = "Draft" // read, waiting for the user
+	Confirmed ReceiptStatus = "Confirmed"
+	Rejected  ReceiptStatus = "Rejected"
+)
+
+const (
+	Queued    ReceiptJobStatus = "Queued"
+	Running   ReceiptJobStatus = "Running"
+	Succeeded ReceiptJobStatus = "Succeeded"
+	Failed    ReceiptJobStatus = "Failed" // given up, the draft is left for the user to fill in
 )
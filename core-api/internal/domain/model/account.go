@@ -0,0 +1,20 @@
+package model
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
+	"time"
+)
+
+// Account is where a user's money is kept, e.g. cash or a bank account. Its balances are
+// in the account's own currency.
+type Account struct {
+	ID             int64            `json:"id"`
+	OwnerId        string           `json:"owner_id"`
+	Name           string           `json:"name"`
+	Type           enum.AccountType `json:"type"`
+	Currency       enum.Currency    `json:"currency"`
+	OpeningBalance money.Money      `json:"opening_balance"`
+	Balance        money.Money      `json:"balance"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type Budget struct {
+	ID         int64          `json:"id"`
+	OwnerId    string         `json:"owner_id"`
+	CategoryId int64          `json:"category_id"`
+	Amount     money.Money    `json:"amount"`
+	Period     enum.Frequency `json:"period"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// BudgetSpending is a budget together with what has been spent in its current period.
+type BudgetSpending struct {
+	Budget
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+	Spent       money.Money     `json:"spent"`
+	Remaining   money.Money     `json:"remaining"`
+	PercentUsed decimal.Decimal `json:"percent_used"`
+	Overspent   bool            `json:"overspent"`
+}
@@ -1,6 +1,11 @@
 package model
 
 type Category struct {
-	ID   int8   `gorm:"type:text;primaryKey" json:"id"`
-	Name string `gorm:"size:100" json:"name"`
+	ID       int64   `gorm:"primaryKey" json:"id"`
+	Name     string  `gorm:"size:100" json:"name"`
+	OwnerId  *string `gorm:"type:text" json:"owner_id"` // nil for the global default categories
+	ParentId *int64  `json:"parent_id"`
+	Color    string  `gorm:"size:7" json:"color"`
+	Icon     string  `gorm:"size:50" json:"icon"`
+	Archived bool    `json:"archived"`
 }
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExchangeRate is a euro reference rate: how many units of Currency one EUR was worth on Date.
+type ExchangeRate struct {
+	Currency enum.Currency   `json:"currency"`
+	Date     time.Time       `json:"date"`
+	Rate     decimal.Decimal `json:"rate"`
+}
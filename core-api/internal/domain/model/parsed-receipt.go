@@ -0,0 +1,36 @@
+package model
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ParsedReceipt is what the rule-based parser found in the OCR text of a receipt. Every field
+// has a confidence between 0 (not found) and 1.
+type ParsedReceipt struct {
+	Total      *decimal.Decimal  `json:"total"`
+	Currency   enum.Currency     `json:"currency"` // empty when the receipt does not say
+	Date       *time.Time        `json:"date"`
+	Merchant   string            `json:"merchant"`
+	TaxNumber  string            `json:"tax_number"` // ЕДБ of Macedonian receipts, the VAT number of others
+	VAT        []ReceiptVAT      `json:"vat"`
+	Confidence ReceiptConfidence `json:"confidence"`
+}
+
+type ReceiptConfidence struct {
+	Total     float64 `json:"total"`
+	Date      float64 `json:"date"`
+	Merchant  float64 `json:"merchant"`
+	TaxNumber float64 `json:"tax_number"`
+}
+
+// ReceiptVAT is a line of the VAT breakdown, Base is the net amount the VAT is charged on
+// when the receipt prints it.
+type ReceiptVAT struct {
+	Rate       decimal.Decimal  `json:"rate"` // percent
+	Base       *decimal.Decimal `json:"base"`
+	Amount     decimal.Decimal  `json:"amount"`
+	Confidence float64          `json:"confidence"`
+}
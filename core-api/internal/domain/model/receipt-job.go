@@ -0,0 +1,24 @@
+package model
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"time"
+)
+
+// ReceiptJob reads an uploaded receipt in the background. Once done ReceiptId is the draft
+// it was read into.
+type ReceiptJob struct {
+	ID          int64                 `json:"id"`
+	OwnerId     string                `json:"owner_id"`
+	Status      enum.ReceiptJobStatus `json:"status"`
+	Filename    string                `json:"filename"`
+	ContentType string                `json:"content_type"`
+	Attempts    int                   `json:"attempts"`
+	MaxAttempts int                   `json:"max_attempts"`
+	RunAt       time.Time             `json:"run_at"`
+	LastError   *string               `json:"last_error"`
+	ReceiptId   *int64                `json:"receipt_id"`
+	RequestId   string                `json:"request_id"` // of the upload, sent on to the ocr-service
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
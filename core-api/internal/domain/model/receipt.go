@@ -0,0 +1,21 @@
+package model
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"time"
+)
+
+// Receipt is an uploaded receipt. Draft is the transaction read from it, which becomes a
+// real transaction once the user confirms it. The image is loaded on its own.
+type Receipt struct {
+	ID              int64              `json:"id"`
+	OwnerId         string             `json:"owner_id"`
+	Status          enum.ReceiptStatus `json:"status"`
+	Filename        string             `json:"filename"`
+	ContentType     string             `json:"content_type"`
+	Draft           Transaction        `json:"draft"`
+	ExtractionError *string            `json:"extraction_error"` // why nothing could be read, the draft is empty then
+	TransactionId   *int64             `json:"transaction_id"`   // set once confirmed
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
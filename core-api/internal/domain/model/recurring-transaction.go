@@ -0,0 +1,23 @@
+package model
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
+	"time"
+)
+
+type RecurringTransaction struct {
+	ID             int64                `json:"id"`
+	OwnerId        string               `json:"owner_id"`
+	Title          string               `json:"title"`
+	Price          money.Money          `json:"price"`
+	CategoryId     *int64               `json:"category_id"`
+	Type           enum.TransactionType `json:"type"`
+	Frequency      enum.Frequency       `json:"frequency"`
+	Interval       int32                `json:"interval"`
+	StartDate      time.Time            `json:"start_date"`
+	EndDate        *time.Time           `json:"end_date"`
+	MaxOccurrences *int32               `json:"count"`
+	Occurrences    int32                `json:"occurrences"` // how many transactions have been materialized so far
+	NextRun        *time.Time           `json:"next_run"`    // nil once the rule is exhausted
+}
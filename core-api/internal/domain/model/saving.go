@@ -1,11 +1,14 @@
 package model
 
-import "time"
+import (
+	"SmartSpend/internal/domain/money"
+	"time"
+)
 
 type Saving struct {
-	ID      int64     `json:"id"`
-	OwnerId string    `json:"owner_id"`
-	Amount  float32   `json:"amount"`
-	From    time.Time `json:"from"`
-	To      time.Time `json:"to"`
+	ID      int64       `json:"id"`
+	OwnerId string      `json:"owner_id"`
+	Amount  money.Money `json:"amount"`
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
 }
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"SmartSpend/internal/domain/money"
+
+	"github.com/shopspring/decimal"
+)
+
+// TransactionItem is a line of a receipt, its amounts are in the currency of the transaction.
+type TransactionItem struct {
+	ID            int64            `json:"id"`
+	TransactionId int64            `json:"transaction_id"`
+	Name          string           `json:"name"`
+	Quantity      decimal.Decimal  `json:"quantity"`
+	UnitPrice     money.Money      `json:"unit_price"`
+	Total         money.Money      `json:"total"`
+	VatRate       *decimal.Decimal `json:"vat_rate"` // in percent, e.g. 18
+}
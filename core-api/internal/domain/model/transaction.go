@@ -2,15 +2,43 @@ package model
 
 import (
 	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
 	"time"
 )
 
 type Transaction struct {
-	ID         int64                `json:"id"`
-	Title      string               `json:"title"`
-	Price      float32              `json:"price"`
-	DateMade   time.Time            `json:"date_made"`
-	OwnerId    string               `json:"owner_id"`
-	CategoryId *int64               `json:"category_id"`
-	Type       enum.TransactionType `json:"type"`
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	// Price is the amount in the currency it was paid in, ConvertedPrice the same amount
+	// in the owner's preferred currency at the rate of DateMade
+	Price          money.Money          `json:"price"`
+	ConvertedPrice money.Money          `json:"converted_price"`
+	DateMade       time.Time            `json:"date_made"`
+	OwnerId        string               `json:"owner_id"`
+	CategoryId     *int64               `json:"category_id"`
+	Type           enum.TransactionType `json:"type"`
+	RecurringId    *int64               `json:"recurring_id"`
+	// AccountId is the account the money is taken from or added to, for a transfer
+	// TransferAccountId is the account it goes to
+	AccountId         *int64 `json:"account_id"`
+	TransferAccountId *int64 `json:"transfer_account_id"`
+	// ExternalId is the bank's id of an imported transaction
+	ExternalId *string `json:"external_id"`
+	// ReceiptId is the receipt the transaction was confirmed from
+	ReceiptId *int64 `json:"receipt_id"`
+	// Items are the lines of the receipt, only set when they are saved or read with it
+	Items []TransactionItem `json:"items,omitempty"`
+	// Parsed is what the rule-based parser found on the receipt of a draft, with how sure it
+	// is of every field. It is kept with the draft only
+	Parsed *ParsedReceipt `json:"parsed,omitempty"`
+}
+
+// TransactionExport is a transaction as it is exported, with names instead of ids and the
+// balance of all accounts after it, in the owner's preferred currency.
+type TransactionExport struct {
+	Transaction
+	CategoryName        *string
+	AccountName         string
+	TransferAccountName *string
+	RunningBalance      money.Money
 }
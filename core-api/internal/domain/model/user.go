@@ -2,6 +2,7 @@ package model
 
 import (
 	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
 	"time"
 )
 
@@ -16,7 +17,7 @@ type User struct {
 	RefreshTokenExpiryDate time.Time     // 30 days after each log in
 	AvatarURL              string        `gorm:"size:512" json:"avatar_url"`
 	CreatedAt              time.Time     `gorm:"autoCreateTime" json:"created_at"`
-	Balance                float64       `gorm:"number" json:"balance"`
-	MonthlySavingGoal      float64       `gorm:"number" json:"monthly_saving_goal"`
+	Balance                money.Money   `gorm:"number" json:"balance"`
+	MonthlySavingGoal      money.Money   `gorm:"number" json:"monthly_saving_goal"`
 	PreferredCurrency      enum.Currency `gorm:"size:255" json:"preferred_currency"`
 }
@@ -0,0 +1,135 @@
+package money
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Scale is the number of decimal places amounts are stored with (NUMERIC(15, 2)).
+const Scale = 2
+
+// Money is an exact amount in a currency. It replaces the float32 prices so that
+// balances do not drift after many additions and subtractions.
+type Money struct {
+	Amount   decimal.Decimal
+	Currency enum.Currency
+}
+
+func New(amount decimal.Decimal, currency enum.Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func Zero(currency enum.Currency) Money {
+	return Money{Amount: decimal.Zero, Currency: currency}
+}
+
+func FromString(amount string, currency enum.Currency) (Money, error) {
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", amount, err)
+	}
+	return Money{Amount: d, Currency: currency}, nil
+}
+
+// MustFromString is FromString for constants, it panics on an invalid amount.
+func MustFromString(amount string, currency enum.Currency) Money {
+	m, err := FromString(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// sameCurrency returns the currency shared by a and b. An empty currency is treated
+// as "not known yet" and takes the other one; adding EUR to MKD is a programming error.
+func sameCurrency(a Money, b Money) enum.Currency {
+	switch {
+	case a.Currency == "":
+		return b.Currency
+	case b.Currency == "" || a.Currency == b.Currency:
+		return a.Currency
+	default:
+		panic(fmt.Sprintf("money: mixing currencies %s and %s", a.Currency, b.Currency))
+	}
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount.Add(other.Amount), Currency: sameCurrency(m, other)}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount.Sub(other.Amount), Currency: sameCurrency(m, other)}
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: m.Amount.Neg(), Currency: m.Currency}
+}
+
+func (m Money) Round() Money {
+	return Money{Amount: m.Amount.Round(Scale), Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount.IsZero()
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount.IsNegative()
+}
+
+func (m Money) GreaterThan(other Money) bool {
+	sameCurrency(m, other)
+	return m.Amount.GreaterThan(other.Amount)
+}
+
+// PercentOf returns m as a percentage of total rounded to two decimals, 0 when total is 0.
+func (m Money) PercentOf(total Money) decimal.Decimal {
+	sameCurrency(m, total)
+	if total.Amount.IsZero() {
+		return decimal.Zero
+	}
+	return m.Amount.Div(total.Amount).Mul(decimal.NewFromInt(100)).Round(Scale)
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Amount.StringFixed(Scale), m.Currency)
+}
+
+type jsonMoney struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency enum.Currency   `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as a string so no client parses it into a float
+// on the way: {"amount": "12.50", "currency": "MKD"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string        `json:"amount"`
+		Currency enum.Currency `json:"currency"`
+	}{
+		Amount:   m.Amount.StringFixed(Scale),
+		Currency: m.Currency,
+	})
+}
+
+// UnmarshalJSON accepts the object form as well as a bare number or numeric string,
+// which is what older clients and the receipt LLM send. The currency is then left
+// empty and filled in from the owner.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var aux jsonMoney
+		if err := json.Unmarshal(trimmed, &aux); err != nil {
+			return err
+		}
+		m.Amount = aux.Amount
+		m.Currency = aux.Currency
+		return nil
+	}
+	m.Currency = ""
+	return m.Amount.UnmarshalJSON(trimmed)
+}
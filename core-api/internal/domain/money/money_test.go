@@ -0,0 +1,46 @@
+package money
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddDoesNotDrift(t *testing.T) {
+	balance := Zero(enum.MKD)
+	for i := 0; i < 1000; i++ {
+		balance = balance.Add(MustFromString("0.10", enum.MKD))
+	}
+	assert.Equal(t, "100.00 MKD", balance.String())
+}
+
+func TestMixingCurrenciesPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		MustFromString("1", enum.MKD).Add(MustFromString("1", enum.EUR))
+	})
+	// an amount without a currency takes the other one
+	assert.Equal(t, enum.EUR, MustFromString("1", "").Add(MustFromString("1", enum.EUR)).Currency)
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(MustFromString("12.5", enum.MKD))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount": "12.50", "currency": "MKD"}`, string(data))
+
+	var m Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": "0.30", "currency": "EUR"}`), &m))
+	assert.Equal(t, "0.30 EUR", m.String())
+
+	assert.NoError(t, json.Unmarshal([]byte(`149.99`), &m))
+	assert.Equal(t, "149.99", m.Amount.String())
+	assert.Empty(t, m.Currency, "a bare number does not keep the currency of before")
+
+	assert.Error(t, json.Unmarshal([]byte(`"abc"`), &m))
+}
+
+func TestPercentOf(t *testing.T) {
+	assert.Equal(t, "33.33", MustFromString("1", enum.MKD).PercentOf(MustFromString("3", enum.MKD)).String())
+	assert.True(t, MustFromString("1", enum.MKD).PercentOf(Zero(enum.MKD)).IsZero())
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+type IAccountRepository interface {
+	FindAll(userId string) []model.Account
+	FindById(id int64, userId string) (*model.Account, error)
+	FindTotalBalance(userId string) (money.Money, error)
+	Save(account model.Account) error
+	Update(account model.Account, id int64) error
+	Delete(id int64, userId string) error
+}
+
+type databaseAccountRepository struct {
+	db *sql.DB
+}
+
+// defaultAccountName is the account every user is created with.
+const defaultAccountName = "Main"
+
+const accountColumns = `id, owner_id, name, type, currency, opening_balance, balance, created_at`
+
+func NewAccountRepository(s database.Service) IAccountRepository {
+	return &databaseAccountRepository{
+		db: s.DB(),
+	}
+}
+
+func scanAccount(row rowScanner) (model.Account, error) {
+	var a model.Account
+	err := row.Scan(&a.ID, &a.OwnerId, &a.Name, &a.Type, &a.Currency, &a.OpeningBalance.Amount, &a.Balance.Amount, &a.CreatedAt)
+	a.OpeningBalance.Currency = a.Currency
+	a.Balance.Currency = a.Currency
+	return a, err
+}
+
+func (d *databaseAccountRepository) FindAll(userId string) []model.Account {
+	rows, err := d.db.Query(`
+		SELECT `+accountColumns+`
+		FROM accounts
+		WHERE owner_id = $1
+		ORDER BY id ASC
+	`, userId)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+
+	var accounts []model.Account
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts
+}
+
+func (d *databaseAccountRepository) FindById(id int64, userId string) (*model.Account, error) {
+	row := d.db.QueryRow(`
+		SELECT `+accountColumns+`
+		FROM accounts
+		WHERE id = $1 AND owner_id = $2
+	`, id, userId)
+
+	a, err := scanAccount(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("account not found")
+		}
+		return nil, fmt.Errorf("failed to scan account: %v", err)
+	}
+
+	return &a, nil
+}
+
+// FindTotalBalance sums the balances of all the user's accounts in their preferred currency
+// at today's rates.
+func (d *databaseAccountRepository) FindTotalBalance(userId string) (money.Money, error) {
+	row := d.db.QueryRow(`
+		SELECT COALESCE(SUM(convert_amount(a.balance, a.currency, u.preferred_currency, CURRENT_DATE)), 0),
+		       u.preferred_currency
+		FROM users u
+		LEFT JOIN accounts a
+			ON a.owner_id = u.id
+		WHERE u.id = $1
+		GROUP BY u.preferred_currency
+	`, userId)
+
+	var total money.Money
+	if err := row.Scan(&total.Amount, &total.Currency); err != nil {
+		return money.Money{}, err
+	}
+	return total, nil
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertAccount creates the account with its balance set to the opening balance.
+func insertAccount(e execer, account model.Account) error {
+	_, err := e.Exec(`
+		INSERT INTO accounts (owner_id, name, type, currency, opening_balance, balance)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`, account.OwnerId, account.Name, account.Type, account.Currency, account.OpeningBalance.Amount)
+	return err
+}
+
+// findDefaultAccount returns the user's oldest account, which transactions without an
+// account are booked on.
+func findDefaultAccount(q rowQuerier, userId string) (int64, error) {
+	var id int64
+	err := q.QueryRow(`SELECT id FROM accounts WHERE owner_id = $1 ORDER BY id ASC LIMIT 1`, userId).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("user has no account")
+	}
+	return id, err
+}
+
+func (d *databaseAccountRepository) Save(account model.Account) error {
+	log.Println("Saving account:", account.Name)
+	return insertAccount(d.db, account)
+}
+
+// Update renames the account or changes its type or opening balance. A different opening
+// balance moves the current balance by the same amount.
+func (d *databaseAccountRepository) Update(account model.Account, id int64) error {
+	result, err := d.db.Exec(`
+		UPDATE accounts
+		SET name = $1,
+		    type = $2,
+		    balance = balance + $3 - opening_balance,
+		    opening_balance = $3
+		WHERE id = $4 AND owner_id = $5
+	`, account.Name, account.Type, account.OpeningBalance.Amount, id, account.OwnerId)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("account not found")
+	}
+	return nil
+}
+
+// Delete removes an account that no transaction is booked on.
+func (d *databaseAccountRepository) Delete(id int64, userId string) error {
+	a, err := d.FindById(id, userId)
+	if a == nil {
+		return err
+	}
+
+	var used bool
+	err = d.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM transactions WHERE account_id = $1 OR transfer_account_id = $1)
+	`, id).Scan(&used)
+	if err != nil {
+		return err
+	}
+	if used {
+		return fmt.Errorf("account still has transactions")
+	}
+
+	_, err = d.db.Exec("DELETE FROM accounts WHERE id = $1 AND owner_id = $2", id, userId)
+	return err
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+type IBudgetRepository interface {
+	FindAll(userId string) []model.Budget
+	FindById(id int64, userId string) (*model.Budget, error)
+	Save(budget model.Budget) error
+	Update(budget model.Budget, id int64) error
+	Delete(id int64, userId string) error
+}
+
+type databaseBudgetRepository struct {
+	db *sql.DB
+}
+
+func NewBudgetRepository(s database.Service) IBudgetRepository {
+	return &databaseBudgetRepository{
+		db: s.DB(),
+	}
+}
+
+func (d *databaseBudgetRepository) FindAll(userId string) []model.Budget {
+	rows, err := d.db.Query(`
+		SELECT b.id, b.owner_id, b.category_id, b.amount, u.preferred_currency, b.period, b.created_at
+		FROM budgets b
+		JOIN users u ON u.id = b.owner_id
+		WHERE b.owner_id = $1
+		ORDER BY b.id ASC
+	`, userId)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+
+	var budgets []model.Budget
+	for rows.Next() {
+		var b model.Budget
+		if err := rows.Scan(&b.ID, &b.OwnerId, &b.CategoryId, &b.Amount.Amount, &b.Amount.Currency, &b.Period, &b.CreatedAt); err != nil {
+			log.Println(err)
+			continue
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets
+}
+
+func (d *databaseBudgetRepository) FindById(id int64, userId string) (*model.Budget, error) {
+	row := d.db.QueryRow(`
+		SELECT b.id, b.owner_id, b.category_id, b.amount, u.preferred_currency, b.period, b.created_at
+		FROM budgets b
+		JOIN users u ON u.id = b.owner_id
+		WHERE b.id = $1 AND b.owner_id = $2
+	`, id, userId)
+
+	var budget model.Budget
+	err := row.Scan(&budget.ID, &budget.OwnerId, &budget.CategoryId, &budget.Amount.Amount, &budget.Amount.Currency, &budget.Period, &budget.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("budget not found")
+		}
+		return nil, fmt.Errorf("failed to scan budget: %v", err)
+	}
+
+	return &budget, nil
+}
+
+// Save stores the budget. Budgets are kept in the owner's preferred currency, an amount in
+// another currency is converted at today's rate.
+func (d *databaseBudgetRepository) Save(budget model.Budget) error {
+	log.Println("Saving budget for category:", budget.CategoryId)
+	_, err := d.db.Exec(`
+		INSERT INTO budgets (owner_id, category_id, amount, period)
+		SELECT $1, $2, convert_amount($3, COALESCE(NULLIF($5, ''), u.preferred_currency), u.preferred_currency, CURRENT_DATE), $4
+		FROM users u
+		WHERE u.id = $1
+	`, budget.OwnerId, budget.CategoryId, budget.Amount.Amount, budget.Period, budget.Amount.Currency)
+	return err
+}
+
+func (d *databaseBudgetRepository) Update(budget model.Budget, id int64) error {
+	_, err := d.db.Exec(`
+		UPDATE budgets b
+		SET category_id = $1,
+		    amount = convert_amount($2, COALESCE(NULLIF($6, ''), u.preferred_currency), u.preferred_currency, CURRENT_DATE),
+		    period = $3
+		FROM users u
+		WHERE u.id = b.owner_id
+		  AND b.id = $4 AND b.owner_id = $5
+	`, budget.CategoryId, budget.Amount.Amount, budget.Period, id, budget.OwnerId, budget.Amount.Currency)
+	return err
+}
+
+func (d *databaseBudgetRepository) Delete(id int64, userId string) error {
+	b, err := d.FindById(id, userId)
+	if b == nil {
+		return err
+	}
+
+	_, err = d.db.Exec("DELETE FROM budgets WHERE id = $1 AND owner_id = $2", id, userId)
+	return err
+}
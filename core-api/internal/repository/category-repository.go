@@ -4,11 +4,17 @@ import (
 	"SmartSpend/internal/database"
 	"SmartSpend/internal/domain/model"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 )
 
 type ICategoryRepository interface {
-	FindAll() []model.Category
+	FindAll(userId string, includeArchived bool) []model.Category
+	FindById(id int64, userId string, includeArchived bool) (*model.Category, error)
+	Save(category model.Category) error
+	Update(category model.Category, id int64) error
+	Archive(id int64, userId string) error
 }
 
 type databaseCategoryRepository struct {
@@ -21,8 +27,16 @@ func NewCategoryRepository(s database.Service) ICategoryRepository {
 	}
 }
 
-func (d *databaseCategoryRepository) FindAll() []model.Category {
-	rows, err := d.db.Query("SELECT * FROM categories")
+// FindAll returns the global categories together with the ones owned by userId.
+// An empty userId returns only the global ones.
+func (d *databaseCategoryRepository) FindAll(userId string, includeArchived bool) []model.Category {
+	rows, err := d.db.Query(`
+		SELECT id, name, owner_id, parent_id, color, icon, archived
+		FROM categories
+		WHERE (owner_id IS NULL OR owner_id = $1)
+		  AND (archived = FALSE OR $2)
+		ORDER BY owner_id NULLS FIRST, id
+	`, userId, includeArchived)
 	if err != nil {
 		log.Println(err)
 		return nil
@@ -32,7 +46,7 @@ func (d *databaseCategoryRepository) FindAll() []model.Category {
 	var categories []model.Category
 	for rows.Next() {
 		var t model.Category
-		if err := rows.Scan(&t.ID, &t.Name); err != nil {
+		if err := rows.Scan(&t.ID, &t.Name, &t.OwnerId, &t.ParentId, &t.Color, &t.Icon, &t.Archived); err != nil {
 			log.Println(err)
 			continue
 		}
@@ -40,3 +54,67 @@ func (d *databaseCategoryRepository) FindAll() []model.Category {
 	}
 	return categories
 }
+
+// FindById returns the category if it is visible to userId, i.e. global or owned by them.
+// Archived categories are only found with includeArchived, they cannot be picked anymore.
+func (d *databaseCategoryRepository) FindById(id int64, userId string, includeArchived bool) (*model.Category, error) {
+	row := d.db.QueryRow(`
+		SELECT id, name, owner_id, parent_id, color, icon, archived
+		FROM categories
+		WHERE id = $1
+		  AND (owner_id IS NULL OR owner_id = $2)
+		  AND (archived = FALSE OR $3)
+	`, id, userId, includeArchived)
+
+	var category model.Category
+	err := row.Scan(&category.ID, &category.Name, &category.OwnerId, &category.ParentId, &category.Color, &category.Icon, &category.Archived)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("category not found")
+		}
+		return nil, fmt.Errorf("failed to scan category: %v", err)
+	}
+
+	return &category, nil
+}
+
+func (d *databaseCategoryRepository) Save(category model.Category) error {
+	log.Println("Saving category:", category.Name)
+	_, err := d.db.Exec(`
+		INSERT INTO categories (name, owner_id, parent_id, color, icon)
+		VALUES ($1, $2, $3, $4, $5)
+	`, category.Name, category.OwnerId, category.ParentId, category.Color, category.Icon)
+	return err
+}
+
+// Update only touches categories owned by the user, global ones are read-only.
+func (d *databaseCategoryRepository) Update(category model.Category, id int64) error {
+	result, err := d.db.Exec(`
+		UPDATE categories
+		SET name = $1,
+		    parent_id = $2,
+		    color = $3,
+		    icon = $4,
+		    archived = $5
+		WHERE id = $6 AND owner_id = $7
+	`, category.Name, category.ParentId, category.Color, category.Icon, category.Archived, id, category.OwnerId)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("category not found or not owned by user")
+	}
+	return nil
+}
+
+// Archive hides the category from pickers; transactions keep pointing at it.
+func (d *databaseCategoryRepository) Archive(id int64, userId string) error {
+	result, err := d.db.Exec(`UPDATE categories SET archived = TRUE WHERE id = $1 AND owner_id = $2`, id, userId)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("category not found or not owned by user")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"database/sql"
+	"log"
+)
+
+type IExchangeRateRepository interface {
+	FindCurrencies() []enum.Currency
+	SaveAll(rates []model.ExchangeRate) error
+}
+
+type databaseExchangeRateRepository struct {
+	db *sql.DB
+}
+
+func NewExchangeRateRepository(s database.Service) IExchangeRateRepository {
+	return &databaseExchangeRateRepository{
+		db: s.DB(),
+	}
+}
+
+// FindCurrencies returns every currency amounts can be converted from and to. EUR is the
+// base of the rates and therefore never stored.
+func (d *databaseExchangeRateRepository) FindCurrencies() []enum.Currency {
+	rows, err := d.db.Query(`
+		SELECT 'EUR'
+		UNION
+		SELECT DISTINCT currency
+		FROM exchange_rates
+		ORDER BY 1
+	`)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+
+	var currencies []enum.Currency
+	for rows.Next() {
+		var currency enum.Currency
+		if err := rows.Scan(&currency); err != nil {
+			log.Println(err)
+			continue
+		}
+		currencies = append(currencies, currency)
+	}
+	return currencies
+}
+
+// SaveAll stores the rates in one transaction, a rate that was already imported for the
+// same day is overwritten.
+func (d *databaseExchangeRateRepository) SaveAll(rates []model.ExchangeRate) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO exchange_rates (currency, rate_date, rate)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (currency, rate_date) DO UPDATE SET rate = EXCLUDED.rate
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		if _, err := stmt.Exec(rate.Currency, rate.Date, rate.Rate); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
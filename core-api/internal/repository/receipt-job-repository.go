@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type IReceiptJobRepository interface {
+	Enqueue(job model.ReceiptJob, image []byte) (int64, error)
+	FindById(id int64, userId string) (*model.ReceiptJob, error)
+	Claim(lease time.Duration) (*model.ReceiptJob, []byte, error)
+	Retry(id int64, attempt int, lastError string, runAt time.Time) error
+	Finish(id int64, attempt int, status enum.ReceiptJobStatus, draft model.Transaction, lastError *string) (int64, error)
+}
+
+// ErrReceiptJobLost is returned to a worker that updates a job it no longer holds: its lease
+// ran out and the job was claimed again, or it has finished since.
+var ErrReceiptJobLost = errors.New("receipt job was claimed by another worker")
+
+type databaseReceiptJobRepository struct {
+	db *sql.DB
+}
+
+func NewReceiptJobRepository(s database.Service) IReceiptJobRepository {
+	return &databaseReceiptJobRepository{
+		db: s.DB(),
+	}
+}
+
+const receiptJobColumns = `id, owner_id, status, filename, content_type, attempts, max_attempts, run_at, last_error,
+	receipt_id, request_id, created_at, updated_at`
+
+func scanReceiptJob(row rowScanner, extra ...any) (model.ReceiptJob, error) {
+	var j model.ReceiptJob
+	dest := append([]any{&j.ID, &j.OwnerId, &j.Status, &j.Filename, &j.ContentType, &j.Attempts, &j.MaxAttempts,
+		&j.RunAt, &j.LastError, &j.ReceiptId, &j.RequestId, &j.CreatedAt, &j.UpdatedAt}, extra...)
+	return j, row.Scan(dest...)
+}
+
+// Enqueue stores a job for the image that runs as soon as a worker is free and returns its id.
+func (d *databaseReceiptJobRepository) Enqueue(job model.ReceiptJob, image []byte) (int64, error) {
+	var id int64
+	err := d.db.QueryRow(`
+		INSERT INTO receipt_jobs (owner_id, status, filename, content_type, image, max_attempts, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, job.OwnerId, enum.Queued, job.Filename, job.ContentType, image, job.MaxAttempts, job.RequestId).Scan(&id)
+	return id, err
+}
+
+func (d *databaseReceiptJobRepository) FindById(id int64, userId string) (*model.ReceiptJob, error) {
+	job, err := scanReceiptJob(d.db.QueryRow(`
+		SELECT `+receiptJobColumns+`
+		FROM receipt_jobs
+		WHERE id = $1 AND owner_id = $2
+	`, id, userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("receipt job not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Claim takes the next job that is due and returns it with its image, or nil when there is
+// none. The job is the worker's for lease; a running job whose lease ran out, because its
+// worker died, is claimed again. SKIP LOCKED keeps concurrent workers from blocking each other.
+func (d *databaseReceiptJobRepository) Claim(lease time.Duration) (*model.ReceiptJob, []byte, error) {
+	var image []byte
+	job, err := scanReceiptJob(d.db.QueryRow(`
+		UPDATE receipt_jobs
+		SET status = $1, attempts = attempts + 1, locked_until = NOW() + make_interval(secs => $2), updated_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM receipt_jobs
+			WHERE (status = $3 AND run_at <= NOW())
+			   OR (status = $1 AND locked_until < NOW())
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+receiptJobColumns+`, image
+	`, enum.Running, lease.Seconds(), enum.Queued), &image)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &job, image, nil
+}
+
+// Retry puts the job back in the queue to run again at runAt. attempt is the attempt the
+// worker claimed, the job is only requeued while that attempt still holds it.
+func (d *databaseReceiptJobRepository) Retry(id int64, attempt int, lastError string, runAt time.Time) error {
+	result, err := d.db.Exec(`
+		UPDATE receipt_jobs
+		SET status = $2, run_at = $3, last_error = $4, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = $5 AND attempts = $6
+	`, id, enum.Queued, runAt, lastError, enum.Running, attempt)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrReceiptJobLost
+	}
+	return nil
+}
+
+// Finish stores the draft receipt read by the job, with the image of the job, and ends the
+// job with status. It returns the id of the receipt. Like Retry it only ends the attempt that
+// still holds the job.
+func (d *databaseReceiptJobRepository) Finish(id int64, attempt int, status enum.ReceiptJobStatus, draft model.Transaction, lastError *string) (int64, error) {
+	draftJSON, err := json.Marshal(draft)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// locks the job, Claim skips it until this attempt is done with it
+	var held bool
+	err = tx.QueryRow(`
+		SELECT TRUE
+		FROM receipt_jobs
+		WHERE id = $1 AND status = $2 AND attempts = $3 AND image IS NOT NULL
+		FOR UPDATE
+	`, id, enum.Running, attempt).Scan(&held)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrReceiptJobLost
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var receiptId int64
+	err = tx.QueryRow(`
+		INSERT INTO receipts (owner_id, status, filename, content_type, image, draft, extraction_error)
+		SELECT owner_id, $2, filename, content_type, image, $3, $4
+		FROM receipt_jobs
+		WHERE id = $1
+		RETURNING id
+	`, id, enum.Draft, draftJSON, lastError).Scan(&receiptId)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE receipt_jobs
+		SET status = $2, receipt_id = $3, last_error = $4, image = NULL, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, status, receiptId, lastError)
+	if err != nil {
+		return 0, err
+	}
+	return receiptId, tx.Commit()
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrReceiptNotDraft is returned when a receipt that has already been confirmed or rejected
+// is changed.
+var ErrReceiptNotDraft = errors.New("receipt has already been confirmed or rejected")
+
+type IReceiptRepository interface {
+	FindAll(userId string, status enum.ReceiptStatus) ([]model.Receipt, error)
+	FindById(id int64, userId string) (*model.Receipt, error)
+	FindImage(id int64, userId string) ([]byte, string, error)
+	Save(receipt model.Receipt, image []byte) (int64, error)
+	UpdateDraft(id int64, userId string, draft model.Transaction) error
+	Confirm(id int64, userId string, transaction model.Transaction) (int64, error)
+	Reject(id int64, userId string) error
+}
+
+type databaseReceiptRepository struct {
+	db *sql.DB
+}
+
+func NewReceiptRepository(s database.Service) IReceiptRepository {
+	return &databaseReceiptRepository{
+		db: s.DB(),
+	}
+}
+
+// receiptColumns selects a receipt r without its image, with the transaction it was confirmed into.
+const receiptColumns = `r.id, r.owner_id, r.status, r.filename, r.content_type, r.draft, r.extraction_error,
+	(SELECT t.id FROM transactions t WHERE t.receipt_id = r.id), r.created_at, r.updated_at`
+
+func scanReceipt(row rowScanner) (model.Receipt, error) {
+	var r model.Receipt
+	var draft []byte
+	err := row.Scan(&r.ID, &r.OwnerId, &r.Status, &r.Filename, &r.ContentType, &draft, &r.ExtractionError,
+		&r.TransactionId, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(draft, &r.Draft); err != nil {
+		return r, fmt.Errorf("failed to read draft of receipt %d: %w", r.ID, err)
+	}
+	return r, nil
+}
+
+// FindAll returns the user's receipts with the status, oldest first.
+func (d *databaseReceiptRepository) FindAll(userId string, status enum.ReceiptStatus) ([]model.Receipt, error) {
+	rows, err := d.db.Query(`
+		SELECT `+receiptColumns+`
+		FROM receipts r
+		WHERE r.owner_id = $1 AND r.status = $2
+		ORDER BY r.created_at ASC, r.id ASC
+	`, userId, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := []model.Receipt{}
+	for rows.Next() {
+		r, err := scanReceipt(rows)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, rows.Err()
+}
+
+func (d *databaseReceiptRepository) FindById(id int64, userId string) (*model.Receipt, error) {
+	r, err := scanReceipt(d.db.QueryRow(`
+		SELECT `+receiptColumns+`
+		FROM receipts r
+		WHERE r.id = $1 AND r.owner_id = $2
+	`, id, userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("receipt not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// FindImage returns the uploaded image of the receipt and its content type.
+func (d *databaseReceiptRepository) FindImage(id int64, userId string) ([]byte, string, error) {
+	var image []byte
+	var contentType string
+	err := d.db.QueryRow(`SELECT image, content_type FROM receipts WHERE id = $1 AND owner_id = $2`, id, userId).
+		Scan(&image, &contentType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", fmt.Errorf("receipt not found")
+	}
+	return image, contentType, err
+}
+
+// Save stores a new draft receipt with its image and returns its id.
+func (d *databaseReceiptRepository) Save(receipt model.Receipt, image []byte) (int64, error) {
+	log.Println("Saving receipt:", receipt.Filename)
+
+	draft, err := json.Marshal(receipt.Draft)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = d.db.QueryRow(`
+		INSERT INTO receipts (owner_id, status, filename, content_type, image, draft, extraction_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, receipt.OwnerId, enum.Draft, receipt.Filename, receipt.ContentType, image, draft, receipt.ExtractionError).Scan(&id)
+	return id, err
+}
+
+// lockDraft locks the user's receipt for the rest of tx, it has to still be a draft.
+func lockDraft(tx *sql.Tx, id int64, userId string) error {
+	var status enum.ReceiptStatus
+	err := tx.QueryRow(`SELECT status FROM receipts WHERE id = $1 AND owner_id = $2 FOR UPDATE`, id, userId).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("receipt not found")
+	}
+	if err != nil {
+		return err
+	}
+	if status != enum.Draft {
+		return ErrReceiptNotDraft
+	}
+	return nil
+}
+
+// setReceiptStatus moves a receipt locked with lockDraft on.
+func setReceiptStatus(tx *sql.Tx, id int64, status enum.ReceiptStatus) error {
+	_, err := tx.Exec(`UPDATE receipts SET status = $2, updated_at = NOW() WHERE id = $1`, id, status)
+	return err
+}
+
+// UpdateDraft replaces the draft of a receipt that has not been confirmed or rejected yet.
+func (d *databaseReceiptRepository) UpdateDraft(id int64, userId string, draft model.Transaction) error {
+	draftJSON, err := json.Marshal(draft)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockDraft(tx, id, userId); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE receipts SET draft = $2, updated_at = NOW() WHERE id = $1`, id, draftJSON)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Confirm saves the transaction for the draft receipt, books it on its accounts and marks the
+// receipt confirmed, all or nothing. It returns the id of the new transaction.
+func (d *databaseReceiptRepository) Confirm(id int64, userId string, transaction model.Transaction) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := lockDraft(tx, id, userId); err != nil {
+		return 0, err
+	}
+
+	transaction.ReceiptId = &id
+	transactionId, err := insertTransactionRow(tx, &transaction)
+	if err != nil {
+		return 0, err
+	}
+	if err := applyToBalances(tx, transaction, 1); err != nil {
+		return 0, err
+	}
+	if err := setReceiptStatus(tx, id, enum.Confirmed); err != nil {
+		return 0, err
+	}
+	return transactionId, tx.Commit()
+}
+
+// Reject marks the draft receipt rejected, the image is kept.
+func (d *databaseReceiptRepository) Reject(id int64, userId string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockDraft(tx, id, userId); err != nil {
+		return err
+	}
+	if err := setReceiptStatus(tx, id, enum.Rejected); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
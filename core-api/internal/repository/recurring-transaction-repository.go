@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+type IRecurringTransactionRepository interface {
+	FindAll(userId string) []model.RecurringTransaction
+	FindById(id int64, userId string) (*model.RecurringTransaction, error)
+	FindDue(now time.Time) []model.RecurringTransaction
+	Save(recurring model.RecurringTransaction) error
+	Update(recurring model.RecurringTransaction, id int64) error
+	UpdateSchedule(id int64, occurrences int32, nextRun *time.Time) error
+	Delete(id int64, userId string) error
+}
+
+type databaseRecurringTransactionRepository struct {
+	db *sql.DB
+}
+
+const recurringTransactionColumns = `id, owner_id, title, price, currency, category_id, type, frequency, repeat_interval,
+		start_date, end_date, max_occurrences, occurrences, next_run`
+
+func NewRecurringTransactionRepository(s database.Service) IRecurringTransactionRepository {
+	return &databaseRecurringTransactionRepository{
+		db: s.DB(),
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecurringTransaction(row rowScanner) (model.RecurringTransaction, error) {
+	var r model.RecurringTransaction
+	err := row.Scan(
+		&r.ID,
+		&r.OwnerId,
+		&r.Title,
+		&r.Price.Amount,
+		&r.Price.Currency,
+		&r.CategoryId,
+		&r.Type,
+		&r.Frequency,
+		&r.Interval,
+		&r.StartDate,
+		&r.EndDate,
+		&r.MaxOccurrences,
+		&r.Occurrences,
+		&r.NextRun,
+	)
+	return r, err
+}
+
+func (d *databaseRecurringTransactionRepository) queryAll(query string, args ...any) []model.RecurringTransaction {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+
+	var recurring []model.RecurringTransaction
+	for rows.Next() {
+		r, err := scanRecurringTransaction(rows)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		recurring = append(recurring, r)
+	}
+	return recurring
+}
+
+func (d *databaseRecurringTransactionRepository) FindAll(userId string) []model.RecurringTransaction {
+	return d.queryAll(`
+		SELECT `+recurringTransactionColumns+`
+		FROM recurring_transactions
+		WHERE owner_id = $1
+		ORDER BY start_date ASC
+	`, userId)
+}
+
+func (d *databaseRecurringTransactionRepository) FindById(id int64, userId string) (*model.RecurringTransaction, error) {
+	row := d.db.QueryRow(`
+		SELECT `+recurringTransactionColumns+`
+		FROM recurring_transactions
+		WHERE id = $1 AND owner_id = $2
+	`, id, userId)
+
+	r, err := scanRecurringTransaction(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("recurring transaction not found")
+		}
+		return nil, fmt.Errorf("failed to scan recurring transaction: %v", err)
+	}
+
+	return &r, nil
+}
+
+// FindDue returns every rule, for every user, with an occurrence at or before now.
+func (d *databaseRecurringTransactionRepository) FindDue(now time.Time) []model.RecurringTransaction {
+	return d.queryAll(`
+		SELECT `+recurringTransactionColumns+`
+		FROM recurring_transactions
+		WHERE next_run IS NOT NULL
+		  AND next_run <= $1
+		ORDER BY next_run ASC
+	`, now)
+}
+
+func (d *databaseRecurringTransactionRepository) Save(recurring model.RecurringTransaction) error {
+	log.Println("Saving recurring transaction:", recurring.Title)
+	currency, err := resolveCurrency(d.db, recurring.Price.Currency, recurring.OwnerId)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(`
+		INSERT INTO recurring_transactions (owner_id, title, price, currency, category_id, type, frequency, repeat_interval,
+		                                    start_date, end_date, max_occurrences, occurrences, next_run)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`,
+		recurring.OwnerId, recurring.Title, recurring.Price.Amount, currency, recurring.CategoryId, recurring.Type,
+		recurring.Frequency, recurring.Interval, recurring.StartDate, recurring.EndDate, recurring.MaxOccurrences,
+		recurring.Occurrences, recurring.NextRun)
+	return err
+}
+
+func (d *databaseRecurringTransactionRepository) Update(recurring model.RecurringTransaction, id int64) error {
+	log.Println("Updating recurring transaction:", recurring)
+	currency, err := resolveCurrency(d.db, recurring.Price.Currency, recurring.OwnerId)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(`
+		UPDATE recurring_transactions
+		SET title = $1,
+		    price = $2,
+		    currency = $3,
+		    category_id = $4,
+		    type = $5,
+		    frequency = $6,
+		    repeat_interval = $7,
+		    start_date = $8,
+		    end_date = $9,
+		    max_occurrences = $10,
+		    next_run = $11
+		WHERE id = $12 AND owner_id = $13
+	`,
+		recurring.Title, recurring.Price.Amount, currency, recurring.CategoryId, recurring.Type, recurring.Frequency,
+		recurring.Interval, recurring.StartDate, recurring.EndDate, recurring.MaxOccurrences, recurring.NextRun, id,
+		recurring.OwnerId)
+	return err
+}
+
+func (d *databaseRecurringTransactionRepository) UpdateSchedule(id int64, occurrences int32, nextRun *time.Time) error {
+	_, err := d.db.Exec(`
+		UPDATE recurring_transactions
+		SET occurrences = $1,
+		    next_run = $2
+		WHERE id = $3
+	`, occurrences, nextRun, id)
+	return err
+}
+
+func (d *databaseRecurringTransactionRepository) Delete(id int64, userId string) error {
+	r, err := d.FindById(id, userId)
+	if r == nil {
+		return err
+	}
+
+	// transactions that were already materialized stay, only their link to the rule is dropped
+	_, err = d.db.Exec("DELETE FROM recurring_transactions WHERE id = $1 AND owner_id = $2", id, userId)
+	return err
+}
@@ -24,12 +24,13 @@ type databaseSavingRepository struct {
 
 func (d *databaseSavingRepository) FindAll(userId string, from time.Time, to time.Time) []model.Saving {
 	rows, err := d.db.Query(`
-		SELECT id, owner_id, amount, "from", "to"
-		FROM savings
-		WHERE owner_id = $1
-		  AND date_made >= $2
-		  AND date_made <= $3
-		ORDER BY date_made ASC
+		SELECT s.id, s.owner_id, s.amount, u.preferred_currency, s."from", s."to"
+		FROM savings s
+		JOIN users u ON u.id = s.owner_id
+		WHERE s.owner_id = $1
+		  AND s.date_made >= $2
+		  AND s.date_made <= $3
+		ORDER BY s.date_made ASC
 	`, userId, from, to)
 
 	if err != nil {
@@ -40,7 +41,7 @@ func (d *databaseSavingRepository) FindAll(userId string, from time.Time, to tim
 	var savings []model.Saving
 	for rows.Next() {
 		var t model.Saving
-		if err := rows.Scan(&t.ID, &t.OwnerId, &t.Amount, &t.From, &t.To); err != nil {
+		if err := rows.Scan(&t.ID, &t.OwnerId, &t.Amount.Amount, &t.Amount.Currency, &t.From, &t.To); err != nil {
 			log.Println(err)
 			continue
 		}
@@ -57,7 +58,10 @@ func NewSavingRepository(s database.Service) ISavingRepository {
 
 func (d *databaseSavingRepository) FindById(id int64, userId string) (*model.Saving, error) {
 	row := d.db.QueryRow(
-		`SELECT * FROM savings WHERE id = $1 and owner_id = $2`,
+		`SELECT s.id, s.owner_id, s.amount, u.preferred_currency, s."from", s."to"
+		 FROM savings s
+		 JOIN users u ON u.id = s.owner_id
+		 WHERE s.id = $1 and s.owner_id = $2`,
 		id, userId,
 	)
 
@@ -65,7 +69,8 @@ func (d *databaseSavingRepository) FindById(id int64, userId string) (*model.Sav
 	err := row.Scan(
 		&saving.ID,
 		&saving.OwnerId,
-		&saving.Amount,
+		&saving.Amount.Amount,
+		&saving.Amount.Currency,
 		&saving.From,
 		&saving.To,
 	)
@@ -91,7 +96,7 @@ func (d *databaseSavingRepository) Save(saving model.Saving) error {
 								INSERT INTO savings (id, owner_id, amount, from, to)
     					        VALUES ($1, $2, $3, $4, $5)
     					        `,
-		saving.ID, saving.OwnerId, saving.Amount, saving.From, saving.To)
+		saving.ID, saving.OwnerId, saving.Amount.Amount, saving.From, saving.To)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -117,7 +122,7 @@ func (d *databaseSavingRepository) Update(saving model.Saving, id int64) error {
              from = $2,
              to = $3
          WHERE id = $4`,
-		saving.Amount, saving.From, saving.To, id)
+		saving.Amount.Amount, saving.From, saving.To, id)
 
 	if err != nil {
 		return err
@@ -2,18 +2,38 @@ package repository
 
 import (
 	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/money"
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 type IStatisticsRepository interface {
-	FindTotalIncomeAndExpense(userId string, from time.Time, to time.Time) (float32, float32, error)
-	FindPercentageSpentPerCategory(userId string, from time.Time, to time.Time) (map[string]float32, float32, float32, error)
-	FindTotalSpentPerMonth(userId string, from time.Time, to time.Time) (map[int32]float32, error)
-	FindAverage(userId string, from time.Time, to time.Time) (float32, float32, error)
+	FindTotalIncomeAndExpense(userId string, from time.Time, to time.Time) (money.Money, money.Money, error)
+	FindPercentageSpentPerCategory(userId string, from time.Time, to time.Time, rollup bool) (map[string]decimal.Decimal, money.Money, money.Money, error)
+	FindTotalSpentPerCategory(userId string, from time.Time, to time.Time) (map[int64]money.Money, error)
+	FindTotalSpentPerMonth(userId string, from time.Time, to time.Time) (map[int32]money.Money, error)
+	FindAverage(userId string, from time.Time, to time.Time) (money.Money, money.Money, error)
 }
 
+// convertedPrice is the price of a transaction in the preferred currency of the user ($1) at
+// the rate of the day it was made, every statistic is reported in that currency.
+const convertedPrice = `convert_amount(price, currency, (SELECT preferred_currency FROM users WHERE id = $1), date_made::date)`
+
+// expensesPerCategoryQuery sums a user's expenses per category_id in a date range. It is
+// the aggregation behind both the pie statistics and the budget spending.
+const expensesPerCategoryQuery = `
+	SELECT category_id, SUM(` + convertedPrice + `) AS total_per_category
+	FROM transactions
+	WHERE owner_id = $1
+	  AND type = 'Expense'
+	  AND date_made BETWEEN $2 AND $3
+	GROUP BY category_id`
+
 type databaseStatisticsRepository struct {
 	db *sql.DB
 }
@@ -23,114 +43,166 @@ func NewStatisticsRepository(s database.Service) IStatisticsRepository {
 		db: s.DB(),
 	}
 }
-func (r *databaseStatisticsRepository) FindTotalIncomeAndExpense(userId string, from time.Time, to time.Time) (float32, float32, error) {
+
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// findPreferredCurrency returns the currency the user's amounts are kept in.
+func findPreferredCurrency(q rowQuerier, userId string) (enum.Currency, error) {
+	var currency enum.Currency
+	err := q.QueryRow(`SELECT preferred_currency FROM users WHERE id = $1`, userId).Scan(&currency)
+	return currency, err
+}
+
+func (r *databaseStatisticsRepository) FindTotalIncomeAndExpense(userId string, from time.Time, to time.Time) (money.Money, money.Money, error) {
 	row := r.db.QueryRow(`
 		WITH total_expense AS (
-			SELECT COALESCE(SUM(price), 0) AS total
+			SELECT COALESCE(SUM(`+convertedPrice+`), 0) AS total
 			FROM transactions
 			WHERE owner_id = $1 AND type = 'Expense' AND date_made BETWEEN $2 AND $3
 		),
 		total_income AS (
-			SELECT COALESCE(SUM(price), 0) AS total
+			SELECT COALESCE(SUM(`+convertedPrice+`), 0) AS total
 			FROM transactions
 			WHERE owner_id = $1 AND type = 'Income' AND date_made BETWEEN $2 AND $3
 		)
 		SELECT 
 			(SELECT total FROM total_expense),
-			(SELECT total FROM total_income)
+			(SELECT total FROM total_income),
+			(SELECT preferred_currency FROM users WHERE id = $1)
 	`, userId, from, to)
 
-	var totalExpense float32
-	var totalIncome float32
-	if err := row.Scan(&totalExpense, &totalIncome); err != nil {
-		return 0, 0, err
+	var totalExpense money.Money
+	var totalIncome money.Money
+	if err := row.Scan(&totalExpense.Amount, &totalIncome.Amount, &totalExpense.Currency); err != nil {
+		return money.Money{}, money.Money{}, err
 	}
+	totalIncome.Currency = totalExpense.Currency
 	return totalExpense, totalIncome, nil
 
 }
 
-func (r *databaseStatisticsRepository) FindPercentageSpentPerCategory(userId string, from time.Time, to time.Time) (map[string]float32, float32, float32, error) {
+func (r *databaseStatisticsRepository) FindPercentageSpentPerCategory(userId string, from time.Time, to time.Time, rollup bool) (map[string]decimal.Decimal, money.Money, money.Money, error) {
 	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{
 		Isolation: sql.LevelRepeatableRead, // needed so all the reads see the same snapshot of the data when the transaction is being ran.
 		ReadOnly:  true,
 	})
 	if err != nil {
-		return nil, 0, 0, err
+		return nil, money.Money{}, money.Money{}, err
 	}
 	defer tx.Rollback()
 
+	currency, err := findPreferredCurrency(tx, userId)
+	if err != nil {
+		return nil, money.Money{}, money.Money{}, err
+	}
+
+	// category_roots maps every category to its top level ancestor, with rollup the
+	// expenses of child categories are reported under their root (Restaurants -> Food)
 	query := `
-		WITH total_expense AS (
-			SELECT owner_id, SUM(price) AS total_expense
-			FROM transactions
-			WHERE owner_id = $1
-			  AND type = 'Expense'
-			  AND date_made BETWEEN $2 AND $3
-			GROUP BY owner_id
+		WITH RECURSIVE category_roots AS (
+			SELECT id, id AS root_id
+			FROM categories
+			WHERE parent_id IS NULL
+			UNION ALL
+			SELECT c.id, cr.root_id
+			FROM categories c
+			JOIN category_roots cr
+				ON c.parent_id = cr.id
+		),
+		expenses_per_category AS (` + expensesPerCategoryQuery + `),
+		total_expense AS (
+			SELECT COALESCE(SUM(total_per_category), 0) AS total_expense
+			FROM expenses_per_category
 		),
 		total_income AS (
-			SELECT owner_id, SUM(price) AS total_income
+			SELECT COALESCE(SUM(` + convertedPrice + `), 0) AS total_income
 			FROM transactions
 			WHERE owner_id = $1
 			  AND type = 'Income'
 			  AND date_made BETWEEN $2 AND $3
-			GROUP BY owner_id
 		)
 		SELECT 
-			t.owner_id,
+			c.id,
 			c.name,
-			SUM(t.price) AS total_per_category,
-			(SUM(t.price) / COALESCE(te.total_expense, 1)) * 100.0 AS percentage_per_category,
-			COALESCE(te.total_expense, 0) AS total_expense,
-			COALESCE(ti.total_income, 0) AS total_income
-		FROM transactions t
-		LEFT JOIN total_expense te
-			ON t.owner_id = te.owner_id
+			SUM(e.total_per_category) AS total_per_category,
+			ROUND(SUM(e.total_per_category) * 100.0 / COALESCE(NULLIF(te.total_expense, 0), 1), 2) AS percentage_per_category,
+			te.total_expense,
+			ti.total_income
+		FROM expenses_per_category e
+		JOIN category_roots cr
+			ON cr.id = e.category_id
 		JOIN categories c
-			ON c.id = t.category_id
-		LEFT JOIN total_income ti
-			ON t.owner_id = ti.owner_id
-		WHERE 
-			t.owner_id = $1
-			AND t.type = 'Expense'
-			AND t.date_made BETWEEN $2 AND $3
-		GROUP BY t.owner_id, c.name, te.total_expense, ti.total_income;
+			ON c.id = CASE WHEN $4 THEN cr.root_id ELSE e.category_id END
+		CROSS JOIN total_expense te
+		CROSS JOIN total_income ti
+		GROUP BY c.id, c.name, te.total_expense, ti.total_income
+		ORDER BY c.id;
 `
 
-	rows, err := tx.Query(query, userId, from, to)
+	rows, err := tx.Query(query, userId, from, to, rollup)
 	if err != nil {
-		return nil, 0, 0, err
+		return nil, money.Money{}, money.Money{}, err
 	}
 	defer rows.Close()
 
-	percentages := make(map[string]float32)
-	var totalExpense float32
-	var totalIncome float32
+	percentages := make(map[string]decimal.Decimal)
+	totalExpense := money.Zero(currency)
+	totalIncome := money.Zero(currency)
 
 	for rows.Next() {
-		var ownerId string
+		var id int64
 		var category string
-		var total float32
-		var percentage float32
-		var totalUserExpense float32
-		var totalUserIncome float32
+		var total decimal.Decimal
+		var percentage decimal.Decimal
 
-		if err := rows.Scan(&ownerId, &category, &total, &percentage, &totalUserExpense, &totalUserIncome); err != nil {
-			return nil, 0, 0, err
+		if err := rows.Scan(&id, &category, &total, &percentage, &totalExpense.Amount, &totalIncome.Amount); err != nil {
+			return nil, money.Money{}, money.Money{}, err
+		}
+		// a user's category can be named like a default one, each keeps its own slice
+		if _, taken := percentages[category]; taken {
+			category = fmt.Sprintf("%s (%d)", category, id)
 		}
 		percentages[category] = percentage
-		totalExpense = totalUserExpense
-		totalIncome = totalUserIncome
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, 0, 0, err
+		return nil, money.Money{}, money.Money{}, err
 	}
 
 	return percentages, totalExpense, totalIncome, nil
 }
 
-func (r *databaseStatisticsRepository) FindTotalSpentPerMonth(userId string, from time.Time, to time.Time) (map[int32]float32, error) {
+func (r *databaseStatisticsRepository) FindTotalSpentPerCategory(userId string, from time.Time, to time.Time) (map[int64]money.Money, error) {
+	currency, err := findPreferredCurrency(r.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(expensesPerCategoryQuery, userId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spentPerCategory := make(map[int64]money.Money)
+	for rows.Next() {
+		var categoryId sql.NullInt64
+		var total decimal.Decimal
+
+		if err := rows.Scan(&categoryId, &total); err != nil {
+			return nil, err
+		}
+		if categoryId.Valid {
+			spentPerCategory[categoryId.Int64] = money.New(total, currency)
+		}
+	}
+
+	return spentPerCategory, rows.Err()
+}
+
+func (r *databaseStatisticsRepository) FindTotalSpentPerMonth(userId string, from time.Time, to time.Time) (map[int32]money.Money, error) {
 	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{
 		Isolation: sql.LevelRepeatableRead,
 		ReadOnly:  true,
@@ -140,10 +212,15 @@ func (r *databaseStatisticsRepository) FindTotalSpentPerMonth(userId string, fro
 	}
 	defer tx.Rollback()
 
+	currency, err := findPreferredCurrency(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT 
 			EXTRACT(month FROM date_made) AS month,
-			SUM(price) AS value_spent
+			SUM(` + convertedPrice + `) AS value_spent
 		FROM transactions
 		WHERE owner_id = $1
 		  AND type = 'Expense'
@@ -158,17 +235,17 @@ func (r *databaseStatisticsRepository) FindTotalSpentPerMonth(userId string, fro
 	}
 	defer rows.Close()
 
-	spentPerMonth := make(map[int32]float32)
+	spentPerMonth := make(map[int32]money.Money)
 
 	for rows.Next() {
 		var month int32
-		var valueSpent float32
+		var valueSpent decimal.Decimal
 
 		if err := rows.Scan(&month, &valueSpent); err != nil {
 			return nil, err
 		}
 
-		spentPerMonth[month] = valueSpent
+		spentPerMonth[month] = money.New(valueSpent, currency)
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -178,19 +255,19 @@ func (r *databaseStatisticsRepository) FindTotalSpentPerMonth(userId string, fro
 	return spentPerMonth, nil
 }
 
-func (r *databaseStatisticsRepository) FindAverage(userId string, from time.Time, to time.Time) (float32, float32, error) {
+func (r *databaseStatisticsRepository) FindAverage(userId string, from time.Time, to time.Time) (money.Money, money.Money, error) {
 	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{
 		Isolation: sql.LevelRepeatableRead,
 		ReadOnly:  true,
 	})
 	if err != nil {
-		return 0, 0, err
+		return money.Money{}, money.Money{}, err
 	}
 	defer tx.Rollback()
 
 	query := `
 	WITH average_expense AS (
-		SELECT owner_id, COALESCE(AVG(price), 0) AS average_expense
+		SELECT owner_id, COALESCE(ROUND(AVG(` + convertedPrice + `), 2), 0) AS average_expense
 		FROM transactions
 		WHERE owner_id = $1
 		  AND type = 'Expense'
@@ -198,7 +275,7 @@ func (r *databaseStatisticsRepository) FindAverage(userId string, from time.Time
 		GROUP BY owner_id
 	),
 	average_income AS (
-		SELECT owner_id, COALESCE(AVG(price), 0) AS average_income
+		SELECT owner_id, COALESCE(ROUND(AVG(` + convertedPrice + `), 2), 0) AS average_income
 		FROM transactions
 		WHERE owner_id = $1
 		  AND type = 'Income'
@@ -213,8 +290,10 @@ func (r *databaseStatisticsRepository) FindAverage(userId string, from time.Time
 	SELECT 
 		b.owner_id,
 		COALESCE(ae.average_expense, 0) AS average_expense,
-		COALESCE(ai.average_income, 0) AS average_income
+		COALESCE(ai.average_income, 0) AS average_income,
+		u.preferred_currency
 	FROM base b
+	JOIN users u ON u.id = b.owner_id
 	LEFT JOIN average_expense ae ON b.owner_id = ae.owner_id
 	LEFT JOIN average_income ai ON b.owner_id = ai.owner_id;
 	`
@@ -222,15 +301,16 @@ func (r *databaseStatisticsRepository) FindAverage(userId string, from time.Time
 	row := tx.QueryRow(query, userId, from, to)
 
 	var ownerId string
-	var averageExpense float32
-	var averageIncome float32
+	var averageExpense money.Money
+	var averageIncome money.Money
 
-	if err := row.Scan(&ownerId, &averageExpense, &averageIncome); err != nil {
-		return 0, 0, err
+	if err := row.Scan(&ownerId, &averageExpense.Amount, &averageIncome.Amount, &averageExpense.Currency); err != nil {
+		return money.Money{}, money.Money{}, err
 	}
+	averageIncome.Currency = averageExpense.Currency
 
 	if err := tx.Commit(); err != nil {
-		return 0, 0, err
+		return money.Money{}, money.Money{}, err
 	}
 
 	return averageExpense, averageIncome, nil
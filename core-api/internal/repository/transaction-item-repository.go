@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/model"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+type ITransactionItemRepository interface {
+	FindAll(transactionId int64, userId string) ([]model.TransactionItem, error)
+	ReplaceAll(transactionId int64, userId string, items []model.TransactionItem) error
+}
+
+type databaseTransactionItemRepository struct {
+	db *sql.DB
+}
+
+func NewTransactionItemRepository(s database.Service) ITransactionItemRepository {
+	return &databaseTransactionItemRepository{
+		db: s.DB(),
+	}
+}
+
+// FindAll returns the items of the user's transaction in receipt order, their amounts in the
+// currency of the transaction.
+func (d *databaseTransactionItemRepository) FindAll(transactionId int64, userId string) ([]model.TransactionItem, error) {
+	rows, err := d.db.Query(`
+		SELECT i.id, i.transaction_id, i.name, i.quantity, i.unit_price, i.total, i.vat_rate, t.currency
+		FROM transaction_items i
+		JOIN transactions t ON t.id = i.transaction_id
+		WHERE i.transaction_id = $1 AND t.owner_id = $2
+		ORDER BY i.position ASC
+	`, transactionId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.TransactionItem{}
+	for rows.Next() {
+		var item model.TransactionItem
+		err := rows.Scan(&item.ID, &item.TransactionId, &item.Name, &item.Quantity, &item.UnitPrice.Amount,
+			&item.Total.Amount, &item.VatRate, &item.UnitPrice.Currency)
+		if err != nil {
+			return nil, err
+		}
+		item.Total.Currency = item.UnitPrice.Currency
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ReplaceAll replaces the items of the user's transaction, the price stays as it is.
+func (d *databaseTransactionItemRepository) ReplaceAll(transactionId int64, userId string, items []model.TransactionItem) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(`SELECT true FROM transactions WHERE id = $1 AND owner_id = $2 FOR UPDATE`, transactionId, userId).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("transaction not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM transaction_items WHERE transaction_id = $1`, transactionId); err != nil {
+		return err
+	}
+	if err := insertTransactionItems(tx, transactionId, items); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertTransactionItems(e execer, transactionId int64, items []model.TransactionItem) error {
+	for position, item := range items {
+		_, err := e.Exec(`
+			INSERT INTO transaction_items (transaction_id, position, name, quantity, unit_price, total, vat_rate)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, transactionId, position, item.Name, item.Quantity, item.UnitPrice.Amount, item.Total.Amount, item.VatRate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
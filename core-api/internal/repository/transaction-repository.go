@@ -4,17 +4,26 @@ import (
 	"SmartSpend/internal/database"
 	"SmartSpend/internal/domain/enum"
 	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// ErrDuplicateTransaction is returned for a transaction the bank id of which has already been
+// imported into the same account.
+var ErrDuplicateTransaction = errors.New("transaction has already been imported")
+
 type ITransactionRepository interface {
 	FindAll(userId string, from time.Time, to time.Time) []model.Transaction
 	FindById(id int64, userId string) (*model.Transaction, error)
 	Save(transaction model.Transaction) error
+	SaveBatch(transactions []model.Transaction) (int, error)
+	Export(userId string, from time.Time, to time.Time, fn func(model.TransactionExport) error) error
 	Update(transaction model.Transaction, id int64) error
 	Delete(id int64, userId string) error
 }
@@ -23,85 +32,147 @@ type databaseTransactionRepository struct {
 	db *sql.DB
 }
 
+// convertedPriceColumns selects the price of t in the preferred currency of its owner u.
+const convertedPriceColumns = `convert_amount(t.price, t.currency, u.preferred_currency, t.date_made::date), u.preferred_currency`
+
 func (d *databaseTransactionRepository) Save(transaction model.Transaction) error {
 	log.Println("Saving transaction:", transaction.Title)
 
-	if transaction.Type == enum.Income {
-		tx, err := d.db.Begin()
-		if err != nil {
-			return err
-		}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-		_, err = tx.Exec(`
-								INSERT INTO transactions (title, price, date_made, owner_id, category_id, type)
-    					        VALUES ($1, $2, $3, $4, NULL, $5)
-    					        `,
-			transaction.Title, transaction.Price, transaction.DateMade, transaction.OwnerId, transaction.Type)
-		if err != nil {
-			tx.Rollback()
-			return err
-		}
-		_, err = tx.Exec(`
-    							UPDATE users
-							    SET balance = balance + $1
-    							WHERE id = $2
-								`,
-			transaction.Price, transaction.OwnerId)
-		if err != nil {
-			tx.Rollback()
-			return err
-		}
+	if err := insertTransaction(tx, transaction); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-		err = tx.Commit()
-		if err != nil {
-			return err
-		}
+// insertTransaction stores the transaction and books it on its accounts. Missing currency
+// and account default to the owner's preferred currency and default account.
+func insertTransaction(tx *sql.Tx, transaction model.Transaction) error {
+	if _, err := insertTransactionRow(tx, &transaction); err != nil {
 		return err
-	} else { // Expense
-		tx, err := d.db.Begin()
-		if err != nil {
-			return err
-		}
+	}
+	return applyToBalances(tx, transaction, 1)
+}
 
-		_, err = tx.Exec(`
-								INSERT INTO transactions (title, price, date_made, owner_id, category_id, type)
-    					        VALUES ($1, $2, $3, $4, $5, $6)
-    					        `,
-			transaction.Title, transaction.Price, transaction.DateMade, transaction.OwnerId, transaction.CategoryId, transaction.Type)
-		if err != nil {
-			tx.Rollback()
-			return err
-		}
+// insertTransactionRow stores the transaction and its items without touching any balance and
+// returns its id.
+// A transaction with a bank id that is already stored is skipped with ErrDuplicateTransaction.
+func insertTransactionRow(tx *sql.Tx, transaction *model.Transaction) (int64, error) {
+	var err error
+	transaction.Price.Currency, err = resolveCurrency(tx, transaction.Price.Currency, transaction.OwnerId)
+	if err != nil {
+		return 0, err
+	}
+	if err := resolveAccounts(tx, transaction); err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO transactions (title, price, currency, date_made, owner_id, category_id, type, recurring_id,
+		                          account_id, transfer_account_id, external_id, receipt_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (account_id, external_id) WHERE external_id IS NOT NULL DO NOTHING
+		RETURNING id
+	`,
+		transaction.Title, transaction.Price.Amount, transaction.Price.Currency, transaction.DateMade,
+		transaction.OwnerId, categoryOf(*transaction), transaction.Type, transaction.RecurringId,
+		transaction.AccountId, transaction.TransferAccountId, transaction.ExternalId, transaction.ReceiptId).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDuplicateTransaction
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, insertTransactionItems(tx, id, transaction.Items)
+}
 
-		_, err = tx.Exec(`
-    							UPDATE users
-							    SET balance = balance - $1
-    							WHERE id = $2
-								`,
-			transaction.Price, transaction.OwnerId)
+// SaveBatch stores all transactions or none of them and returns how many were saved,
+// transactions that have already been imported are skipped. The balances are adjusted once
+// for the whole batch instead of once per transaction, so only incomes and expenses are accepted.
+func (d *databaseTransactionRepository) SaveBatch(transactions []model.Transaction) (int, error) {
+	log.Printf("Saving %d transactions", len(transactions))
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, 0, len(transactions))
+	for _, transaction := range transactions {
+		if transaction.Type != enum.Income && transaction.Type != enum.Expense {
+			return 0, fmt.Errorf("only incomes and expenses can be saved in a batch")
+		}
+		id, err := insertTransactionRow(tx, &transaction)
+		if errors.Is(err, ErrDuplicateTransaction) {
+			continue
+		}
 		if err != nil {
-			tx.Rollback()
-			return err
+			return 0, err
 		}
+		ids = append(ids, id)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE accounts a
+		SET balance = a.balance + b.delta
+		FROM (
+			SELECT t.account_id,
+			       SUM(CASE WHEN t.type = 'Income' THEN 1 ELSE -1 END
+			           * convert_amount(t.price, t.currency, acc.currency, t.date_made::date)) AS delta
+			FROM transactions t
+			JOIN accounts acc ON acc.id = t.account_id
+			WHERE t.id = ANY($1)
+			GROUP BY t.account_id
+		) b
+		WHERE a.id = b.account_id
+	`, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
 
-		err = tx.Commit()
+// categoryOf returns the category to store, only expenses are categorized.
+func categoryOf(transaction model.Transaction) *int64 {
+	if transaction.Type != enum.Expense {
+		return nil
+	}
+	return transaction.CategoryId
+}
+
+// resolveAccounts books a transaction without an account on the owner's default account
+// and makes sure only transfers have a destination account.
+func resolveAccounts(q rowQuerier, transaction *model.Transaction) error {
+	if transaction.AccountId == nil {
+		accountId, err := findDefaultAccount(q, transaction.OwnerId)
 		if err != nil {
 			return err
 		}
-		return err
+		transaction.AccountId = &accountId
+	}
+
+	if transaction.Type != enum.Transfer {
+		transaction.TransferAccountId = nil
+	} else if transaction.TransferAccountId == nil {
+		return fmt.Errorf("transfer needs a destination account")
 	}
+	return nil
 }
 
 func (d *databaseTransactionRepository) Update(transaction model.Transaction, id int64) error {
 	log.Println("Updating transaction:", transaction)
 
-	var categoryId interface{}
-	if transaction.CategoryId == nil {
-		categoryId = nil
-	} else {
-		categoryId = *transaction.CategoryId
-	}
-
 	tx, err := d.db.Begin()
 	if err != nil {
 		return err
@@ -112,10 +183,22 @@ func (d *databaseTransactionRepository) Update(transaction model.Transaction, id
 		}
 	}()
 
-	var oldPrice float32
-	var oldType string
-	var ownerId string
-	err = tx.QueryRow(`SELECT price, type, owner_id FROM transactions WHERE id = $1`, id).Scan(&oldPrice, &oldType, &ownerId)
+	var old model.Transaction
+	err = tx.QueryRow(`
+		SELECT price, currency, type, date_made, owner_id, account_id, transfer_account_id
+		FROM transactions
+		WHERE id = $1
+	`, id).Scan(&old.Price.Amount, &old.Price.Currency, &old.Type, &old.DateMade, &old.OwnerId, &old.AccountId, &old.TransferAccountId)
+	if err != nil {
+		return err
+	}
+
+	transaction.OwnerId = old.OwnerId
+	transaction.Price.Currency, err = resolveCurrency(tx, transaction.Price.Currency, old.OwnerId)
+	if err != nil {
+		return err
+	}
+	err = resolveAccounts(tx, &transaction)
 	if err != nil {
 		return err
 	}
@@ -124,34 +207,26 @@ func (d *databaseTransactionRepository) Update(transaction model.Transaction, id
 		`UPDATE transactions
          SET title = $1,
              price = $2,
-             date_made = $3,
-             category_id = $4,
-             "type" = $5
-         WHERE id = $6`,
-		transaction.Title, transaction.Price, transaction.DateMade,
-		categoryId, transaction.Type, id,
+             currency = $3,
+             date_made = $4,
+             category_id = $5,
+             "type" = $6,
+             account_id = $7,
+             transfer_account_id = $8
+         WHERE id = $9`,
+		transaction.Title, transaction.Price.Amount, transaction.Price.Currency, transaction.DateMade,
+		categoryOf(transaction), transaction.Type, transaction.AccountId, transaction.TransferAccountId, id,
 	)
 	if err != nil {
 		return err
 	}
 
-	var balanceAdjustment float32
-	if oldType == "Expense" {
-		balanceAdjustment += oldPrice
-	} else if oldType == "Income" {
-		balanceAdjustment -= oldPrice
-	}
-
-	if transaction.Type == "Expense" {
-		balanceAdjustment -= transaction.Price
-	} else if transaction.Type == "Income" {
-		balanceAdjustment += transaction.Price
+	// undo the old transaction and apply the new one, each at the rate of its own date
+	err = applyToBalances(tx, old, -1)
+	if err != nil {
+		return err
 	}
-
-	_, err = tx.Exec(
-		`UPDATE users SET balance = balance + $1 WHERE id = $2`,
-		balanceAdjustment, ownerId,
-	)
+	err = applyToBalances(tx, transaction, 1)
 	if err != nil {
 		return err
 	}
@@ -160,6 +235,50 @@ func (d *databaseTransactionRepository) Update(transaction model.Transaction, id
 	return err
 }
 
+// applyToBalances books the transaction on its accounts, with sign -1 it is reverted. Every
+// account moves by the price converted into its own currency at the rate of the day the
+// transaction was made; a transfer is neither income nor expense and only moves money.
+func applyToBalances(tx *sql.Tx, transaction model.Transaction, sign int) error {
+	switch transaction.Type {
+	case enum.Income:
+		return adjustAccountBalance(tx, *transaction.AccountId, sign, transaction.Price, transaction.DateMade)
+	case enum.Expense:
+		return adjustAccountBalance(tx, *transaction.AccountId, -sign, transaction.Price, transaction.DateMade)
+	case enum.Transfer:
+		if err := adjustAccountBalance(tx, *transaction.AccountId, -sign, transaction.Price, transaction.DateMade); err != nil {
+			return err
+		}
+		return adjustAccountBalance(tx, *transaction.TransferAccountId, sign, transaction.Price, transaction.DateMade)
+	default:
+		return nil
+	}
+}
+
+func adjustAccountBalance(tx *sql.Tx, accountId int64, sign int, price money.Money, dateMade time.Time) error {
+	_, err := tx.Exec(`
+		UPDATE accounts
+		SET balance = balance + $1 * convert_amount($2, $3, currency, $4::date)
+		WHERE id = $5
+	`, sign, price.Amount, price.Currency, dateMade, accountId)
+	return err
+}
+
+// resolveCurrency defaults an amount without a currency, e.g. from a receipt, to the
+// owner's preferred one.
+func resolveCurrency(q rowQuerier, currency enum.Currency, ownerId string) (enum.Currency, error) {
+	if currency != "" {
+		return currency, nil
+	}
+	return findPreferredCurrency(q, ownerId)
+}
+
+// IsUniqueViolation reports whether err was caused by a unique constraint, e.g. a
+// recurring occurrence that has already been materialized.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 func NewTransactionRepository(s database.Service) ITransactionRepository {
 	return &databaseTransactionRepository{
 		db: s.DB(),
@@ -168,12 +287,15 @@ func NewTransactionRepository(s database.Service) ITransactionRepository {
 
 func (d *databaseTransactionRepository) FindAll(userId string, from time.Time, to time.Time) []model.Transaction {
 	rows, err := d.db.Query(`
-		SELECT id, title, price, date_made, owner_id, category_id, "type"
-		FROM transactions
-		WHERE owner_id = $1
-		  AND date_made >= $2
-		  AND date_made <= $3
-		ORDER BY date_made ASC
+		SELECT t.id, t.title, t.price, t.currency, `+convertedPriceColumns+`, t.date_made, t.owner_id, t.category_id,
+		       t."type", t.recurring_id, t.account_id, t.transfer_account_id, t.external_id, t.receipt_id
+		FROM transactions t
+		JOIN users u
+			ON u.id = t.owner_id
+		WHERE t.owner_id = $1
+		  AND t.date_made >= $2
+		  AND t.date_made <= $3
+		ORDER BY t.date_made ASC
 	`, userId, from, to)
 
 	if err != nil {
@@ -184,7 +306,7 @@ func (d *databaseTransactionRepository) FindAll(userId string, from time.Time, t
 	var transactions []model.Transaction
 	for rows.Next() {
 		var t model.Transaction
-		if err := rows.Scan(&t.ID, &t.Title, &t.Price, &t.DateMade, &t.OwnerId, &t.CategoryId, &t.Type); err != nil {
+		if err := rows.Scan(&t.ID, &t.Title, &t.Price.Amount, &t.Price.Currency, &t.ConvertedPrice.Amount, &t.ConvertedPrice.Currency, &t.DateMade, &t.OwnerId, &t.CategoryId, &t.Type, &t.RecurringId, &t.AccountId, &t.TransferAccountId, &t.ExternalId, &t.ReceiptId); err != nil {
 			log.Println(err)
 			continue
 		}
@@ -195,7 +317,11 @@ func (d *databaseTransactionRepository) FindAll(userId string, from time.Time, t
 
 func (d *databaseTransactionRepository) FindById(id int64, userId string) (*model.Transaction, error) {
 	row := d.db.QueryRow(
-		`SELECT * FROM transactions WHERE id = $1 and owner_id = $2`,
+		`SELECT t.id, t.title, t.price, t.currency, `+convertedPriceColumns+`, t.date_made, t.owner_id, t.category_id,
+		        t."type", t.recurring_id, t.account_id, t.transfer_account_id, t.external_id, t.receipt_id
+		 FROM transactions t
+		 JOIN users u ON u.id = t.owner_id
+		 WHERE t.id = $1 and t.owner_id = $2`,
 		id, userId,
 	)
 
@@ -203,11 +329,19 @@ func (d *databaseTransactionRepository) FindById(id int64, userId string) (*mode
 	err := row.Scan(
 		&transaction.ID,
 		&transaction.Title,
-		&transaction.Price,
+		&transaction.Price.Amount,
+		&transaction.Price.Currency,
+		&transaction.ConvertedPrice.Amount,
+		&transaction.ConvertedPrice.Currency,
 		&transaction.DateMade,
 		&transaction.OwnerId,
 		&transaction.CategoryId,
 		&transaction.Type,
+		&transaction.RecurringId,
+		&transaction.AccountId,
+		&transaction.TransferAccountId,
+		&transaction.ExternalId,
+		&transaction.ReceiptId,
 	)
 
 	if err != nil {
@@ -241,17 +375,63 @@ func (d *databaseTransactionRepository) Delete(id int64, userId string) error {
 		return err
 	}
 
-	var balanceAdjustment float32
-	if t.Type == "Expense" {
-		balanceAdjustment += t.Price
-	} else if t.Type == "Income" {
-		balanceAdjustment = -t.Price
+	err = applyToBalances(tx, *t, -1)
+	if err != nil {
+		return err
 	}
 
-	_, err = tx.Exec("UPDATE users SET balance = balance + $1 WHERE id = $2", balanceAdjustment, userId)
+	return tx.Commit()
+}
+
+// Export calls fn for every transaction of the user between from and to, oldest first. The
+// rows are read from the cursor one by one instead of being collected like FindAll does.
+// The running balance starts with the opening balances of the accounts and moves with every
+// income and expense at the rate of the day it was made; transfers do not change it.
+func (d *databaseTransactionRepository) Export(userId string, from time.Time, to time.Time, fn func(model.TransactionExport) error) error {
+	rows, err := d.db.Query(`
+		WITH opening AS (
+			SELECT COALESCE(SUM(convert_amount(a.opening_balance, a.currency, u.preferred_currency, a.created_at::date)), 0) AS balance
+			FROM accounts a
+			JOIN users u ON u.id = a.owner_id
+			WHERE a.owner_id = $1
+		), ledger AS (
+			SELECT t.id, t.title, t.price, t.currency, `+convertedPriceColumns+`, t.date_made, t.owner_id, t.category_id,
+			       t."type", t.recurring_id, t.account_id, t.transfer_account_id, t.external_id,
+			       c.name AS category_name, acc.name AS account_name, ta.name AS transfer_account_name,
+			       (SELECT balance FROM opening)
+			           + SUM(CASE t."type" WHEN 'Income' THEN 1 WHEN 'Expense' THEN -1 ELSE 0 END
+			                 * convert_amount(t.price, t.currency, u.preferred_currency, t.date_made::date))
+			             OVER (ORDER BY t.date_made, t.id) AS running_balance
+			FROM transactions t
+			JOIN users u ON u.id = t.owner_id
+			JOIN accounts acc ON acc.id = t.account_id
+			LEFT JOIN accounts ta ON ta.id = t.transfer_account_id
+			LEFT JOIN categories c ON c.id = t.category_id
+			WHERE t.owner_id = $1
+			  AND t.date_made <= $3
+		)
+		SELECT *
+		FROM ledger
+		WHERE date_made >= $2
+		ORDER BY date_made, id
+	`, userId, from, to)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
-	return tx.Commit()
+	for rows.Next() {
+		var e model.TransactionExport
+		err := rows.Scan(&e.ID, &e.Title, &e.Price.Amount, &e.Price.Currency, &e.ConvertedPrice.Amount, &e.ConvertedPrice.Currency,
+			&e.DateMade, &e.OwnerId, &e.CategoryId, &e.Type, &e.RecurringId, &e.AccountId, &e.TransferAccountId, &e.ExternalId,
+			&e.CategoryName, &e.AccountName, &e.TransferAccountName, &e.RunningBalance.Amount)
+		if err != nil {
+			return err
+		}
+		e.RunningBalance.Currency = e.ConvertedPrice.Currency
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
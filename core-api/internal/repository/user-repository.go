@@ -2,7 +2,9 @@ package repository
 
 import (
 	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/enum"
 	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
 	"database/sql"
 	"fmt"
 	"log"
@@ -28,6 +30,21 @@ func NewUserRepository(s database.Service) IUserRepository {
 	}
 }
 
+// userColumns lists the users columns in model.User order. The balance is the total of the
+// user's accounts in the preferred currency at today's rates.
+const userColumns = `id, first_name, last_name, username, google_email, apple_email, refresh_token,
+	refresh_token_expiry_date, avatar_url, created_at,
+	(SELECT COALESCE(SUM(convert_amount(a.balance, a.currency, users.preferred_currency, CURRENT_DATE)), 0)
+	 FROM accounts a
+	 WHERE a.owner_id = users.id) AS balance,
+	monthly_saving_goal, preferred_currency`
+
+// setUserCurrency marks the balance and saving goal as being in the user's preferred currency.
+func setUserCurrency(user *model.User) {
+	user.Balance.Currency = user.PreferredCurrency
+	user.MonthlySavingGoal.Currency = user.PreferredCurrency
+}
+
 func (d *databaseUserRepository) FindAll() []model.User {
 	rows, err := d.db.Query("SELECT id, first_name, last_name, google_email FROM users")
 	if err != nil {
@@ -50,7 +67,7 @@ func (d *databaseUserRepository) FindAll() []model.User {
 
 func (d *databaseUserRepository) FindById(id string) (*model.User, error) {
 	row := d.db.QueryRow(
-		"SELECT * FROM users WHERE id = $1",
+		"SELECT "+userColumns+" FROM users WHERE id = $1",
 		id,
 	)
 
@@ -66,10 +83,11 @@ func (d *databaseUserRepository) FindById(id string) (*model.User, error) {
 		&user.RefreshTokenExpiryDate,
 		&user.AvatarURL,
 		&user.CreatedAt,
-		&user.Balance,
-		&user.MonthlySavingGoal,
+		&user.Balance.Amount,
+		&user.MonthlySavingGoal.Amount,
 		&user.PreferredCurrency,
 	)
+	setUserCurrency(&user)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -83,7 +101,7 @@ func (d *databaseUserRepository) FindById(id string) (*model.User, error) {
 
 func (d *databaseUserRepository) FindByGoogleEmail(email string) *model.User {
 	row := d.db.QueryRow(
-		"SELECT * FROM users WHERE google_email = $1",
+		"SELECT "+userColumns+" FROM users WHERE google_email = $1",
 		email,
 	)
 
@@ -99,10 +117,11 @@ func (d *databaseUserRepository) FindByGoogleEmail(email string) *model.User {
 		&user.RefreshTokenExpiryDate,
 		&user.AvatarURL,
 		&user.CreatedAt,
-		&user.Balance,
-		&user.MonthlySavingGoal,
+		&user.Balance.Amount,
+		&user.MonthlySavingGoal.Amount,
 		&user.PreferredCurrency,
 	)
+	setUserCurrency(&user)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("user with google_email=%s not found", email)
@@ -117,7 +136,7 @@ func (d *databaseUserRepository) FindByGoogleEmail(email string) *model.User {
 
 func (d *databaseUserRepository) FindByAppleEmail(email string) *model.User {
 	row := d.db.QueryRow(
-		"SELECT * FROM users WHERE apple_email = $1",
+		"SELECT "+userColumns+" FROM users WHERE apple_email = $1",
 		email,
 	)
 
@@ -133,10 +152,11 @@ func (d *databaseUserRepository) FindByAppleEmail(email string) *model.User {
 		&user.RefreshTokenExpiryDate,
 		&user.AvatarURL,
 		&user.CreatedAt,
-		&user.Balance,
-		&user.MonthlySavingGoal,
+		&user.Balance.Amount,
+		&user.MonthlySavingGoal.Amount,
 		&user.PreferredCurrency,
 	)
+	setUserCurrency(&user)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("user with google_email=%s not found", email)
@@ -151,15 +171,64 @@ func (d *databaseUserRepository) FindByAppleEmail(email string) *model.User {
 
 func (d *databaseUserRepository) Save(user model.User) error {
 	log.Println("Saving user:", user.FirstName)
-	_, err := d.db.Exec(
-		"INSERT INTO users (id,first_name,last_name,username, google_email,apple_email,refresh_token,refresh_token_expiry_date,avatar_url,created_at,balance,monthly_saving_goal,preferred_currency) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
-		user.ID, user.FirstName, user.LastName, user.Username, user.GoogleEmail, user.AppleEmail, user.RefreshToken, user.RefreshTokenExpiryDate, user.AvatarURL, user.CreatedAt, user.Balance, user.MonthlySavingGoal, user.PreferredCurrency,
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"INSERT INTO users (id,first_name,last_name,username, google_email,apple_email,refresh_token,refresh_token_expiry_date,avatar_url,created_at,monthly_saving_goal,preferred_currency) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		user.ID, user.FirstName, user.LastName, user.Username, user.GoogleEmail, user.AppleEmail, user.RefreshToken, user.RefreshTokenExpiryDate, user.AvatarURL, user.CreatedAt, user.MonthlySavingGoal.Amount, user.PreferredCurrency,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// every user starts with one account, transactions without an account go there
+	err = insertAccount(tx, model.Account{
+		OwnerId:        user.ID,
+		Name:           defaultAccountName,
+		Type:           enum.Cash,
+		Currency:       user.PreferredCurrency,
+		OpeningBalance: money.New(user.Balance.Amount, user.PreferredCurrency),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
+// Update stores the user. A new preferred currency converts the budgets into it at today's
+// rate in the same transaction, and the saving goal when it is given in another currency.
+// Accounts keep their own currency.
 func (d *databaseUserRepository) Update(user model.User) error {
-	_, err := d.db.Exec(
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// budgets first, they are converted from the currency the user still has
+	_, err = tx.Exec(`
+		UPDATE budgets b
+		SET amount = convert_amount(b.amount, u.preferred_currency, $1, CURRENT_DATE)
+		FROM users u
+		WHERE u.id = b.owner_id
+		  AND b.owner_id = $2
+		  AND u.preferred_currency <> $1
+	`, user.PreferredCurrency, user.ID)
+	if err != nil {
+		log.Printf("failed to convert the budgets of user %s: %v", user.ID, err)
+		return err
+	}
+
+	goalCurrency := user.MonthlySavingGoal.Currency
+	if goalCurrency == "" {
+		goalCurrency = user.PreferredCurrency
+	}
+	_, err = tx.Exec(
 		`
 		UPDATE users
 		
@@ -171,11 +240,10 @@ func (d *databaseUserRepository) Update(user model.User) error {
 		     refresh_token = $6,
 		     refresh_token_expiry_date = $7,
 		     avatar_url = $8,
-		     balance = $9,
-		     monthly_saving_goal = $10,
-		     preferred_currency = $12
+		     monthly_saving_goal = convert_amount($9, $12, $11, CURRENT_DATE),
+		     preferred_currency = $11
 		
-	    WHERE id = $11`,
+	    WHERE id = $10`,
 		user.FirstName,
 		user.LastName,
 		user.Username,
@@ -184,16 +252,16 @@ func (d *databaseUserRepository) Update(user model.User) error {
 		user.RefreshToken,
 		user.RefreshTokenExpiryDate,
 		user.AvatarURL,
-		user.Balance,
-		user.MonthlySavingGoal,
+		user.MonthlySavingGoal.Amount,
 		user.ID,
 		user.PreferredCurrency,
+		goalCurrency,
 	)
 	if err != nil {
 		log.Printf("failed to update user %s: %v", user.ID, err)
 		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
 func (d *databaseUserRepository) Delete(id string) error {
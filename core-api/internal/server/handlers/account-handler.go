@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) GetAllAccounts(c *gin.Context) {
+	_, userId := getUserFromDatabase(c)
+	accounts := applicationAccountService.FindAll(userId)
+
+	c.JSON(200, gin.H{"data": accounts})
+}
+
+func (s *Server) GetAccountByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	_, userId := getUserFromDatabase(c)
+	account, err := applicationAccountService.FindById(id, userId)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"data": account})
+}
+
+func (s *Server) SaveAccount(c *gin.Context) {
+	var a dto.AccountDto
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, userId := getUserFromDatabase(c)
+	a.ID = 0
+	err, message := applicationAccountService.CreateOrUpdate(&a, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+	} else {
+		c.JSON(200, gin.H{
+			"message": message,
+		})
+	}
+}
+
+func (s *Server) UpdateAccount(c *gin.Context) {
+	var a dto.AccountDto
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+	a.ID = id
+	err, message := applicationAccountService.CreateOrUpdate(&a, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+	} else {
+		c.JSON(200, gin.H{
+			"message": message,
+		})
+	}
+}
+
+func (s *Server) DeleteAccount(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	if err := applicationAccountService.Delete(&dto.AccountDto{ID: id}, userId); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+	} else {
+		c.JSON(200, gin.H{
+			"message": "Account deleted successfully",
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"SmartSpend/internal/domain/enum"
 	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
 	_ "SmartSpend/internal/repository"
 	_ "database/sql"
 	"net/http"
@@ -101,8 +103,8 @@ func (s *Server) GoogleAuth(c *gin.Context) {
 				return ""
 			}(),
 			CreatedAt:         time.Now(),
-			Balance:           0.00,
-			MonthlySavingGoal: 0.00,
+			Balance:           money.Zero(enum.MKD),
+			MonthlySavingGoal: money.Zero(enum.MKD),
 			PreferredCurrency: "MKD",
 		}
 
@@ -178,8 +180,8 @@ func (s *Server) AppleSignIn(c *gin.Context) {
 		RefreshTokenExpiryDate: time.Now().Add(30 * 24 * time.Hour),
 		AvatarURL:              "",
 		CreatedAt:              time.Now(),
-		Balance:                0.00,
-		MonthlySavingGoal:      0.00,
+		Balance:                money.Zero(enum.MKD),
+		MonthlySavingGoal:      money.Zero(enum.MKD),
 		PreferredCurrency:      "MKD",
 	}
 
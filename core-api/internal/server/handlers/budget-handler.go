@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getReferenceDate reads the optional 'date' query parameter which selects the
+// budget period to report on, defaulting to the current one.
+func getReferenceDate(c *gin.Context) (time.Time, error) {
+	dateStr := c.Query("date")
+	if dateStr == "" {
+		return time.Now(), nil
+	}
+	return parseFlexibleTime(dateStr)
+}
+
+func (s *Server) GetAllBudgets(c *gin.Context) {
+	at, err := getReferenceDate(c)
+	if err != nil {
+		log.Printf("Failed to parse 'date': %s, error: %v", c.Query("date"), err)
+		c.JSON(400, gin.H{"error": "invalid 'date' format"})
+		return
+	}
+
+	_, userId := getUserFromDatabase(c)
+	budgets, err := applicationBudgetService.FindAll(userId, at)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"data": budgets})
+}
+
+func (s *Server) GetBudgetByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	at, err := getReferenceDate(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid 'date' format"})
+		return
+	}
+
+	_, userId := getUserFromDatabase(c)
+	budget, err := applicationBudgetService.FindById(id, userId, at)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"data": budget})
+}
+
+func (s *Server) SaveBudget(c *gin.Context) {
+	var b dto.BudgetDto
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, userId := getUserFromDatabase(c)
+	b.ID = 0
+	err, message := applicationBudgetService.CreateOrUpdate(&b, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+	} else {
+		c.JSON(200, gin.H{
+			"message": message,
+		})
+	}
+}
+
+func (s *Server) UpdateBudget(c *gin.Context) {
+	var b dto.BudgetDto
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+	b.ID = id
+	err, message := applicationBudgetService.CreateOrUpdate(&b, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+	} else {
+		c.JSON(200, gin.H{
+			"message": message,
+		})
+	}
+}
+
+func (s *Server) DeleteBudget(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	if err := applicationBudgetService.Delete(&dto.BudgetDto{ID: id}, userId); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+	} else {
+		c.JSON(200, gin.H{
+			"message": "Budget deleted successfully",
+		})
+	}
+}
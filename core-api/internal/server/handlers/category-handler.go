@@ -1,9 +1,17 @@
 package handlers
 
-import "github.com/gin-gonic/gin"
+import (
+	"SmartSpend/internal/domain/dto"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
 
 func (s *Server) GetAllCategories(c *gin.Context) {
-	categories := categoryService.FindAll()
+	includeArchived, _ := strconv.ParseBool(c.Query("archived"))
+
+	_, userId := getUserFromDatabase(c)
+	categories := applicationCategoryService.FindAll(userId, includeArchived)
 
 	if len(categories) == 0 {
 		c.JSON(200, gin.H{
@@ -17,3 +25,84 @@ func (s *Server) GetAllCategories(c *gin.Context) {
 	}
 	return
 }
+
+func (s *Server) GetCategoryByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+	category, err := applicationCategoryService.FindById(id, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"data": category})
+}
+
+func (s *Server) SaveCategory(c *gin.Context) {
+	var category dto.CategoryDto
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, userId := getUserFromDatabase(c)
+	category.ID = 0
+	err, message := applicationCategoryService.CreateOrUpdate(&category, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+	} else {
+		c.JSON(200, gin.H{
+			"message": message,
+		})
+	}
+}
+
+func (s *Server) UpdateCategory(c *gin.Context) {
+	var category dto.CategoryDto
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+	category.ID = id
+	err, message := applicationCategoryService.CreateOrUpdate(&category, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+	} else {
+		c.JSON(200, gin.H{
+			"message": message,
+		})
+	}
+}
+
+// ArchiveCategory is the DELETE of a category. Transactions still reference it,
+// so it is only hidden; PATCH with "archived": false brings it back.
+func (s *Server) ArchiveCategory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	if err := applicationCategoryService.Archive(id, userId); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+	} else {
+		c.JSON(200, gin.H{
+			"message": "Category archived successfully",
+		})
+	}
+}
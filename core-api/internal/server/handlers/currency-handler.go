@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) GetAllCurrencies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": exchangeRateService.FindCurrencies(),
+	})
+}
@@ -6,7 +6,9 @@ import (
 	"SmartSpend/internal/server/middleware"
 	"SmartSpend/internal/service/application"
 	"SmartSpend/internal/service/domain"
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -18,25 +20,56 @@ import (
 var (
 	database db.Service = db.New()
 
-	userRepository        repository.IUserRepository        = repository.NewUserRepository(database)
-	transactionRepository repository.ITransactionRepository = repository.NewTransactionRepository(database)
-	categoryRepository    repository.ICategoryRepository    = repository.NewCategoryRepository(database)
-	statisticsRepository  repository.IStatisticsRepository  = repository.NewStatisticsRepository(database)
-	savingRepository      repository.ISavingRepository      = repository.NewSavingRepository(database)
-
-	userService        domain.IUserService        = domain.NewUserService(userRepository)
-	jwtService         domain.IJWTService         = domain.NewJWTService()
-	tokenService       domain.ITokenService       = domain.NewTokenService()
-	transactionService domain.ITransactionService = domain.NewTransactionService(transactionRepository)
-	categoryService    domain.ICategoryService    = domain.NewCategoryService(categoryRepository)
-	statisticsService  domain.IStatisticsService  = domain.NewStatisticsService(statisticsRepository)
-	geminiService      domain.IGeminiService      = domain.NewGeminiService()
-
-	applicationUserService        application.IUserAppService                = application.NewUserAppService(userService)
-	applicationTransactionService application.IApplicationTransactionService = application.NewApplicationTransactionService(transactionRepository)
-	applicationSavingService      application.IApplicationSavingService      = application.NewApplicationSavingService(savingRepository)
+	userRepository            repository.IUserRepository                 = repository.NewUserRepository(database)
+	transactionRepository     repository.ITransactionRepository          = repository.NewTransactionRepository(database)
+	transactionItemRepository repository.ITransactionItemRepository      = repository.NewTransactionItemRepository(database)
+	categoryRepository        repository.ICategoryRepository             = repository.NewCategoryRepository(database)
+	statisticsRepository      repository.IStatisticsRepository           = repository.NewStatisticsRepository(database)
+	savingRepository          repository.ISavingRepository               = repository.NewSavingRepository(database)
+	recurringRepository       repository.IRecurringTransactionRepository = repository.NewRecurringTransactionRepository(database)
+	budgetRepository          repository.IBudgetRepository               = repository.NewBudgetRepository(database)
+	accountRepository         repository.IAccountRepository              = repository.NewAccountRepository(database)
+	exchangeRateRepository    repository.IExchangeRateRepository         = repository.NewExchangeRateRepository(database)
+	receiptRepository         repository.IReceiptRepository              = repository.NewReceiptRepository(database)
+	receiptJobRepository      repository.IReceiptJobRepository           = repository.NewReceiptJobRepository(database)
+
+	userService         domain.IUserService         = domain.NewUserService(userRepository)
+	jwtService          domain.IJWTService          = domain.NewJWTService()
+	tokenService        domain.ITokenService        = domain.NewTokenService()
+	transactionService  domain.ITransactionService  = domain.NewTransactionService(transactionRepository)
+	categoryService     domain.ICategoryService     = domain.NewCategoryService(categoryRepository)
+	statisticsService   domain.IStatisticsService   = domain.NewStatisticsService(statisticsRepository)
+	ocrService          domain.IOCRService          = domain.NewOCRService()
+	receiptService      domain.IReceiptService      = domain.NewReceiptService(ocrService, newReceiptProvider())
+	budgetService       domain.IBudgetService       = domain.NewBudgetService(budgetRepository, statisticsRepository)
+	exchangeRateService domain.IExchangeRateService = domain.NewExchangeRateService(exchangeRateRepository)
+	notificationHub     domain.INotificationHub     = domain.NewNotificationHub()
+
+	applicationUserService        application.IUserAppService                         = application.NewUserAppService(userService, exchangeRateService)
+	applicationTransactionService application.IApplicationTransactionService          = application.NewApplicationTransactionService(transactionRepository, transactionItemRepository, categoryRepository, accountRepository, exchangeRateService)
+	applicationSavingService      application.IApplicationSavingService               = application.NewApplicationSavingService(savingRepository)
+	applicationRecurringService   application.IApplicationRecurringTransactionService = application.NewApplicationRecurringTransactionService(recurringRepository, categoryRepository, exchangeRateService)
+	applicationBudgetService      application.IApplicationBudgetService               = application.NewApplicationBudgetService(budgetRepository, budgetService, categoryRepository, exchangeRateService)
+	applicationAccountService     application.IApplicationAccountService              = application.NewApplicationAccountService(accountRepository, exchangeRateService)
+	applicationCategoryService    application.IApplicationCategoryService             = application.NewApplicationCategoryService(categoryService)
+	applicationReceiptService     application.IApplicationReceiptService              = application.NewApplicationReceiptService(receiptRepository, receiptJobRepository, receiptService, categoryRepository, applicationTransactionService, exchangeRateService, notificationHub)
 )
 
+// newReceiptProvider creates the provider chosen by RECEIPT_PROVIDER, a typo there should
+// stop the server rather than every receipt upload.
+func newReceiptProvider() domain.IReceiptProvider {
+	provider, err := domain.NewReceiptProvider(domain.ReceiptProviderConfigFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return provider
+}
+
+// StartReceiptWorkers reads the queued receipts in the background until ctx is cancelled.
+func (s *Server) StartReceiptWorkers(ctx context.Context, workers int) {
+	applicationReceiptService.StartWorkers(ctx, workers, 5*time.Second)
+}
+
 func parseFlexibleTime(timeStr string) (time.Time, error) {
 	if t, err := time.Parse(time.RFC3339Nano, timeStr); err == nil {
 		return t, nil
@@ -63,10 +96,12 @@ type Server struct {
 func (s *Server) RegisterRoutes() http.Handler {
 	r := gin.Default()
 
+	r.Use(middleware.RequestID())
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"}, // frontend URL
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
+		ExposeHeaders:    []string{"X-Request-ID"},
 		AllowCredentials: true, // Enable cookies/auth
 	}))
 	authBasePath := "/api/auth"
@@ -77,6 +112,9 @@ func (s *Server) RegisterRoutes() http.Handler {
 	currencyBasePath := "/api/currency"
 	statisticsBasePath := "/api/statistics"
 	savingsBasePath := "/api/saving"
+	budgetBasePath := "/api/budget"
+	accountBasePath := "/api/account"
+	receiptBasePath := "/api/receipt"
 
 	r.GET("/health", s.healthHandler)
 
@@ -108,30 +146,40 @@ func (s *Server) RegisterRoutes() http.Handler {
 	transaction := r.Group(transactionBasePath, middleware.AuthMiddleware())
 	{
 		transaction.GET("", s.GetAllTransactions)
+		transaction.GET("/export", s.ExportTransactions)
 		transaction.GET("/:id", s.GetTransactionByID)
 		transaction.POST("", s.SaveTransaction)
 		transaction.PATCH("/:id", s.UpdateTransaction)
 		transaction.DELETE("/:id", s.DeleteTransaction)
-		transaction.POST("/receipt", s.SaveFromReceipt)
+		transaction.GET("/:id/items", s.GetTransactionItems)
+		transaction.PUT("/:id/items", s.UpdateTransactionItems)
+		transaction.POST("/receipt", s.EnqueueReceipt) // answers with the job, see /api/receipt/jobs/:id
+		transaction.POST("/import", s.ImportTransactions)
+
+		transaction.GET("/recurring", s.GetAllRecurringTransactions)
+		transaction.GET("/recurring/:id", s.GetRecurringTransactionByID)
+		transaction.POST("/recurring", s.SaveRecurringTransaction)
+		transaction.PATCH("/recurring/:id", s.UpdateRecurringTransaction)
+		transaction.DELETE("/recurring/:id", s.DeleteRecurringTransaction)
 	}
 
 	category := r.Group(categoryBasePath, middleware.AuthMiddleware())
 	{
 		category.GET("", s.GetAllCategories)
+		category.GET("/:id", s.GetCategoryByID)
+		category.POST("", s.SaveCategory)
+		category.PATCH("/:id", s.UpdateCategory)
+		category.DELETE("/:id", s.ArchiveCategory)
 	}
 
 	currency := r.Group(currencyBasePath, middleware.AuthMiddleware())
 	{
-		currency.GET("", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"data": []string{"MKD", "USD", "EUR"},
-			})
-		})
+		currency.GET("", s.GetAllCurrencies) // every currency an exchange rate has been imported for
 	}
 
 	statistics := r.Group(statisticsBasePath, middleware.AuthMiddleware())
 	{
-		statistics.GET("/pie", s.Pie)                                   // sum of money spent (grouped by categories) - includes categories user has used
+		statistics.GET("/pie", s.Pie)                                   // sum of money spent (grouped by categories) - includes categories user has used, ?rollup=true groups children under their parent
 		statistics.GET("/monthly", s.Monthly)                           // sum of money spent per-month (all categories) - includes only months user has made transactions on
 		statistics.GET("/total-spent", s.TotalSpentOnExpensesAndIncome) // sum of money used on expenses and income alone
 		statistics.GET("/average", s.Average)                           // average price spent for expense and added for incomes
@@ -146,5 +194,35 @@ func (s *Server) RegisterRoutes() http.Handler {
 		saving.DELETE("/:id", s.DeleteTransaction)
 	}
 
+	budget := r.Group(budgetBasePath, middleware.AuthMiddleware())
+	{
+		budget.GET("", s.GetAllBudgets)
+		budget.GET("/:id", s.GetBudgetByID)
+		budget.POST("", s.SaveBudget)
+		budget.PATCH("/:id", s.UpdateBudget)
+		budget.DELETE("/:id", s.DeleteBudget)
+	}
+
+	account := r.Group(accountBasePath, middleware.AuthMiddleware())
+	{
+		account.GET("", s.GetAllAccounts)
+		account.GET("/:id", s.GetAccountByID)
+		account.POST("", s.SaveAccount)
+		account.PATCH("/:id", s.UpdateAccount)
+		account.DELETE("/:id", s.DeleteAccount) // only accounts without transactions
+	}
+
+	receipt := r.Group(receiptBasePath, middleware.AuthMiddleware())
+	{
+		receipt.POST("", s.EnqueueReceipt) // read in the background and kept as a draft until confirmed
+		receipt.GET("/jobs/:id", s.GetReceiptJob)
+		receipt.GET("/drafts", s.GetReceiptDrafts)
+		receipt.GET("/drafts/:id", s.GetReceiptByID)
+		receipt.PATCH("/drafts/:id", s.UpdateReceiptDraft)
+		receipt.POST("/drafts/:id/confirm", s.ConfirmReceiptDraft)
+		receipt.POST("/drafts/:id/reject", s.RejectReceiptDraft)
+		receipt.GET("/:id/image", s.GetReceiptImage) // also for confirmed receipts, see receipt_id of the transaction
+	}
+
 	return r
 }
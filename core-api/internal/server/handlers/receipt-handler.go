@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"SmartSpend/internal/repository"
+	"SmartSpend/internal/service/domain"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnqueueReceipt queues the receipt uploaded as "image" to be read in the background and
+// answers with the job right away. The draft it is read into is pushed over /websocket and
+// can be polled at /api/receipt/jobs/:id.
+func (s *Server) EnqueueReceipt(c *gin.Context) {
+	_, userId := getUserFromDatabase(c)
+
+	file, header, err := c.Request.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get image file"})
+		return
+	}
+	defer file.Close()
+
+	imgBytes, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read image file"})
+		return
+	}
+
+	job, err := applicationReceiptService.Enqueue(header.Filename, imgBytes, userId, domain.RequestID(c.Request.Context()))
+	if errors.Is(err, domain.ErrInvalidImage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode image"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"data": job})
+}
+
+func (s *Server) GetReceiptJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	job, err := applicationReceiptService.FindJob(id, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+func (s *Server) GetReceiptDrafts(c *gin.Context) {
+	_, userId := getUserFromDatabase(c)
+
+	receipts, err := applicationReceiptService.FindDrafts(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": receipts})
+}
+
+func (s *Server) GetReceiptByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	receipt, err := applicationReceiptService.FindById(id, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": receipt})
+}
+
+// GetReceiptImage sends the image the receipt was uploaded as.
+func (s *Server) GetReceiptImage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	image, contentType, err := applicationReceiptService.FindImage(id, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// receiptStatusCode answers a change of a receipt that is no longer a draft with 409.
+func receiptStatusCode(err error) int {
+	if errors.Is(err, repository.ErrReceiptNotDraft) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// UpdateReceiptDraft changes the fields of the draft that are set in the body.
+func (s *Server) UpdateReceiptDraft(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var t dto.TransactionDto
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	err, message := applicationReceiptService.UpdateDraft(id, &t, userId)
+	if err != nil {
+		c.JSON(receiptStatusCode(err), gin.H{"error": message})
+		return
+	}
+
+	receipt, err := applicationReceiptService.FindById(id, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "data": receipt})
+}
+
+// ConfirmReceiptDraft saves the draft as a transaction. The body is optional and changes the
+// draft one last time, like UpdateReceiptDraft.
+func (s *Server) ConfirmReceiptDraft(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var changes *dto.TransactionDto
+	if c.Request.ContentLength != 0 {
+		changes = &dto.TransactionDto{}
+		if err := c.ShouldBindJSON(changes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	_, userId := getUserFromDatabase(c)
+
+	err, message := applicationReceiptService.Confirm(id, changes, userId)
+	if err != nil {
+		c.JSON(receiptStatusCode(err), gin.H{"error": message})
+		return
+	}
+
+	receipt, err := applicationReceiptService.FindById(id, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "data": receipt})
+}
+
+func (s *Server) RejectReceiptDraft(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	if err := applicationReceiptService.Reject(id, userId); err != nil {
+		c.JSON(receiptStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Receipt rejected successfully"})
+}
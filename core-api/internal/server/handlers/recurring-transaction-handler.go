@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) GetAllRecurringTransactions(c *gin.Context) {
+	_, userId := getUserFromDatabase(c)
+	recurring := applicationRecurringService.FindAll(userId)
+
+	c.JSON(200, gin.H{"data": recurring})
+}
+
+func (s *Server) GetRecurringTransactionByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+	recurring, err := applicationRecurringService.FindById(id, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"data": recurring})
+}
+
+func (s *Server) SaveRecurringTransaction(c *gin.Context) {
+	var r dto.RecurringTransactionDto
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, userId := getUserFromDatabase(c)
+	r.ID = 0
+	err, message := applicationRecurringService.CreateOrUpdate(&r, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+	} else {
+		c.JSON(200, gin.H{
+			"message": message,
+		})
+	}
+}
+
+func (s *Server) UpdateRecurringTransaction(c *gin.Context) {
+	var r dto.RecurringTransactionDto
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+	r.ID = id
+	err, message := applicationRecurringService.CreateOrUpdate(&r, userId)
+
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+	} else {
+		c.JSON(200, gin.H{
+			"message": message,
+		})
+	}
+}
+
+func (s *Server) DeleteRecurringTransaction(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	r, err := applicationRecurringService.FindById(id, userId)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := applicationRecurringService.Delete(r, userId); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+	} else {
+		c.JSON(200, gin.H{
+			"message": "Recurring transaction deleted successfully",
+		})
+	}
+}
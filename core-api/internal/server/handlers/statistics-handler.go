@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -39,7 +40,10 @@ func (s *Server) Pie(c *gin.Context) {
 		return
 	}
 
-	percentages, totalExpenses, totalIncome, err := statisticsService.FindPercentageSpentPerCategory(userId, from, to)
+	// ?rollup=true reports child categories under their top level parent
+	rollup, _ := strconv.ParseBool(c.Query("rollup"))
+
+	percentages, totalExpenses, totalIncome, err := statisticsService.FindPercentageSpentPerCategory(userId, from, to, rollup)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
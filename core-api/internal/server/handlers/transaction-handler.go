@@ -2,21 +2,22 @@ package handlers
 
 import (
 	"SmartSpend/internal/domain/dto"
-	"bytes"
-	"encoding/base64"
+	"SmartSpend/internal/service/domain"
+	"encoding/json"
 	"fmt"
-	"image"
-	"image/jpeg"
-	"io"
 	"log"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func (s *Server) GetAllTransactions(c *gin.Context) {
+// parseDateRange reads the optional from and to query parameters, to defaults to now. On
+// failure the response has been written already.
+func parseDateRange(c *gin.Context) (time.Time, time.Time, bool) {
 	fromStr := c.Query("from")
 	toStr := c.Query("to")
 
@@ -30,7 +31,7 @@ func (s *Server) GetAllTransactions(c *gin.Context) {
 		if err != nil {
 			log.Printf("Failed to parse 'from': %s, error: %v", fromStr, err)
 			c.JSON(400, gin.H{"error": "invalid 'from' date format"})
-			return
+			return from, to, false
 		}
 	}
 
@@ -41,12 +42,20 @@ func (s *Server) GetAllTransactions(c *gin.Context) {
 		if err != nil {
 			log.Printf("Failed to parse 'to': %s, error: %v", toStr, err)
 			c.JSON(400, gin.H{"error": "invalid 'to' date format"})
-			return
+			return from, to, false
 		}
 	}
 
 	if !from.IsZero() && !to.IsZero() && from.After(to) {
 		c.JSON(400, gin.H{"error": "'from' date cannot be after 'to' date"})
+		return from, to, false
+	}
+	return from, to, true
+}
+
+func (s *Server) GetAllTransactions(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
 		return
 	}
 
@@ -56,6 +65,48 @@ func (s *Server) GetAllTransactions(c *gin.Context) {
 	c.JSON(200, gin.H{"data": transactions})
 }
 
+// ExportTransactions streams the transactions between from and to as csv, json or xlsx.
+func (s *Server) ExportTransactions(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	contentType, ok := domain.ExportFormats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of csv, json or xlsx"})
+		return
+	}
+
+	_, userId := getUserFromDatabase(c)
+	// the status is sent with the first row, a later failure can only cut the file short
+	w := &attachmentWriter{ResponseWriter: c.Writer, contentType: contentType, filename: "transactions." + format}
+	if err := applicationTransactionService.Export(w, format, userId, from, to); err != nil {
+		log.Printf("Failed to export transactions: %v", err)
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+	}
+}
+
+// attachmentWriter answers 200 with the headers of a file download when the first bytes of
+// the file are written, until then the request can still be answered with an error.
+type attachmentWriter struct {
+	gin.ResponseWriter
+	contentType string
+	filename    string
+}
+
+func (w *attachmentWriter) Write(data []byte) (int, error) {
+	if !w.Written() {
+		w.Header().Set("Content-Type", w.contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
 func (s *Server) GetTransactionByID(c *gin.Context) {
 	id := c.Param("id")
 	idInteger, err := strconv.ParseInt(id, 10, 64)
@@ -117,6 +168,52 @@ func (s *Server) UpdateTransaction(c *gin.Context) {
 	return
 }
 
+func (s *Server) GetTransactionItems(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	items, err := applicationTransactionService.FindItems(id, userId)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"data": items})
+}
+
+// UpdateTransactionItems replaces the items of a transaction with the JSON array in the body.
+func (s *Server) UpdateTransactionItems(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	var items []dto.TransactionItemDto
+	if err := c.ShouldBindJSON(&items); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	_, userId := getUserFromDatabase(c)
+
+	err, message := applicationTransactionService.UpdateItems(id, items, userId)
+	if err != nil {
+		c.JSON(400, gin.H{"error": message})
+		return
+	}
+
+	// the saved items come back with the flag telling whether they add up
+	result, err := applicationTransactionService.FindItems(id, userId)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": message, "data": result})
+}
+
 func (s *Server) DeleteTransaction(c *gin.Context) {
 	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 	_, userId := getUserFromDatabase(c)
@@ -137,49 +234,49 @@ func (s *Server) DeleteTransaction(c *gin.Context) {
 	return
 }
 
-func imageToBase64(img image.Image) (string, error) {
-	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, img, nil); err != nil {
-		return "", err
-	}
-	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
-}
-
-func (s *Server) SaveFromReceipt(c *gin.Context) {
+// ImportTransactions reads a bank statement uploaded as "file". The format (csv, ofx or camt)
+// is taken from the "format" form field or the file extension, the options and the column
+// mapping of a CSV file from the "mapping" form field. It returns a preview unless called
+// with ?commit=true.
+func (s *Server) ImportTransactions(c *gin.Context) {
 	_, userId := getUserFromDatabase(c)
 
-	file, _, err := c.Request.FormFile("image")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get image file"})
-		return
+	var mapping dto.CSVMappingDto
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mapping"})
+			return
+		}
 	}
-	defer file.Close()
-
-	var imgBuffer bytes.Buffer
-	teeReader := io.TeeReader(file, &imgBuffer)
 
-	img, _, err := image.Decode(teeReader)
+	file, header, err := c.Request.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode image"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get statement file"})
 		return
 	}
+	defer file.Close()
 
-	base64Image, err := imageToBase64(img)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode image to Base64"})
-		return
+	format := c.PostForm("format")
+	if format == "" {
+		format = statementFormat(header.Filename)
 	}
 
-	tx, err := geminiService.SendToGemini("", base64Image)
+	commit := c.Query("commit") == "true"
+	result, err := applicationTransactionService.ImportStatement(file, format, mapping, userId, commit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Errorf("Gemini service error: %w", err).Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	tx.OwnerId = userId
-	tx.DateMade = time.Now()
-
-	log.Println("Gemini Transaction:", tx)
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
 
-	c.JSON(http.StatusOK, tx)
+// statementFormat guesses the format of a statement from its file name, CAMT.053 files are XML.
+func statementFormat(filename string) string {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".xml":
+		return "camt"
+	default:
+		return strings.TrimPrefix(ext, ".")
+	}
 }
@@ -43,11 +43,22 @@ func (s *Server) GetUserData(c *gin.Context) {
 }
 
 func (s *Server) GetUserBalances(c *gin.Context) {
-	user, _ := getUserFromDatabase(c)
+	user, userId := getUserFromDatabase(c)
+	if user == nil {
+		return
+	}
+
+	total, err := applicationAccountService.FindTotalBalance(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
-			"balance":             user.Balance,
+			"accounts":            applicationAccountService.FindAll(userId),
+			"total":               total,
+			"balance":             total, // kept for clients from before accounts
 			"monthly_saving_goal": user.MonthlySavingGoal,
 		},
 	})
@@ -62,6 +73,7 @@ func (s *Server) UpdateUserInformation(c *gin.Context) {
 	}
 	err := applicationUserService.Update(userID, u)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -6,10 +6,34 @@ import (
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// websocketUser returns the user of the access token in the Authorization header or, as
+// browsers cannot set headers on a websocket, in the token query parameter. An anonymous
+// connection gets an empty id.
+func websocketUser(c *gin.Context) string {
+	accessToken := c.Query("token")
+	if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		accessToken = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if accessToken == "" {
+		return ""
+	}
+	claims, err := tokenService.DecodeAccessToken(accessToken)
+	if err != nil {
+		return ""
+	}
+	userId, _ := claims["user-id"].(string)
+	return userId
+}
+
+// websocketHandler sends the server time every two seconds and, on a connection with an access
+// token, the notifications of its user, e.g. finished receipt jobs.
 func (s *Server) websocketHandler(c *gin.Context) {
+	userId := websocketUser(c)
+
 	w := c.Writer
 	r := c.Request
 	socket, err := websocket.Accept(w, r, nil)
@@ -26,12 +50,29 @@ func (s *Server) websocketHandler(c *gin.Context) {
 	ctx := r.Context()
 	socketCtx := socket.CloseRead(ctx)
 
+	// nil for an anonymous connection, which never receives from it
+	var notifications <-chan []byte
+	if userId != "" {
+		messages, unsubscribe := notificationHub.Subscribe(userId)
+		defer unsubscribe()
+		notifications = messages
+	}
+
+	ticker := time.NewTicker(time.Second * 2)
+	defer ticker.Stop()
+
+	payload := []byte(fmt.Sprintf("server timestamp: %d", time.Now().UnixNano()))
 	for {
-		payload := fmt.Sprintf("server timestamp: %d", time.Now().UnixNano())
-		err := socket.Write(socketCtx, websocket.MessageText, []byte(payload))
-		if err != nil {
-			break
+		if err := socket.Write(socketCtx, websocket.MessageText, payload); err != nil {
+			return
+		}
+
+		select {
+		case <-socketCtx.Done():
+			return
+		case <-ticker.C:
+			payload = []byte(fmt.Sprintf("server timestamp: %d", time.Now().UnixNano()))
+		case payload = <-notifications:
 		}
-		time.Sleep(time.Second * 2)
 	}
 }
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"SmartSpend/internal/service/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestID gives every request an ID, the X-Request-ID it came with or a new one, answers
+// with it and keeps it in the context of the request for the services it calls.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		c.Header("X-Request-ID", id)
+		c.Request = c.Request.WithContext(domain.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
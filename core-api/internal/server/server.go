@@ -4,10 +4,13 @@ import (
 	"SmartSpend/internal/database"
 	"SmartSpend/internal/repository"
 	"SmartSpend/internal/server/handlers"
+	"SmartSpend/internal/service/domain"
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 )
 
 func NewServer() *http.Server {
@@ -17,6 +20,8 @@ func NewServer() *http.Server {
 	database.RunMigrations(dbService.DB())
 
 	userRepo := repository.NewUserRepository(dbService) // Pass the service
+	transactionRepo := repository.NewTransactionRepository(dbService)
+	recurringRepo := repository.NewRecurringTransactionRepository(dbService)
 
 	serverHandler := &handlers.Server{
 		Port:     port,
@@ -29,5 +34,24 @@ func NewServer() *http.Server {
 		Handler: serverHandler.RegisterRoutes(),
 	}
 
+	// background jobs live as long as the server, they are stopped on graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	srv.RegisterOnShutdown(cancel)
+
+	domain.NewRecurringTransactionScheduler(recurringRepo, transactionRepo, time.Minute).Start(ctx)
+
+	// receipts uploaded to any instance are read by the workers of all of them
+	receiptWorkers := 2
+	if workers, err := strconv.Atoi(os.Getenv("RECEIPT_WORKERS")); err == nil && workers >= 0 {
+		receiptWorkers = workers
+	}
+	serverHandler.StartReceiptWorkers(ctx, receiptWorkers)
+
+	// the ECB publishes new rates once every working day
+	if ratesFile := os.Getenv("ECB_RATES_FILE"); ratesFile != "" {
+		exchangeRateService := domain.NewExchangeRateService(repository.NewExchangeRateRepository(dbService))
+		exchangeRateService.StartImporter(ctx, domain.NewECBFileProvider(ratesFile), 12*time.Hour)
+	}
+
 	return srv
 }
@@ -0,0 +1,157 @@
+package application
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"SmartSpend/internal/repository"
+	"SmartSpend/internal/service/domain"
+	"fmt"
+)
+
+type IApplicationAccountService interface {
+	FindAll(userId string) []dto.AccountDto
+	FindById(id int64, userId string) (*dto.AccountDto, error)
+	FindTotalBalance(userId string) (money.Money, error)
+	CreateOrUpdate(accountDto *dto.AccountDto, userId string) (error, string)
+	Delete(accountDto *dto.AccountDto, userId string) error
+}
+
+type ApplicationAccountService struct {
+	accountRepository   repository.IAccountRepository
+	exchangeRateService domain.IExchangeRateService
+}
+
+func NewApplicationAccountService(repo repository.IAccountRepository, exchangeRateService domain.IExchangeRateService) *ApplicationAccountService {
+	return &ApplicationAccountService{
+		accountRepository:   repo,
+		exchangeRateService: exchangeRateService,
+	}
+}
+
+func mapToDtoAccount(a model.Account) dto.AccountDto {
+	return dto.AccountDto{
+		ID:             a.ID,
+		Name:           &a.Name,
+		Type:           &a.Type,
+		Currency:       &a.Currency,
+		OpeningBalance: &a.OpeningBalance,
+		Balance:        a.Balance,
+		CreatedAt:      a.CreatedAt,
+	}
+}
+
+func validateAccount(a model.Account) (error, string) {
+	if a.Name == "" {
+		return fmt.Errorf("name is required"), "Name is required for an account"
+	}
+	switch a.Type {
+	case enum.Cash, enum.Debit, enum.Credit, enum.Savings:
+	default:
+		return fmt.Errorf("invalid type"), "Type must be one of Cash, Debit, Credit or Savings"
+	}
+	if a.OpeningBalance.Currency != "" && a.OpeningBalance.Currency != a.Currency {
+		return fmt.Errorf("opening balance in another currency"), "Opening balance must be in the currency of the account"
+	}
+	return nil, ""
+}
+
+func (s *ApplicationAccountService) FindAll(userId string) []dto.AccountDto {
+	accounts := s.accountRepository.FindAll(userId)
+	result := make([]dto.AccountDto, len(accounts))
+	for i, a := range accounts {
+		result[i] = mapToDtoAccount(a)
+	}
+	return result
+}
+
+func (s *ApplicationAccountService) FindById(id int64, userId string) (*dto.AccountDto, error) {
+	a, err := s.accountRepository.FindById(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	accountDto := mapToDtoAccount(*a)
+	return &accountDto, nil
+}
+
+func (s *ApplicationAccountService) FindTotalBalance(userId string) (money.Money, error) {
+	return s.accountRepository.FindTotalBalance(userId)
+}
+
+func (s *ApplicationAccountService) CreateOrUpdate(accountDto *dto.AccountDto, userId string) (error, string) {
+	if accountDto.ID != 0 { // update
+		existing, err := s.accountRepository.FindById(accountDto.ID, userId)
+		if err != nil {
+			return err, "Account not found"
+		}
+
+		account := *existing
+
+		// the transactions on the account are booked in its currency, so it stays fixed
+		if accountDto.Currency != nil && *accountDto.Currency != account.Currency {
+			return fmt.Errorf("currency cannot change"), "The currency of an account cannot be changed"
+		}
+		if accountDto.Name != nil {
+			account.Name = *accountDto.Name
+		}
+		if accountDto.Type != nil {
+			account.Type = *accountDto.Type
+		}
+		if accountDto.OpeningBalance != nil {
+			account.OpeningBalance = *accountDto.OpeningBalance
+		}
+
+		if err, message := validateAccount(account); err != nil {
+			return err, message
+		}
+
+		err = s.accountRepository.Update(account, account.ID)
+		if err != nil {
+			return err, err.Error()
+		}
+		return nil, fmt.Sprintf("Account with id %d updated successfully", account.ID)
+	} else {
+		if accountDto.Name == nil {
+			return fmt.Errorf("name is required"), "Name is required for new account"
+		}
+		if accountDto.Type == nil {
+			return fmt.Errorf("type is required"), "Type is required for new account"
+		}
+		if accountDto.Currency == nil {
+			return fmt.Errorf("currency is required"), "Currency is required for new account"
+		}
+		if !s.exchangeRateService.IsSupported(*accountDto.Currency) {
+			return fmt.Errorf("unsupported currency %s", *accountDto.Currency), fmt.Sprintf("Currency %s is not supported", *accountDto.Currency)
+		}
+
+		account := model.Account{
+			OwnerId:        userId,
+			Name:           *accountDto.Name,
+			Type:           *accountDto.Type,
+			Currency:       *accountDto.Currency,
+			OpeningBalance: money.Zero(*accountDto.Currency),
+		}
+		if accountDto.OpeningBalance != nil {
+			account.OpeningBalance = *accountDto.OpeningBalance
+		}
+
+		if err, message := validateAccount(account); err != nil {
+			return err, message
+		}
+
+		err := s.accountRepository.Save(account)
+		if err != nil {
+			return err, err.Error()
+		}
+		return nil, "Account successfully created."
+	}
+}
+
+// Delete removes an account without transactions, a user always keeps at least one.
+func (s *ApplicationAccountService) Delete(accountDto *dto.AccountDto, userId string) error {
+	if len(s.accountRepository.FindAll(userId)) <= 1 {
+		return fmt.Errorf("the only account cannot be deleted")
+	}
+	return s.accountRepository.Delete(accountDto.ID, userId)
+}
@@ -0,0 +1,159 @@
+package application
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/repository"
+	"SmartSpend/internal/service/domain"
+	"fmt"
+	"time"
+)
+
+type IApplicationBudgetService interface {
+	FindAll(userId string, at time.Time) ([]dto.BudgetDto, error)
+	FindById(id int64, userId string, at time.Time) (*dto.BudgetDto, error)
+	CreateOrUpdate(budgetDto *dto.BudgetDto, userId string) (error, string)
+	Delete(budgetDto *dto.BudgetDto, userId string) error
+}
+
+type ApplicationBudgetService struct {
+	budgetRepository    repository.IBudgetRepository
+	budgetService       domain.IBudgetService
+	categoryRepository  repository.ICategoryRepository
+	exchangeRateService domain.IExchangeRateService
+}
+
+func NewApplicationBudgetService(repo repository.IBudgetRepository, budgetService domain.IBudgetService, categoryRepo repository.ICategoryRepository, exchangeRateService domain.IExchangeRateService) *ApplicationBudgetService {
+	return &ApplicationBudgetService{
+		budgetRepository:    repo,
+		budgetService:       budgetService,
+		categoryRepository:  categoryRepo,
+		exchangeRateService: exchangeRateService,
+	}
+}
+
+func mapToDtoBudget(b model.BudgetSpending) dto.BudgetDto {
+	return dto.BudgetDto{
+		ID:          b.ID,
+		CategoryId:  &b.CategoryId,
+		Amount:      &b.Amount,
+		Period:      &b.Period,
+		PeriodStart: b.PeriodStart,
+		PeriodEnd:   b.PeriodEnd,
+		Spent:       b.Spent,
+		Remaining:   b.Remaining,
+		PercentUsed: b.PercentUsed,
+		Overspent:   b.Overspent,
+	}
+}
+
+// validateBudget checks the budget before it is stored. An amount in another currency than the
+// preferred one is converted by the repository, an empty currency means the preferred one.
+func (s *ApplicationBudgetService) validateBudget(b model.Budget) (error, string) {
+	switch b.Period {
+	case enum.Daily, enum.Weekly, enum.Monthly, enum.Yearly:
+	default:
+		return fmt.Errorf("invalid period"), "Period must be one of Daily, Weekly, Monthly or Yearly"
+	}
+	if !b.Amount.Amount.IsPositive() {
+		return fmt.Errorf("invalid amount"), "Amount must be greater than 0"
+	}
+	if b.Amount.Currency != "" && !s.exchangeRateService.IsSupported(b.Amount.Currency) {
+		return fmt.Errorf("currency %s is not supported", b.Amount.Currency), fmt.Sprintf("Currency %s is not supported", b.Amount.Currency)
+	}
+	return nil, ""
+}
+
+func (s *ApplicationBudgetService) FindAll(userId string, at time.Time) ([]dto.BudgetDto, error) {
+	budgets, err := s.budgetService.FindAllWithSpending(userId, at)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.BudgetDto, len(budgets))
+	for i, b := range budgets {
+		result[i] = mapToDtoBudget(b)
+	}
+	return result, nil
+}
+
+func (s *ApplicationBudgetService) FindById(id int64, userId string, at time.Time) (*dto.BudgetDto, error) {
+	budget, err := s.budgetService.FindById(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	spending, err := s.budgetService.FindSpending(*budget, at)
+	if err != nil {
+		return nil, err
+	}
+	budgetDto := mapToDtoBudget(spending)
+	return &budgetDto, nil
+}
+
+func (s *ApplicationBudgetService) CreateOrUpdate(budgetDto *dto.BudgetDto, userId string) (error, string) {
+	if budgetDto.ID != 0 { // update
+		existing, err := s.budgetRepository.FindById(budgetDto.ID, userId)
+		if err != nil {
+			return err, "Budget not found"
+		}
+
+		budget := *existing
+
+		if budgetDto.CategoryId != nil {
+			if _, err := s.categoryRepository.FindById(*budgetDto.CategoryId, userId, false); err != nil {
+				return err, "Category not found"
+			}
+			budget.CategoryId = *budgetDto.CategoryId
+		}
+		if budgetDto.Amount != nil {
+			budget.Amount = *budgetDto.Amount
+		}
+		if budgetDto.Period != nil {
+			budget.Period = *budgetDto.Period
+		}
+
+		if err, message := s.validateBudget(budget); err != nil {
+			return err, message
+		}
+
+		err = s.budgetRepository.Update(budget, budget.ID)
+		if err != nil {
+			return err, err.Error()
+		}
+		return nil, fmt.Sprintf("Budget with id %d updated successfully", budget.ID)
+	} else {
+		if budgetDto.CategoryId == nil {
+			return fmt.Errorf("category is required"), "Category is required for new budget"
+		}
+		if budgetDto.Amount == nil {
+			return fmt.Errorf("amount is required"), "Amount is required for new budget"
+		}
+		if _, err := s.categoryRepository.FindById(*budgetDto.CategoryId, userId, false); err != nil {
+			return err, "Category not found"
+		}
+
+		budget := model.Budget{
+			OwnerId:    userId,
+			CategoryId: *budgetDto.CategoryId,
+			Amount:     *budgetDto.Amount,
+			Period:     enum.Monthly,
+		}
+		if budgetDto.Period != nil {
+			budget.Period = *budgetDto.Period
+		}
+
+		if err, message := s.validateBudget(budget); err != nil {
+			return err, message
+		}
+
+		err := s.budgetRepository.Save(budget)
+		if err != nil {
+			return err, err.Error()
+		}
+		return nil, "Budget successfully created."
+	}
+}
+
+func (s *ApplicationBudgetService) Delete(budgetDto *dto.BudgetDto, userId string) error {
+	return s.budgetRepository.Delete(budgetDto.ID, userId)
+}
@@ -0,0 +1,146 @@
+package application
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/service/domain"
+	"fmt"
+	"regexp"
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type IApplicationCategoryService interface {
+	FindAll(userId string, includeArchived bool) []dto.CategoryDto
+	FindById(id int64, userId string) (*dto.CategoryDto, error)
+	CreateOrUpdate(categoryDto *dto.CategoryDto, userId string) (error, string)
+	Archive(id int64, userId string) error
+}
+
+type ApplicationCategoryService struct {
+	categoryService domain.ICategoryService
+}
+
+func NewApplicationCategoryService(categoryService domain.ICategoryService) *ApplicationCategoryService {
+	return &ApplicationCategoryService{
+		categoryService: categoryService,
+	}
+}
+
+func mapToDtoCategory(c model.Category) dto.CategoryDto {
+	return dto.CategoryDto{
+		ID:       c.ID,
+		Name:     &c.Name,
+		ParentId: c.ParentId,
+		Color:    &c.Color,
+		Icon:     &c.Icon,
+		Archived: &c.Archived,
+		Global:   c.OwnerId == nil,
+	}
+}
+
+func validateCategory(c model.Category) (error, string) {
+	if c.Name == "" || len(c.Name) > 100 {
+		return fmt.Errorf("invalid name"), "Name is required and must be at most 100 characters"
+	}
+	if c.Color != "" && !colorPattern.MatchString(c.Color) {
+		return fmt.Errorf("invalid color"), "Color must be a hex value like #A1B2C3"
+	}
+	if len(c.Icon) > 50 {
+		return fmt.Errorf("invalid icon"), "Icon must be at most 50 characters"
+	}
+	return nil, ""
+}
+
+func (s *ApplicationCategoryService) FindAll(userId string, includeArchived bool) []dto.CategoryDto {
+	categories := s.categoryService.FindAll(userId, includeArchived)
+	result := make([]dto.CategoryDto, len(categories))
+	for i, c := range categories {
+		result[i] = mapToDtoCategory(c)
+	}
+	return result
+}
+
+func (s *ApplicationCategoryService) FindById(id int64, userId string) (*dto.CategoryDto, error) {
+	category, err := s.categoryService.FindById(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	categoryDto := mapToDtoCategory(*category)
+	return &categoryDto, nil
+}
+
+func (s *ApplicationCategoryService) CreateOrUpdate(categoryDto *dto.CategoryDto, userId string) (error, string) {
+	if categoryDto.ID != 0 { // update
+		existing, err := s.categoryService.FindById(categoryDto.ID, userId)
+		if err != nil {
+			return err, "Category not found"
+		}
+		if existing.OwnerId == nil {
+			return fmt.Errorf("global category"), "Default categories cannot be changed"
+		}
+
+		category := *existing
+
+		// Only update fields that are provided
+		if categoryDto.Name != nil {
+			category.Name = *categoryDto.Name
+		}
+		if categoryDto.ParentId != nil {
+			if *categoryDto.ParentId == 0 {
+				category.ParentId = nil
+			} else {
+				category.ParentId = categoryDto.ParentId
+			}
+		}
+		if categoryDto.Color != nil {
+			category.Color = *categoryDto.Color
+		}
+		if categoryDto.Icon != nil {
+			category.Icon = *categoryDto.Icon
+		}
+		if categoryDto.Archived != nil {
+			category.Archived = *categoryDto.Archived
+		}
+
+		if err, message := validateCategory(category); err != nil {
+			return err, message
+		}
+
+		if err := s.categoryService.Update(category); err != nil {
+			return err, err.Error()
+		}
+		return nil, fmt.Sprintf("Category with id %d updated successfully", category.ID)
+	} else {
+		if categoryDto.Name == nil {
+			return fmt.Errorf("name is required"), "Name is required for new category"
+		}
+
+		category := model.Category{
+			Name:    *categoryDto.Name,
+			OwnerId: &userId,
+		}
+		if categoryDto.ParentId != nil && *categoryDto.ParentId != 0 {
+			category.ParentId = categoryDto.ParentId
+		}
+		if categoryDto.Color != nil {
+			category.Color = *categoryDto.Color
+		}
+		if categoryDto.Icon != nil {
+			category.Icon = *categoryDto.Icon
+		}
+
+		if err, message := validateCategory(category); err != nil {
+			return err, message
+		}
+
+		if err := s.categoryService.Save(category); err != nil {
+			return err, err.Error()
+		}
+		return nil, "Category successfully created."
+	}
+}
+
+func (s *ApplicationCategoryService) Archive(id int64, userId string) error {
+	return s.categoryService.Archive(id, userId)
+}
@@ -0,0 +1,167 @@
+package application
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/service/domain"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// receiptJobAttempts is how often a receipt is tried before the draft is left to the user.
+	receiptJobAttempts = 3
+	// receiptJobLease is how long a worker may take for a receipt before another one takes the
+	// job over, well above the OCR and provider timeouts together.
+	receiptJobLease = 5 * time.Minute
+)
+
+// receiptJobBackoff is the wait before attempt+1, doubling from 30 seconds up to 10 minutes.
+func receiptJobBackoff(attempt int) time.Duration {
+	backoff := 30 * time.Second
+	for i := 1; i < attempt && backoff < 10*time.Minute; i++ {
+		backoff *= 2
+	}
+	return min(backoff, 10*time.Minute)
+}
+
+func mapToDtoReceiptJob(j model.ReceiptJob) dto.ReceiptJobDto {
+	return dto.ReceiptJobDto{
+		ID:          j.ID,
+		Status:      j.Status,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		RunAt:       j.RunAt,
+		Error:       j.LastError,
+		ReceiptId:   j.ReceiptId,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+	}
+}
+
+// Enqueue stores the receipt image to be read in the background. Only an upload that is not
+// an image is refused right away. requestId is that of the upload, the job reads the receipt
+// under it.
+func (s *ApplicationReceiptService) Enqueue(filename string, image []byte, userId string, requestId string) (*dto.ReceiptJobDto, error) {
+	if err := domain.CheckReceiptImage(image); err != nil {
+		return nil, err
+	}
+
+	job := model.ReceiptJob{
+		OwnerId:     userId,
+		Filename:    filename,
+		ContentType: http.DetectContentType(image),
+		MaxAttempts: receiptJobAttempts,
+		RequestId:   requestId,
+	}
+	id, err := s.jobRepository.Enqueue(job, image)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default: // a worker is being woken already
+	}
+	return s.FindJob(id, userId)
+}
+
+// FindJob returns the job with the draft receipt once it is done.
+func (s *ApplicationReceiptService) FindJob(id int64, userId string) (*dto.ReceiptJobDto, error) {
+	job, err := s.jobRepository.FindById(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	jobDto := mapToDtoReceiptJob(*job)
+	if job.ReceiptId != nil {
+		receipt, err := s.FindById(*job.ReceiptId, userId)
+		if err != nil {
+			return nil, err
+		}
+		jobDto.Receipt = receipt
+	}
+	return &jobDto, nil
+}
+
+// StartWorkers reads the queued receipts with workers goroutines until ctx is cancelled. An
+// idle worker looks for jobs every pollInterval, or right away when one is enqueued here.
+func (s *ApplicationReceiptService) StartWorkers(ctx context.Context, workers int, pollInterval time.Duration) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				for ctx.Err() == nil && s.ProcessNextJob(ctx) {
+				}
+
+				select {
+				case <-ctx.Done():
+					log.Println("Receipt worker stopped")
+					return
+				case <-s.wake:
+				case <-time.After(pollInterval):
+				}
+			}
+		}()
+	}
+}
+
+// ProcessNextJob reads the next due receipt and reports whether there was one. The receipt is
+// read under ctx for at most the lease, by then another worker may have claimed the job.
+func (s *ApplicationReceiptService) ProcessNextJob(ctx context.Context) bool {
+	job, image, err := s.jobRepository.Claim(receiptJobLease)
+	if err != nil {
+		log.Printf("failed to claim receipt job: %v", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	readCtx, cancel := context.WithTimeout(domain.WithRequestID(ctx, job.RequestId), receiptJobLease)
+	defer cancel()
+	read, err := s.receiptService.Read(readCtx, job.Filename, image)
+	switch {
+	case err == nil:
+		s.finishJob(*job, enum.Succeeded, s.draftFromReceipt(*read, job.OwnerId), nil)
+	case errors.Is(err, domain.ErrInvalidImage) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Giving up on receipt job %d after %d attempts: %v", job.ID, job.Attempts, err)
+		message := err.Error()
+		s.finishJob(*job, enum.Failed, model.Transaction{Type: enum.Expense}, &message)
+	default:
+		log.Printf("Receipt job %d failed, retrying: %v", job.ID, err)
+		if err := s.jobRepository.Retry(job.ID, job.Attempts, err.Error(), time.Now().Add(receiptJobBackoff(job.Attempts))); err != nil {
+			// another worker holds the job now and reports on it
+			log.Printf("failed to requeue receipt job %d: %v", job.ID, err)
+			return true
+		}
+		s.notifyJob(job.ID, job.OwnerId)
+	}
+	return true
+}
+
+// finishJob stores the draft of the job as a receipt for the user to review.
+func (s *ApplicationReceiptService) finishJob(job model.ReceiptJob, status enum.ReceiptJobStatus, draft model.Transaction, lastError *string) {
+	draft.OwnerId = job.OwnerId
+	if draft.DateMade.IsZero() {
+		draft.DateMade = time.Now()
+	}
+	if _, err := s.jobRepository.Finish(job.ID, job.Attempts, status, draft, lastError); err != nil {
+		// the lease runs out and the job is tried again, unless another worker has it already
+		log.Printf("failed to finish receipt job %d: %v", job.ID, err)
+		return
+	}
+	s.notifyJob(job.ID, job.OwnerId)
+}
+
+// notifyJob pushes the current state of the job to the websocket connections of its owner.
+func (s *ApplicationReceiptService) notifyJob(id int64, userId string) {
+	job, err := s.FindJob(id, userId)
+	if err != nil {
+		log.Printf("failed to load receipt job %d for its notification: %v", id, err)
+		return
+	}
+	s.notificationHub.Publish(userId, domain.Notification{Type: "receipt_job", Data: job})
+}
@@ -0,0 +1,231 @@
+package application
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/repository"
+	"SmartSpend/internal/service/domain"
+	"context"
+	"fmt"
+	"time"
+)
+
+type IApplicationReceiptService interface {
+	Enqueue(filename string, image []byte, userId string, requestId string) (*dto.ReceiptJobDto, error)
+	FindJob(id int64, userId string) (*dto.ReceiptJobDto, error)
+	StartWorkers(ctx context.Context, workers int, pollInterval time.Duration)
+	ProcessNextJob(ctx context.Context) bool
+	FindDrafts(userId string) ([]dto.ReceiptDto, error)
+	FindById(id int64, userId string) (*dto.ReceiptDto, error)
+	FindImage(id int64, userId string) ([]byte, string, error)
+	UpdateDraft(id int64, changes *dto.TransactionDto, userId string) (error, string)
+	Confirm(id int64, changes *dto.TransactionDto, userId string) (error, string)
+	Reject(id int64, userId string) error
+}
+
+// ApplicationReceiptService keeps the transactions read from receipts as drafts until the user
+// has checked them. Only a confirmed draft becomes a transaction and moves a balance.
+type ApplicationReceiptService struct {
+	receiptRepository   repository.IReceiptRepository
+	jobRepository       repository.IReceiptJobRepository
+	receiptService      domain.IReceiptService
+	categoryRepository  repository.ICategoryRepository
+	transactionService  IApplicationTransactionService
+	exchangeRateService domain.IExchangeRateService
+	notificationHub     domain.INotificationHub
+	wake                chan struct{} // a job was enqueued, an idle worker should not wait for the next poll
+}
+
+func NewApplicationReceiptService(repo repository.IReceiptRepository, jobRepo repository.IReceiptJobRepository, receiptService domain.IReceiptService, categoryRepo repository.ICategoryRepository, transactionService IApplicationTransactionService, exchangeRateService domain.IExchangeRateService, notificationHub domain.INotificationHub) *ApplicationReceiptService {
+	return &ApplicationReceiptService{
+		receiptRepository:   repo,
+		jobRepository:       jobRepo,
+		receiptService:      receiptService,
+		categoryRepository:  categoryRepo,
+		transactionService:  transactionService,
+		exchangeRateService: exchangeRateService,
+		notificationHub:     notificationHub,
+		wake:                make(chan struct{}, 1),
+	}
+}
+
+func mapToDtoReceipt(r model.Receipt) dto.ReceiptDto {
+	draft := mapToDto(r.Draft)
+	for _, item := range r.Draft.Items {
+		draft.Items = append(draft.Items, mapItemToDto(item))
+	}
+	return dto.ReceiptDto{
+		ID:              r.ID,
+		Status:          r.Status,
+		Filename:        r.Filename,
+		Draft:           draft,
+		Parsed:          r.Draft.Parsed,
+		ExtractionError: r.ExtractionError,
+		TransactionId:   r.TransactionId,
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+	}
+}
+
+// draftFromReceipt keeps what the provider read from the receipt, the ids it made up are
+// dropped. A category the user does not have is left for the user to choose.
+func (s *ApplicationReceiptService) draftFromReceipt(read model.Transaction, userId string) model.Transaction {
+	draft := model.Transaction{
+		Title:      read.Title,
+		Price:      read.Price,
+		DateMade:   read.DateMade,
+		CategoryId: read.CategoryId,
+		Type:       read.Type,
+		Items:      read.Items,
+		Parsed:     read.Parsed,
+	}
+	if draft.Type != enum.Income {
+		draft.Type = enum.Expense
+	}
+	if draft.CategoryId != nil {
+		if _, err := s.categoryRepository.FindById(*draft.CategoryId, userId, false); err != nil {
+			draft.CategoryId = nil
+		}
+	}
+	for i := range draft.Items {
+		draft.Items[i].ID = 0
+	}
+	return draft
+}
+
+func (s *ApplicationReceiptService) FindDrafts(userId string) ([]dto.ReceiptDto, error) {
+	receipts, err := s.receiptRepository.FindAll(userId, enum.Draft)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.ReceiptDto, len(receipts))
+	for i, r := range receipts {
+		result[i] = mapToDtoReceipt(r)
+	}
+	return result, nil
+}
+
+func (s *ApplicationReceiptService) FindById(id int64, userId string) (*dto.ReceiptDto, error) {
+	receipt, err := s.receiptRepository.FindById(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	receiptDto := mapToDtoReceipt(*receipt)
+	return &receiptDto, nil
+}
+
+// FindImage returns the uploaded image of the receipt and its content type.
+func (s *ApplicationReceiptService) FindImage(id int64, userId string) ([]byte, string, error) {
+	return s.receiptRepository.FindImage(id, userId)
+}
+
+// applyDraftChanges sets the fields of changes that are set on the draft, the items are
+// replaced when changes has any.
+func (s *ApplicationReceiptService) applyDraftChanges(draft *model.Transaction, changes *dto.TransactionDto, userId string) (error, string) {
+	if changes.Title != nil {
+		draft.Title = *changes.Title
+	}
+	if changes.Price != nil {
+		if err, message := validateCurrency(s.exchangeRateService, changes.Price.Currency); err != nil {
+			return err, message
+		}
+		draft.Price = *changes.Price
+	}
+	if changes.DateMade != nil {
+		draft.DateMade = *changes.DateMade
+	}
+	if changes.CategoryId != nil {
+		if _, err := s.categoryRepository.FindById(*changes.CategoryId, userId, false); err != nil {
+			return err, "Category not found"
+		}
+		draft.CategoryId = changes.CategoryId
+	}
+	if changes.Type != nil {
+		draft.Type = *changes.Type
+	}
+	if changes.AccountId != nil {
+		draft.AccountId = changes.AccountId
+	}
+	if changes.TransferAccountId != nil {
+		draft.TransferAccountId = changes.TransferAccountId
+	}
+	if changes.Items != nil {
+		items, err := normalizeItems(changes.Items, draft.Price.Currency)
+		if err != nil {
+			return err, err.Error()
+		}
+		draft.Items = items
+	}
+	return nil, ""
+}
+
+// draftReceipt returns the user's receipt, which has to still be a draft.
+func (s *ApplicationReceiptService) draftReceipt(id int64, userId string) (*model.Receipt, error, string) {
+	receipt, err := s.receiptRepository.FindById(id, userId)
+	if err != nil {
+		return nil, err, "Receipt not found"
+	}
+	if receipt.Status != enum.Draft {
+		return nil, repository.ErrReceiptNotDraft, fmt.Sprintf("Receipt with id %d has already been %s", id, receiptStatusVerb(receipt.Status))
+	}
+	return receipt, nil, ""
+}
+
+func receiptStatusVerb(status enum.ReceiptStatus) string {
+	if status == enum.Confirmed {
+		return "confirmed"
+	}
+	return "rejected"
+}
+
+// UpdateDraft edits the draft of the receipt, it is checked in full only when confirmed.
+func (s *ApplicationReceiptService) UpdateDraft(id int64, changes *dto.TransactionDto, userId string) (error, string) {
+	receipt, err, message := s.draftReceipt(id, userId)
+	if err != nil {
+		return err, message
+	}
+	if err, message := s.applyDraftChanges(&receipt.Draft, changes, userId); err != nil {
+		return err, message
+	}
+	if err := s.receiptRepository.UpdateDraft(id, userId, receipt.Draft); err != nil {
+		return err, err.Error()
+	}
+	return nil, fmt.Sprintf("Receipt with id %d updated successfully", id)
+}
+
+// Confirm turns the draft, with the last changes when there are any, into a transaction
+// linked to the receipt and books it on its accounts.
+func (s *ApplicationReceiptService) Confirm(id int64, changes *dto.TransactionDto, userId string) (error, string) {
+	receipt, err, message := s.draftReceipt(id, userId)
+	if err != nil {
+		return err, message
+	}
+	if changes != nil {
+		if err, message := s.applyDraftChanges(&receipt.Draft, changes, userId); err != nil {
+			return err, message
+		}
+	}
+	if receipt.Draft.Price.IsZero() || receipt.Draft.Price.IsNegative() {
+		return fmt.Errorf("price is required"), "A positive price is required to confirm a receipt"
+	}
+
+	draftDto := mapToDto(receipt.Draft)
+	transaction, err, message := s.transactionService.NewTransaction(&draftDto, userId)
+	if err != nil {
+		return err, message
+	}
+	// the items have been normalized when they were read or edited
+	transaction.Items = receipt.Draft.Items
+
+	transactionId, err := s.receiptRepository.Confirm(id, userId, transaction)
+	if err != nil {
+		return err, err.Error()
+	}
+	return nil, fmt.Sprintf("Receipt with id %d confirmed as transaction %d", id, transactionId)
+}
+
+// Reject discards the draft, the receipt is kept.
+func (s *ApplicationReceiptService) Reject(id int64, userId string) error {
+	return s.receiptRepository.Reject(id, userId)
+}
@@ -0,0 +1,206 @@
+package application
+
+import (
+	"SmartSpend/internal/domain/dto"
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/repository"
+	"SmartSpend/internal/service/domain"
+	"fmt"
+)
+
+type IApplicationRecurringTransactionService interface {
+	FindAll(userId string) []dto.RecurringTransactionDto
+	FindById(id int64, userId string) (*dto.RecurringTransactionDto, error)
+	CreateOrUpdate(recurringDto *dto.RecurringTransactionDto, userId string) (error, string)
+	Delete(recurringDto *dto.RecurringTransactionDto, userId string) error
+}
+
+type ApplicationRecurringTransactionService struct {
+	recurringRepository repository.IRecurringTransactionRepository
+	categoryRepository  repository.ICategoryRepository
+	exchangeRateService domain.IExchangeRateService
+}
+
+func NewApplicationRecurringTransactionService(repo repository.IRecurringTransactionRepository, categoryRepo repository.ICategoryRepository, exchangeRateService domain.IExchangeRateService) *ApplicationRecurringTransactionService {
+	return &ApplicationRecurringTransactionService{
+		recurringRepository: repo,
+		categoryRepository:  categoryRepo,
+		exchangeRateService: exchangeRateService,
+	}
+}
+
+func mapToDtoRecurringTransaction(r model.RecurringTransaction) dto.RecurringTransactionDto {
+	return dto.RecurringTransactionDto{
+		ID:             r.ID,
+		Title:          &r.Title,
+		Price:          &r.Price,
+		CategoryId:     r.CategoryId,
+		Type:           &r.Type,
+		Frequency:      &r.Frequency,
+		Interval:       &r.Interval,
+		StartDate:      &r.StartDate,
+		EndDate:        r.EndDate,
+		MaxOccurrences: r.MaxOccurrences,
+		Occurrences:    r.Occurrences,
+		NextRun:        r.NextRun,
+	}
+}
+
+func validateRecurringTransaction(r model.RecurringTransaction) (error, string) {
+	switch r.Frequency {
+	case enum.Daily, enum.Weekly, enum.Monthly, enum.Yearly:
+	default:
+		return fmt.Errorf("invalid frequency"), "Frequency must be one of Daily, Weekly, Monthly or Yearly"
+	}
+	if r.Type != enum.Expense && r.Type != enum.Income {
+		return fmt.Errorf("invalid type"), "Type must be Expense or Income"
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("invalid interval"), "Interval must be at least 1"
+	}
+	if r.EndDate != nil && r.MaxOccurrences != nil {
+		return fmt.Errorf("end date and count are exclusive"), "Provide either an end date or a count, not both"
+	}
+	if r.EndDate != nil && r.EndDate.Before(r.StartDate) {
+		return fmt.Errorf("end date before start date"), "End date cannot be before the start date"
+	}
+	if r.MaxOccurrences != nil && *r.MaxOccurrences < 1 {
+		return fmt.Errorf("invalid count"), "Count must be at least 1"
+	}
+	return nil, ""
+}
+
+func (s *ApplicationRecurringTransactionService) FindAll(userId string) []dto.RecurringTransactionDto {
+	recurring := s.recurringRepository.FindAll(userId)
+	result := make([]dto.RecurringTransactionDto, len(recurring))
+	for i, r := range recurring {
+		result[i] = mapToDtoRecurringTransaction(r)
+	}
+	return result
+}
+
+func (s *ApplicationRecurringTransactionService) FindById(id int64, userId string) (*dto.RecurringTransactionDto, error) {
+	r, err := s.recurringRepository.FindById(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	recurringDto := mapToDtoRecurringTransaction(*r)
+	return &recurringDto, nil
+}
+
+func (s *ApplicationRecurringTransactionService) CreateOrUpdate(recurringDto *dto.RecurringTransactionDto, userId string) (error, string) {
+	if recurringDto.ID != 0 { // update
+		existing, err := s.recurringRepository.FindById(recurringDto.ID, userId)
+		if err != nil {
+			return err, "Recurring transaction not found"
+		}
+
+		recurring := *existing
+
+		// Only update fields that are provided
+		if recurringDto.Title != nil {
+			recurring.Title = *recurringDto.Title
+		}
+		if recurringDto.Price != nil {
+			if err, message := validateCurrency(s.exchangeRateService, recurringDto.Price.Currency); err != nil {
+				return err, message
+			}
+			price := *recurringDto.Price
+			if price.Currency == "" {
+				price.Currency = recurring.Price.Currency
+			}
+			recurring.Price = price
+		}
+		if recurringDto.CategoryId != nil {
+			if _, err := s.categoryRepository.FindById(*recurringDto.CategoryId, userId, false); err != nil {
+				return err, "Category not found"
+			}
+			recurring.CategoryId = recurringDto.CategoryId
+		}
+		if recurringDto.Type != nil {
+			recurring.Type = *recurringDto.Type
+		}
+		if recurringDto.Frequency != nil {
+			recurring.Frequency = *recurringDto.Frequency
+		}
+		if recurringDto.Interval != nil {
+			recurring.Interval = *recurringDto.Interval
+		}
+		if recurringDto.StartDate != nil {
+			recurring.StartDate = *recurringDto.StartDate
+		}
+		if recurringDto.EndDate != nil {
+			recurring.EndDate = recurringDto.EndDate
+		}
+		if recurringDto.MaxOccurrences != nil {
+			recurring.MaxOccurrences = recurringDto.MaxOccurrences
+		}
+
+		if err, message := validateRecurringTransaction(recurring); err != nil {
+			return err, message
+		}
+		recurring.NextRun = domain.NextOccurrence(recurring)
+
+		err = s.recurringRepository.Update(recurring, recurring.ID)
+		if err != nil {
+			return err, err.Error()
+		}
+		return nil, fmt.Sprintf("Recurring transaction with id %d updated successfully", recurring.ID)
+	} else {
+		if recurringDto.Title == nil || *recurringDto.Title == "" {
+			return fmt.Errorf("title is required"), "Title is required for new recurring transaction"
+		}
+		if recurringDto.Price == nil {
+			return fmt.Errorf("price is required"), "Price is required for new recurring transaction"
+		}
+		if recurringDto.Type == nil {
+			return fmt.Errorf("type is required"), "Type is required for new recurring transaction"
+		}
+		if recurringDto.Frequency == nil {
+			return fmt.Errorf("frequency is required"), "Frequency is required for new recurring transaction"
+		}
+		if recurringDto.StartDate == nil {
+			return fmt.Errorf("start date is required"), "Start date is required for new recurring transaction"
+		}
+		if err, message := validateCurrency(s.exchangeRateService, recurringDto.Price.Currency); err != nil {
+			return err, message
+		}
+		if recurringDto.CategoryId != nil {
+			if _, err := s.categoryRepository.FindById(*recurringDto.CategoryId, userId, false); err != nil {
+				return err, "Category not found"
+			}
+		}
+
+		recurring := model.RecurringTransaction{
+			OwnerId:        userId,
+			Title:          *recurringDto.Title,
+			Price:          *recurringDto.Price,
+			CategoryId:     recurringDto.CategoryId,
+			Type:           *recurringDto.Type,
+			Frequency:      *recurringDto.Frequency,
+			Interval:       1,
+			StartDate:      *recurringDto.StartDate,
+			EndDate:        recurringDto.EndDate,
+			MaxOccurrences: recurringDto.MaxOccurrences,
+		}
+		if recurringDto.Interval != nil {
+			recurring.Interval = *recurringDto.Interval
+		}
+
+		if err, message := validateRecurringTransaction(recurring); err != nil {
+			return err, message
+		}
+		recurring.NextRun = domain.NextOccurrence(recurring)
+
+		err := s.recurringRepository.Save(recurring)
+		if err != nil {
+			return err, err.Error()
+		}
+		return nil, "Recurring transaction successfully created."
+	}
+}
+
+func (s *ApplicationRecurringTransactionService) Delete(recurringDto *dto.RecurringTransactionDto, userId string) error {
+	return s.recurringRepository.Delete(recurringDto.ID, userId)
+}
@@ -2,10 +2,15 @@ package application
 
 import (
 	"SmartSpend/internal/domain/dto"
+	"SmartSpend/internal/domain/enum"
 	"SmartSpend/internal/domain/model"
 	"SmartSpend/internal/repository"
+	"SmartSpend/internal/service/domain"
 	"fmt"
+	"io"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type IApplicationTransactionService interface {
@@ -14,38 +19,95 @@ type IApplicationTransactionService interface {
 	Save(transactionDto *dto.TransactionDto) error
 	CreateOrUpdate(transactionDto *dto.TransactionDto, userId string) (error, string)
 	Delete(transactionDto *dto.TransactionDto, userId string) error
+	FindItems(id int64, userId string) (*dto.TransactionItemsDto, error)
+	UpdateItems(id int64, itemDtos []dto.TransactionItemDto, userId string) (error, string)
+	Export(w io.Writer, format string, userId string, from time.Time, to time.Time) error
+	ImportStatement(r io.Reader, format string, mappingDto dto.CSVMappingDto, userId string, commit bool) (*dto.ImportResultDto, error)
+	NewTransaction(transactionDto *dto.TransactionDto, userId string) (model.Transaction, error, string)
 }
 
 type ApplicationTransactionService struct {
 	transactionRepository repository.ITransactionRepository
+	itemRepository        repository.ITransactionItemRepository
+	categoryRepository    repository.ICategoryRepository
+	accountRepository     repository.IAccountRepository
+	exchangeRateService   domain.IExchangeRateService
 }
 
-func NewApplicationTransactionService(repo repository.ITransactionRepository) *ApplicationTransactionService {
+func NewApplicationTransactionService(repo repository.ITransactionRepository, itemRepo repository.ITransactionItemRepository, categoryRepo repository.ICategoryRepository, accountRepo repository.IAccountRepository, exchangeRateService domain.IExchangeRateService) *ApplicationTransactionService {
 	return &ApplicationTransactionService{
 		transactionRepository: repo,
+		itemRepository:        itemRepo,
+		categoryRepository:    categoryRepo,
+		accountRepository:     accountRepo,
+		exchangeRateService:   exchangeRateService,
 	}
 }
 
+// validateCurrency checks that an amount in currency can be converted into the preferred
+// currency of its owner. No currency means the preferred one and is always valid.
+func validateCurrency(exchangeRateService domain.IExchangeRateService, currency enum.Currency) (error, string) {
+	if currency != "" && !exchangeRateService.IsSupported(currency) {
+		return fmt.Errorf("unsupported currency %s", currency), fmt.Sprintf("Currency %s is not supported", currency)
+	}
+	return nil, ""
+}
+
 func mapToDto(t model.Transaction) dto.TransactionDto {
 	return dto.TransactionDto{
-		ID:         t.ID,
-		Title:      &t.Title,
-		Price:      &t.Price,
-		DateMade:   &t.DateMade,
-		CategoryId: t.CategoryId,
-		Type:       &t.Type,
+		ID:                t.ID,
+		Title:             &t.Title,
+		Price:             &t.Price,
+		ConvertedPrice:    &t.ConvertedPrice,
+		DateMade:          &t.DateMade,
+		CategoryId:        t.CategoryId,
+		Type:              &t.Type,
+		RecurringId:       t.RecurringId,
+		AccountId:         t.AccountId,
+		TransferAccountId: t.TransferAccountId,
+		ExternalId:        t.ExternalId,
+		ReceiptId:         t.ReceiptId,
 	}
 }
 
 func mapToModel(dto *dto.TransactionDto) model.Transaction {
 	return model.Transaction{
-		ID:         dto.ID,
-		Title:      *dto.Title,
-		Price:      *dto.Price,
-		DateMade:   *dto.DateMade,
-		CategoryId: dto.CategoryId,
-		Type:       *dto.Type,
+		ID:                dto.ID,
+		Title:             *dto.Title,
+		Price:             *dto.Price,
+		DateMade:          *dto.DateMade,
+		CategoryId:        dto.CategoryId,
+		Type:              *dto.Type,
+		AccountId:         dto.AccountId,
+		TransferAccountId: dto.TransferAccountId,
+	}
+}
+
+// validateAccounts checks the type of the transaction and that its accounts belong to the
+// user. A transfer needs two different accounts, the others default to the main account.
+func (s *ApplicationTransactionService) validateAccounts(t model.Transaction, userId string) (error, string) {
+	if t.Type != enum.Expense && t.Type != enum.Income && t.Type != enum.Transfer {
+		return fmt.Errorf("invalid type"), "Type must be one of Expense, Income or Transfer"
+	}
+	if t.AccountId != nil {
+		if _, err := s.accountRepository.FindById(*t.AccountId, userId); err != nil {
+			return err, "Account not found"
+		}
 	}
+	if t.Type != enum.Transfer {
+		return nil, ""
+	}
+
+	if t.AccountId == nil || t.TransferAccountId == nil {
+		return fmt.Errorf("transfer accounts are required"), "A transfer needs both an account and a transfer account"
+	}
+	if *t.AccountId == *t.TransferAccountId {
+		return fmt.Errorf("transfer to the same account"), "A transfer needs two different accounts"
+	}
+	if _, err := s.accountRepository.FindById(*t.TransferAccountId, userId); err != nil {
+		return err, "Transfer account not found"
+	}
+	return nil, ""
 }
 
 func (s *ApplicationTransactionService) FindAll(userId string, from time.Time, to time.Time) []dto.TransactionDto {
@@ -89,17 +151,36 @@ func (s *ApplicationTransactionService) CreateOrUpdate(transactionDto *dto.Trans
 			transaction.Title = *transactionDto.Title
 		}
 		if transactionDto.Price != nil {
-			transaction.Price = *transactionDto.Price
+			if err, message := validateCurrency(s.exchangeRateService, transactionDto.Price.Currency); err != nil {
+				return err, message
+			}
+			price := *transactionDto.Price
+			if price.Currency == "" { // a new amount alone keeps the currency it was paid in
+				price.Currency = transaction.Price.Currency
+			}
+			transaction.Price = price
 		}
 		if transactionDto.DateMade != nil {
 			transaction.DateMade = *transactionDto.DateMade
 		}
 		if transactionDto.CategoryId != nil {
+			if _, err := s.categoryRepository.FindById(*transactionDto.CategoryId, userId, false); err != nil {
+				return err, "Category not found"
+			}
 			transaction.CategoryId = transactionDto.CategoryId
 		}
 		if transactionDto.Type != nil {
 			transaction.Type = *transactionDto.Type
 		}
+		if transactionDto.AccountId != nil {
+			transaction.AccountId = transactionDto.AccountId
+		}
+		if transactionDto.TransferAccountId != nil {
+			transaction.TransferAccountId = transactionDto.TransferAccountId
+		}
+		if err, message := s.validateAccounts(transaction, userId); err != nil {
+			return err, message
+		}
 
 		err = s.transactionRepository.Update(transaction, transaction.ID)
 		if err != nil {
@@ -107,39 +188,303 @@ func (s *ApplicationTransactionService) CreateOrUpdate(transactionDto *dto.Trans
 		}
 		return nil, fmt.Sprintf("Transaction with id %d updated successfully", transaction.ID)
 	} else {
-		if transactionDto.Title == nil || *transactionDto.Title == "" {
-			return fmt.Errorf("title is required"), "Title is required for new transaction"
-		}
-		if transactionDto.Price == nil {
-			return fmt.Errorf("price is required"), "Price is required for new transaction"
+		transaction, err, message := s.NewTransaction(transactionDto, userId)
+		if err != nil {
+			return err, message
 		}
-		if transactionDto.Type == nil {
-			return fmt.Errorf("type is required"), "Type is required for new transaction"
+
+		err = s.transactionRepository.Save(transaction)
+		if err != nil {
+			return err, err.Error()
 		}
+		return nil, "Transaction successfully created."
+	}
+}
+
+// NewTransaction validates a new transaction of the user and builds it, without saving it.
+func (s *ApplicationTransactionService) NewTransaction(transactionDto *dto.TransactionDto, userId string) (model.Transaction, error, string) {
+	if transactionDto.Title == nil || *transactionDto.Title == "" {
+		return model.Transaction{}, fmt.Errorf("title is required"), "Title is required for new transaction"
+	}
+	if transactionDto.Price == nil {
+		return model.Transaction{}, fmt.Errorf("price is required"), "Price is required for new transaction"
+	}
+	if transactionDto.Type == nil {
+		return model.Transaction{}, fmt.Errorf("type is required"), "Type is required for new transaction"
+	}
+	if err, message := validateCurrency(s.exchangeRateService, transactionDto.Price.Currency); err != nil {
+		return model.Transaction{}, err, message
+	}
+
+	transaction := model.Transaction{
+		OwnerId:           userId,
+		Title:             *transactionDto.Title,
+		Price:             *transactionDto.Price,
+		Type:              *transactionDto.Type,
+		DateMade:          time.Now(),
+		AccountId:         transactionDto.AccountId,
+		TransferAccountId: transactionDto.TransferAccountId,
+	}
+	if err, message := s.validateAccounts(transaction, userId); err != nil {
+		return model.Transaction{}, err, message
+	}
 
-		transaction := model.Transaction{
-			OwnerId:  userId,
-			Title:    *transactionDto.Title,
-			Price:    *transactionDto.Price,
-			Type:     *transactionDto.Type,
-			DateMade: time.Now(),
+	if transactionDto.DateMade != nil {
+		transaction.DateMade = *transactionDto.DateMade
+	}
+	if transactionDto.CategoryId != nil {
+		if _, err := s.categoryRepository.FindById(*transactionDto.CategoryId, userId, false); err != nil {
+			return model.Transaction{}, err, "Category not found"
 		}
+		transaction.CategoryId = transactionDto.CategoryId
+	}
+	items, err := normalizeItems(transactionDto.Items, transaction.Price.Currency)
+	if err != nil {
+		return model.Transaction{}, err, err.Error()
+	}
+	transaction.Items = items
+	return transaction, nil, ""
+}
 
-		if transactionDto.DateMade != nil {
-			transaction.DateMade = *transactionDto.DateMade
+func (s *ApplicationTransactionService) Delete(transactionDto *dto.TransactionDto, userId string) error {
+	return s.transactionRepository.Delete(transactionDto.ID, userId)
+}
+
+func mapItemToDto(item model.TransactionItem) dto.TransactionItemDto {
+	return dto.TransactionItemDto{
+		ID:        item.ID,
+		Name:      &item.Name,
+		Quantity:  &item.Quantity,
+		UnitPrice: &item.UnitPrice,
+		Total:     &item.Total,
+		VatRate:   item.VatRate,
+	}
+}
+
+func mapItemToModel(itemDto dto.TransactionItemDto) model.TransactionItem {
+	item := model.TransactionItem{ID: itemDto.ID, VatRate: itemDto.VatRate}
+	if itemDto.Name != nil {
+		item.Name = *itemDto.Name
+	}
+	if itemDto.Quantity != nil {
+		item.Quantity = *itemDto.Quantity
+	}
+	if itemDto.UnitPrice != nil {
+		item.UnitPrice = *itemDto.UnitPrice
+	}
+	if itemDto.Total != nil {
+		item.Total = *itemDto.Total
+	}
+	return item
+}
+
+// normalizeItems validates the items of a transaction paid in currency and completes them.
+func normalizeItems(itemDtos []dto.TransactionItemDto, currency enum.Currency) ([]model.TransactionItem, error) {
+	items := make([]model.TransactionItem, len(itemDtos))
+	for i, itemDto := range itemDtos {
+		item, err := domain.NormalizeTransactionItem(mapItemToModel(itemDto), currency)
+		if err != nil {
+			return nil, err
 		}
-		if transactionDto.CategoryId != nil {
-			transaction.CategoryId = transactionDto.CategoryId
+		items[i] = item
+	}
+	return items, nil
+}
+
+// FindItems returns the items of the transaction and whether they add up to its price.
+func (s *ApplicationTransactionService) FindItems(id int64, userId string) (*dto.TransactionItemsDto, error) {
+	transaction, err := s.transactionRepository.FindById(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.itemRepository.FindAll(id, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	itemsTotal, difference := domain.ItemsDifference(transaction.Price, items)
+	result := &dto.TransactionItemsDto{
+		Items:      make([]dto.TransactionItemDto, len(items)),
+		ItemsTotal: itemsTotal,
+		Price:      transaction.Price,
+		Difference: difference,
+		Balanced:   difference.IsZero(),
+	}
+	for i, item := range items {
+		result.Items[i] = mapItemToDto(item)
+	}
+	return result, nil
+}
+
+// UpdateItems replaces the items of the transaction. Items that do not add up to the price
+// are saved anyway, FindItems flags them.
+func (s *ApplicationTransactionService) UpdateItems(id int64, itemDtos []dto.TransactionItemDto, userId string) (error, string) {
+	transaction, err := s.transactionRepository.FindById(id, userId)
+	if err != nil {
+		return err, "Transaction not found"
+	}
+	items, err := normalizeItems(itemDtos, transaction.Price.Currency)
+	if err != nil {
+		return err, err.Error()
+	}
+	if err := s.itemRepository.ReplaceAll(id, userId, items); err != nil {
+		return err, err.Error()
+	}
+	return nil, fmt.Sprintf("Items of transaction with id %d updated successfully", id)
+}
+
+// importAccount returns the account a statement is imported into, the main account by default.
+func (s *ApplicationTransactionService) importAccount(accountId *int64, userId string) (*model.Account, error) {
+	if accountId != nil {
+		return s.accountRepository.FindById(*accountId, userId)
+	}
+	accounts := s.accountRepository.FindAll(userId)
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("account not found")
+	}
+	return &accounts[0], nil
+}
+
+func mapToCSVMapping(mappingDto dto.CSVMappingDto) (domain.CSVMapping, error) {
+	mapping := domain.CSVMapping{
+		DateColumn:        mappingDto.DateColumn,
+		AmountColumn:      mappingDto.AmountColumn,
+		DescriptionColumn: mappingDto.DescriptionColumn,
+		DebitColumn:       mappingDto.DebitColumn,
+		CreditColumn:      mappingDto.CreditColumn,
+		SignConvention:    domain.SignConvention(mappingDto.SignConvention),
+		DateFormat:        mappingDto.DateFormat,
+		DecimalSeparator:  mappingDto.DecimalSeparator,
+		HasHeader:         mappingDto.HasHeader == nil || *mappingDto.HasHeader,
+	}
+
+	switch mapping.SignConvention {
+	case "":
+		mapping.SignConvention = domain.NegativeIsExpense
+	case domain.NegativeIsExpense, domain.PositiveIsExpense, domain.DebitCredit:
+	default:
+		return mapping, fmt.Errorf("sign convention must be one of negative_expense, positive_expense or debit_credit")
+	}
+	if mapping.DecimalSeparator == "" {
+		mapping.DecimalSeparator = "."
+	} else if mapping.DecimalSeparator != "." && mapping.DecimalSeparator != "," {
+		return mapping, fmt.Errorf("decimal separator must be . or ,")
+	}
+	if mappingDto.Delimiter != "" {
+		delimiter, size := utf8.DecodeRuneInString(mappingDto.Delimiter)
+		if size != len(mappingDto.Delimiter) {
+			return mapping, fmt.Errorf("delimiter must be a single character")
 		}
+		mapping.Delimiter = delimiter
+	}
+	return mapping, nil
+}
 
-		err := s.transactionRepository.Save(transaction)
+// statementParser returns the parser for a statement format: csv, ofx (also qfx) or camt.
+func statementParser(format string, mappingDto dto.CSVMappingDto) (domain.IStatementParser, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		mapping, err := mapToCSVMapping(mappingDto)
 		if err != nil {
-			return err, err.Error()
+			return nil, err
 		}
-		return nil, "Transaction successfully created."
+		return domain.NewCSVStatementParser(mapping), nil
+	case "ofx", "qfx":
+		return domain.NewOFXStatementParser(), nil
+	case "camt", "camt053", "camt.053":
+		return domain.NewCAMTStatementParser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported statement format %q, use csv, ofx or camt", format)
 	}
 }
 
-func (s *ApplicationTransactionService) Delete(transactionDto *dto.TransactionDto, userId string) error {
-	return s.transactionRepository.Delete(transactionDto.ID, userId)
+// ImportStatement reads a bank statement into transactions of the user. Without commit it
+// only returns a preview; with commit every row is saved in a single database transaction.
+// Rows that could not be read prevent the commit unless the mapping asks to skip them, rows
+// the bank id of which has already been imported are skipped.
+func (s *ApplicationTransactionService) ImportStatement(r io.Reader, format string, mappingDto dto.CSVMappingDto, userId string, commit bool) (*dto.ImportResultDto, error) {
+	parser, err := statementParser(format, mappingDto)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.importAccount(mappingDto.AccountId, userId)
+	if err != nil {
+		return nil, err
+	}
+	currency := account.Currency
+	if mappingDto.Currency != nil {
+		if err, message := validateCurrency(s.exchangeRateService, *mappingDto.Currency); err != nil {
+			return nil, fmt.Errorf("%s", message)
+		}
+		currency = *mappingDto.Currency
+	}
+
+	rows, err := parser.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.ImportResultDto{Rows: make([]dto.ImportRowDto, len(rows))}
+	var transactions []model.Transaction
+	for i, row := range rows {
+		result.Rows[i].Line = row.Line
+		transaction := row.Transaction
+		if row.Err == nil && transaction.Price.Currency != "" {
+			// a statement that names its currency knows better than the mapping
+			row.Err, _ = validateCurrency(s.exchangeRateService, transaction.Price.Currency)
+		}
+		if row.Err != nil {
+			result.Rows[i].Error = row.Err.Error()
+			result.Failed++
+			continue
+		}
+
+		if transaction.Title == "" {
+			transaction.Title = "Imported transaction"
+		}
+		if transaction.Price.Currency == "" {
+			transaction.Price.Currency = currency
+		}
+		transaction.Price = transaction.Price.Round()
+		transaction.OwnerId = userId
+		transaction.AccountId = &account.ID
+
+		transactionDto := mapToDto(transaction)
+		transactionDto.ConvertedPrice = nil
+		result.Rows[i].Transaction = &transactionDto
+		transactions = append(transactions, transaction)
+	}
+	result.Imported = len(transactions)
+
+	if !commit {
+		return result, nil
+	}
+	if result.Failed > 0 && !mappingDto.SkipInvalid {
+		return nil, fmt.Errorf("%d rows could not be read, fix them or import with skip_invalid", result.Failed)
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("the statement has no transactions")
+	}
+	saved, err := s.transactionRepository.SaveBatch(transactions)
+	if err != nil {
+		return nil, err
+	}
+	result.Duplicates = len(transactions) - saved
+	result.Imported = saved
+	result.Committed = true
+	return result, nil
+}
+
+// Export writes the user's transactions between from and to in the given format, streaming
+// them from the database.
+func (s *ApplicationTransactionService) Export(w io.Writer, format string, userId string, from time.Time, to time.Time) error {
+	writer, err := domain.NewTransactionExportWriter(format, w)
+	if err != nil {
+		return err
+	}
+	if err := s.transactionRepository.Export(userId, from, to, writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
 }
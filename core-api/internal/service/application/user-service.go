@@ -4,6 +4,7 @@ import (
 	"SmartSpend/internal/domain/dto"
 	"SmartSpend/internal/domain/model"
 	"SmartSpend/internal/service/domain"
+	"fmt"
 )
 
 type IUserAppService interface {
@@ -16,12 +17,14 @@ type IUserAppService interface {
 }
 
 type UserAppService struct {
-	domainService domain.IUserService
+	domainService       domain.IUserService
+	exchangeRateService domain.IExchangeRateService
 }
 
-func NewUserAppService(ds domain.IUserService) *UserAppService {
+func NewUserAppService(ds domain.IUserService, exchangeRateService domain.IExchangeRateService) *UserAppService {
 	return &UserAppService{
-		domainService: ds,
+		domainService:       ds,
+		exchangeRateService: exchangeRateService,
 	}
 }
 
@@ -52,7 +55,6 @@ func mapToUpdateUserDTO(user *model.User) *dto.UpdateUserDto {
 		LastName:          &user.LastName,
 		Username:          &user.Username,
 		AvatarURL:         &user.AvatarURL,
-		Balance:           &user.Balance,
 		MonthlySavingGoal: &user.MonthlySavingGoal,
 		PreferredCurrency: &user.PreferredCurrency,
 	}
@@ -114,6 +116,15 @@ func (u *UserAppService) Update(id string, newUserUpdate dto.UpdateUserDto) erro
 		return err
 	}
 
+	// the saving goal and budgets are converted into a new currency together with the rest of
+	// the update, an explicit saving goal without a currency is taken as being in the new one
+	if newUserUpdate.PreferredCurrency != nil && *newUserUpdate.PreferredCurrency != existing.PreferredCurrency {
+		if !u.exchangeRateService.IsSupported(*newUserUpdate.PreferredCurrency) {
+			return fmt.Errorf("currency %s is not supported", *newUserUpdate.PreferredCurrency)
+		}
+		existing.PreferredCurrency = *newUserUpdate.PreferredCurrency
+	}
+
 	if newUserUpdate.FirstName != nil {
 		existing.FirstName = *newUserUpdate.FirstName
 	}
@@ -126,14 +137,13 @@ func (u *UserAppService) Update(id string, newUserUpdate dto.UpdateUserDto) erro
 	if newUserUpdate.AvatarURL != nil {
 		existing.AvatarURL = *newUserUpdate.AvatarURL
 	}
-	if newUserUpdate.Balance != nil {
-		existing.Balance = *newUserUpdate.Balance
-	}
 	if newUserUpdate.MonthlySavingGoal != nil {
 		existing.MonthlySavingGoal = *newUserUpdate.MonthlySavingGoal
-	}
-	if newUserUpdate.PreferredCurrency != nil {
-		existing.PreferredCurrency = *newUserUpdate.PreferredCurrency
+		if existing.MonthlySavingGoal.Currency == "" {
+			existing.MonthlySavingGoal.Currency = existing.PreferredCurrency
+		} else if !u.exchangeRateService.IsSupported(existing.MonthlySavingGoal.Currency) {
+			return fmt.Errorf("currency %s is not supported", existing.MonthlySavingGoal.Currency)
+		}
 	}
 
 	return u.domainService.Update(*existing)
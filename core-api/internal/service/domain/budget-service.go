@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"SmartSpend/internal/repository"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type IBudgetService interface {
+	FindAll(userId string) []model.Budget
+	FindById(id int64, userId string) (*model.Budget, error)
+	FindAllWithSpending(userId string, at time.Time) ([]model.BudgetSpending, error)
+	FindSpending(budget model.Budget, at time.Time) (model.BudgetSpending, error)
+}
+
+type BudgetService struct {
+	budgetRepository     repository.IBudgetRepository
+	statisticsRepository repository.IStatisticsRepository
+}
+
+func NewBudgetService(budgetRepo repository.IBudgetRepository, statisticsRepo repository.IStatisticsRepository) *BudgetService {
+	return &BudgetService{
+		budgetRepository:     budgetRepo,
+		statisticsRepository: statisticsRepo,
+	}
+}
+
+// BudgetPeriodBounds returns the first and last instant of the period that contains at.
+// Weeks start on Monday.
+func BudgetPeriodBounds(period enum.Frequency, at time.Time) (time.Time, time.Time) {
+	year, month, day := at.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, at.Location())
+
+	var end time.Time
+	switch period {
+	case enum.Daily:
+		end = start.AddDate(0, 0, 1)
+	case enum.Weekly:
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		end = start.AddDate(0, 0, 7)
+	case enum.Yearly:
+		start = time.Date(year, time.January, 1, 0, 0, 0, 0, at.Location())
+		end = start.AddDate(1, 0, 0)
+	default: // Monthly
+		start = time.Date(year, month, 1, 0, 0, 0, 0, at.Location())
+		end = start.AddDate(0, 1, 0)
+	}
+
+	// the statistics queries use BETWEEN, so the end is inclusive
+	return start, end.Add(-time.Nanosecond)
+}
+
+func (b *BudgetService) FindAll(userId string) []model.Budget {
+	return b.budgetRepository.FindAll(userId)
+}
+
+func (b *BudgetService) FindById(id int64, userId string) (*model.Budget, error) {
+	return b.budgetRepository.FindById(id, userId)
+}
+
+func (b *BudgetService) FindAllWithSpending(userId string, at time.Time) ([]model.BudgetSpending, error) {
+	budgets := b.budgetRepository.FindAll(userId)
+
+	// one aggregation per period is enough, all monthly budgets share the same window
+	spentPerPeriod := make(map[enum.Frequency]map[int64]money.Money)
+	result := make([]model.BudgetSpending, len(budgets))
+	for i, budget := range budgets {
+		from, to := BudgetPeriodBounds(budget.Period, at)
+
+		spent, ok := spentPerPeriod[budget.Period]
+		if !ok {
+			var err error
+			spent, err = b.statisticsRepository.FindTotalSpentPerCategory(userId, from, to)
+			if err != nil {
+				return nil, err
+			}
+			spentPerPeriod[budget.Period] = spent
+		}
+
+		result[i] = newBudgetSpending(budget, from, to, spent[budget.CategoryId].Amount)
+	}
+	return result, nil
+}
+
+func (b *BudgetService) FindSpending(budget model.Budget, at time.Time) (model.BudgetSpending, error) {
+	from, to := BudgetPeriodBounds(budget.Period, at)
+	spent, err := b.statisticsRepository.FindTotalSpentPerCategory(budget.OwnerId, from, to)
+	if err != nil {
+		return model.BudgetSpending{}, err
+	}
+	return newBudgetSpending(budget, from, to, spent[budget.CategoryId].Amount), nil
+}
+
+// newBudgetSpending takes the spent amount in the budget's currency.
+func newBudgetSpending(budget model.Budget, from time.Time, to time.Time, spent decimal.Decimal) model.BudgetSpending {
+	spentMoney := money.New(spent, budget.Amount.Currency)
+	return model.BudgetSpending{
+		Budget:      budget,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Spent:       spentMoney,
+		Remaining:   budget.Amount.Sub(spentMoney),
+		PercentUsed: spentMoney.PercentOf(budget.Amount),
+		Overspent:   spentMoney.GreaterThan(budget.Amount),
+	}
+}
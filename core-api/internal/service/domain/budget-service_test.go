@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetPeriodBounds(t *testing.T) {
+	at := time.Date(2025, time.July, 17, 15, 30, 0, 0, time.UTC) // a Thursday
+
+	from, to := BudgetPeriodBounds(enum.Monthly, at)
+	assert.Equal(t, time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond), to)
+
+	from, to = BudgetPeriodBounds(enum.Weekly, at)
+	assert.Equal(t, time.Date(2025, time.July, 14, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2025, time.July, 21, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond), to)
+
+	from, _ = BudgetPeriodBounds(enum.Yearly, at)
+	assert.Equal(t, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), from)
+}
+
+func TestNewBudgetSpendingFlagsOverspend(t *testing.T) {
+	budget := model.Budget{CategoryId: 1, Amount: money.MustFromString("4000", enum.MKD), Period: enum.Monthly}
+
+	spending := newBudgetSpending(budget, time.Time{}, time.Time{}, decimal.RequireFromString("1000.10"))
+	assert.Equal(t, "2999.90 MKD", spending.Remaining.String())
+	assert.Equal(t, "25", spending.PercentUsed.String())
+	assert.False(t, spending.Overspent)
+
+	spending = newBudgetSpending(budget, time.Time{}, time.Time{}, decimal.RequireFromString("5000"))
+	assert.Equal(t, "-1000.00 MKD", spending.Remaining.String())
+	assert.True(t, spending.Overspent)
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CAMTStatementParser reads ISO 20022 CAMT.053 bank to customer statements. Entries that
+// are not booked yet are left out, they may still change or disappear.
+type CAMTStatementParser struct{}
+
+func NewCAMTStatementParser() *CAMTStatementParser {
+	return &CAMTStatementParser{}
+}
+
+// camtDocument is the part of a CAMT.053 document the import needs. The elements are matched
+// by their local name, so every version of the camt.053.001 schema is accepted.
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	// Status is BOOK, PDNG or INFO, newer versions wrap it in a Cd element
+	Status struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate        camtDate `xml:"BookgDt"`
+	ValueDate          camtDate `xml:"ValDt"`
+	AccountServicerRef string   `xml:"AcctSvcrRef"`
+	AdditionalInfo     string   `xml:"AddtlNtryInf"`
+	Details            []struct {
+		AccountServicerRef string   `xml:"Refs>AcctSvcrRef"`
+		Creditor           string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorParty      string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Debtor             string   `xml:"RltdPties>Dbtr>Nm"`
+		DebtorParty        string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+		Remittance         []string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) parse() (time.Time, bool) {
+	if d.Date != "" {
+		date, err := time.Parse(time.DateOnly, d.Date)
+		return date, err == nil
+	}
+	if d.DateTime != "" {
+		// ISODateTime, with or without a time zone
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+			if date, err := time.Parse(layout, d.DateTime); err == nil {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (p *CAMTStatementParser) Parse(r io.Reader) ([]StatementRow, error) {
+	var document camtDocument
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to read CAMT.053 statement: %v", err)
+	}
+
+	var rows []StatementRow
+	position := 0
+	for _, statement := range document.Statements {
+		for _, entry := range statement.Entries {
+			position++
+			status := strings.TrimSpace(entry.Status.Code + entry.Status.Value)
+			if status != "" && status != "BOOK" {
+				continue
+			}
+			row := StatementRow{Line: position}
+			row.Err = entry.toTransaction(&row)
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// toTransaction fills the transaction of row, the credit/debit indicator gives the direction.
+func (e camtEntry) toTransaction(row *StatementRow) error {
+	date, ok := e.BookingDate.parse()
+	if !ok {
+		if date, ok = e.ValueDate.parse(); !ok {
+			return fmt.Errorf("entry has no valid booking date")
+		}
+	}
+	row.Transaction.DateMade = date
+
+	amount, err := decimal.NewFromString(strings.TrimSpace(e.Amount.Value))
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", e.Amount.Value)
+	}
+	switch strings.TrimSpace(e.CreditDebit) {
+	case "CRDT":
+	case "DBIT":
+		amount = amount.Neg()
+	default:
+		return fmt.Errorf("invalid credit/debit indicator %q", e.CreditDebit)
+	}
+	if err := setSignedAmount(&row.Transaction, amount, enum.Currency(e.Amount.Currency)); err != nil {
+		return err
+	}
+
+	// the counterparty is the creditor of money going out and the debtor of money coming in
+	var counterparty, remittance, reference string
+	for _, details := range e.Details {
+		if row.Transaction.Type == enum.Expense {
+			counterparty = firstNonEmpty(counterparty, details.Creditor, details.CreditorParty)
+		} else {
+			counterparty = firstNonEmpty(counterparty, details.Debtor, details.DebtorParty)
+		}
+		remittance = firstNonEmpty(remittance, strings.Join(details.Remittance, " "))
+		reference = firstNonEmpty(reference, details.AccountServicerRef)
+	}
+	row.Transaction.Title = firstNonEmpty(counterparty, remittance, e.AdditionalInfo)
+
+	if reference = firstNonEmpty(e.AccountServicerRef, reference); reference != "" {
+		row.Transaction.ExternalId = &reference
+	}
+	return nil
+}
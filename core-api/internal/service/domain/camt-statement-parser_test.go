@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const camtStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Ntry>
+        <Amt Ccy="EUR">42.10</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-07-04</Dt></BookgDt>
+        <AcctSvcrRef>REF-1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties><Cdtr><Pty><Nm>Supermarket</Nm></Pty></Cdtr></RltdPties>
+          <RmtInf><Ustrd>Card 1234</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">2000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><DtTm>2025-07-05T08:00:00+02:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls>
+          <Refs><AcctSvcrRef>REF-2</AcctSvcrRef></Refs>
+          <RmtInf><Ustrd>Salary</Ustrd><Ustrd>July</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2025-07-06</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt>
+        <CdtDbtInd>XXXX</CdtDbtInd>
+        <BookgDt><Dt>2025-07-06</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestCAMTStatementParser(t *testing.T) {
+	rows, err := NewCAMTStatementParser().Parse(strings.NewReader(camtStatement))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3) // the pending entry is left out
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, enum.Expense, rows[0].Transaction.Type)
+	assert.Equal(t, "42.10 EUR", rows[0].Transaction.Price.String())
+	assert.Equal(t, "Supermarket", rows[0].Transaction.Title)
+	assert.Equal(t, "REF-1", *rows[0].Transaction.ExternalId)
+
+	assert.NoError(t, rows[1].Err)
+	assert.Equal(t, enum.Income, rows[1].Transaction.Type)
+	assert.Equal(t, "Salary July", rows[1].Transaction.Title)
+	assert.Equal(t, "REF-2", *rows[1].Transaction.ExternalId)
+
+	assert.Error(t, rows[2].Err)
+	assert.Equal(t, 4, rows[2].Line)
+}
+
+func TestCAMTStatementParserInvalidXML(t *testing.T) {
+	_, err := NewCAMTStatementParser().Parse(strings.NewReader("<Document>"))
+	assert.Error(t, err)
+}
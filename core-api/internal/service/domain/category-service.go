@@ -3,10 +3,15 @@ package domain
 import (
 	"SmartSpend/internal/domain/model"
 	"SmartSpend/internal/repository"
+	"fmt"
 )
 
 type ICategoryService interface {
-	FindAll() []model.Category
+	FindAll(userId string, includeArchived bool) []model.Category
+	FindById(id int64, userId string) (*model.Category, error)
+	Save(category model.Category) error
+	Update(category model.Category) error
+	Archive(id int64, userId string) error
 }
 
 type CategoryService struct {
@@ -19,6 +24,63 @@ func NewCategoryService(repo repository.ICategoryRepository) *CategoryService {
 	}
 }
 
-func (c *CategoryService) FindAll() []model.Category {
-	return c.categoryRepository.FindAll()
+func (c *CategoryService) FindAll(userId string, includeArchived bool) []model.Category {
+	return c.categoryRepository.FindAll(userId, includeArchived)
+}
+
+func (c *CategoryService) FindById(id int64, userId string) (*model.Category, error) {
+	return c.categoryRepository.FindById(id, userId, true)
+}
+
+func (c *CategoryService) Save(category model.Category) error {
+	if err := c.validateParent(category); err != nil {
+		return err
+	}
+	return c.categoryRepository.Save(category)
+}
+
+func (c *CategoryService) Update(category model.Category) error {
+	if err := c.validateParent(category); err != nil {
+		return err
+	}
+	return c.categoryRepository.Update(category, category.ID)
+}
+
+func (c *CategoryService) Archive(id int64, userId string) error {
+	return c.categoryRepository.Archive(id, userId)
+}
+
+// validateParent makes sure the parent is visible to the owner, not archived and
+// that walking up from it never reaches the category itself.
+func (c *CategoryService) validateParent(category model.Category) error {
+	if category.ParentId == nil {
+		return nil
+	}
+
+	userId := ""
+	if category.OwnerId != nil {
+		userId = *category.OwnerId
+	}
+
+	parent, err := c.categoryRepository.FindById(*category.ParentId, userId, true)
+	if err != nil {
+		return fmt.Errorf("parent category not found")
+	}
+	if parent.Archived {
+		return fmt.Errorf("parent category is archived")
+	}
+
+	for ancestor := parent; ancestor != nil; {
+		if category.ID != 0 && ancestor.ID == category.ID {
+			return fmt.Errorf("a category cannot be nested under itself or one of its children")
+		}
+		if ancestor.ParentId == nil {
+			break
+		}
+		ancestor, err = c.categoryRepository.FindById(*ancestor.ParentId, userId, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/model"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type inMemoryCategoryRepository struct {
+	categories map[int64]model.Category
+}
+
+func (r *inMemoryCategoryRepository) FindAll(userId string, includeArchived bool) []model.Category {
+	return nil
+}
+
+func (r *inMemoryCategoryRepository) FindById(id int64, userId string, includeArchived bool) (*model.Category, error) {
+	c, ok := r.categories[id]
+	if !ok || (c.OwnerId != nil && *c.OwnerId != userId) || (c.Archived && !includeArchived) {
+		return nil, fmt.Errorf("category not found")
+	}
+	return &c, nil
+}
+
+func (r *inMemoryCategoryRepository) Save(category model.Category) error { return nil }
+
+func (r *inMemoryCategoryRepository) Update(category model.Category, id int64) error { return nil }
+
+func (r *inMemoryCategoryRepository) Archive(id int64, userId string) error { return nil }
+
+func TestCategoryParentValidation(t *testing.T) {
+	owner, other := "owner", "other"
+	food, restaurants, fastFood := int64(1), int64(2), int64(3)
+	repo := &inMemoryCategoryRepository{categories: map[int64]model.Category{
+		food:        {ID: food, Name: "Food"},
+		restaurants: {ID: restaurants, Name: "Restaurants", OwnerId: &owner, ParentId: &food},
+		fastFood:    {ID: fastFood, Name: "Fast food", OwnerId: &owner, ParentId: &restaurants},
+		4:           {ID: 4, Name: "Archived", OwnerId: &owner, Archived: true},
+		5:           {ID: 5, Name: "Someone else's", OwnerId: &other},
+	}}
+	service := NewCategoryService(repo)
+
+	parent := restaurants
+	assert.NoError(t, service.Save(model.Category{Name: "Pizza", OwnerId: &owner, ParentId: &parent}))
+
+	parent = 4
+	assert.Error(t, service.Save(model.Category{Name: "Pizza", OwnerId: &owner, ParentId: &parent}))
+
+	parent = 5
+	assert.Error(t, service.Save(model.Category{Name: "Pizza", OwnerId: &owner, ParentId: &parent}))
+
+	// Restaurants cannot move under its own grandchild
+	parent = fastFood
+	assert.Error(t, service.Update(model.Category{ID: restaurants, Name: "Restaurants", OwnerId: &owner, ParentId: &parent}))
+}
@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/repository"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// IExchangeRateProvider is a source of euro reference rates.
+type IExchangeRateProvider interface {
+	FetchRates() ([]model.ExchangeRate, error)
+}
+
+// ECBFileProvider reads the rates from a local copy of the ECB reference rates, either the
+// daily (eurofxref-daily.xml) or the historical (eurofxref-hist.xml) file.
+type ECBFileProvider struct {
+	path string
+}
+
+func NewECBFileProvider(path string) *ECBFileProvider {
+	return &ECBFileProvider{path: path}
+}
+
+func (p *ECBFileProvider) FetchRates() ([]model.ExchangeRate, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseECBRates(file)
+}
+
+// ecbEnvelope is the layout of the ECB files, one inner Cube per day:
+// <Cube><Cube time="2025-07-17"><Cube currency="USD" rate="1.1588"/>...</Cube></Cube>
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func ParseECBRates(r io.Reader) ([]model.ExchangeRate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode ECB rates: %v", err)
+	}
+
+	var rates []model.ExchangeRate
+	for _, day := range envelope.Days {
+		date, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ECB rate date %q", day.Time)
+		}
+		for _, rate := range day.Rates {
+			value, err := decimal.NewFromString(rate.Rate)
+			if err != nil || !value.IsPositive() {
+				return nil, fmt.Errorf("invalid ECB rate %q for %s", rate.Rate, rate.Currency)
+			}
+			rates = append(rates, model.ExchangeRate{
+				Currency: enum.Currency(rate.Currency),
+				Date:     date,
+				Rate:     value,
+			})
+		}
+	}
+	return rates, nil
+}
+
+type IExchangeRateService interface {
+	FindCurrencies() []enum.Currency
+	IsSupported(currency enum.Currency) bool
+	Import(provider IExchangeRateProvider) (int, error)
+	StartImporter(ctx context.Context, provider IExchangeRateProvider, interval time.Duration)
+}
+
+type ExchangeRateService struct {
+	exchangeRateRepository repository.IExchangeRateRepository
+}
+
+func NewExchangeRateService(repo repository.IExchangeRateRepository) *ExchangeRateService {
+	return &ExchangeRateService{
+		exchangeRateRepository: repo,
+	}
+}
+
+func (s *ExchangeRateService) FindCurrencies() []enum.Currency {
+	return s.exchangeRateRepository.FindCurrencies()
+}
+
+// IsSupported reports whether amounts in currency can be converted, i.e. a rate exists for it.
+func (s *ExchangeRateService) IsSupported(currency enum.Currency) bool {
+	for _, c := range s.exchangeRateRepository.FindCurrencies() {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// Import stores the rates of the provider and returns how many were imported.
+func (s *ExchangeRateService) Import(provider IExchangeRateProvider) (int, error) {
+	rates, err := provider.FetchRates()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.exchangeRateRepository.SaveAll(rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+// StartImporter imports the rates of provider now and then every interval until ctx is cancelled.
+func (s *ExchangeRateService) StartImporter(ctx context.Context, provider IExchangeRateProvider, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if imported, err := s.Import(provider); err != nil {
+				log.Printf("failed to import exchange rates: %v", err)
+			} else {
+				log.Printf("Imported %d exchange rates", imported)
+			}
+
+			select {
+			case <-ctx.Done():
+				log.Println("Exchange rate importer stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ecbDailyRates = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time="2025-07-17">
+			<Cube currency="USD" rate="1.1588"/>
+			<Cube currency="GBP" rate="0.86518"/>
+		</Cube>
+		<Cube time="2025-07-16">
+			<Cube currency="USD" rate="1.1618"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestParseECBRates(t *testing.T) {
+	rates, err := ParseECBRates(strings.NewReader(ecbDailyRates))
+	assert.NoError(t, err)
+	assert.Len(t, rates, 3)
+
+	assert.Equal(t, enum.USD, rates[0].Currency)
+	assert.Equal(t, time.Date(2025, time.July, 17, 0, 0, 0, 0, time.UTC), rates[0].Date)
+	assert.Equal(t, "1.1588", rates[0].Rate.String())
+	assert.Equal(t, time.Date(2025, time.July, 16, 0, 0, 0, 0, time.UTC), rates[2].Date)
+}
+
+func TestParseECBRatesRejectsInvalidRate(t *testing.T) {
+	_, err := ParseECBRates(strings.NewReader(`<Envelope><Cube><Cube time="2025-07-17"><Cube currency="USD" rate="-1"/></Cube></Cube></Envelope>`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// Notification is a message pushed to the websocket connections of a user.
+type Notification struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+type INotificationHub interface {
+	// Subscribe returns the messages for the user until the returned function is called.
+	Subscribe(userId string) (<-chan []byte, func())
+	Publish(userId string, notification Notification)
+}
+
+// NotificationHub hands notifications to the subscribers of this process, a user connected to
+// another instance does not get them and has to poll.
+type NotificationHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan []byte]struct{}
+}
+
+// notificationBuffer is how many messages a slow connection may fall behind before it misses some.
+const notificationBuffer = 16
+
+func NewNotificationHub() *NotificationHub {
+	return &NotificationHub{subscribers: map[string]map[chan []byte]struct{}{}}
+}
+
+func (h *NotificationHub) Subscribe(userId string) (<-chan []byte, func()) {
+	ch := make(chan []byte, notificationBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[userId] == nil {
+		h.subscribers[userId] = map[chan []byte]struct{}{}
+	}
+	h.subscribers[userId][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers[userId], ch)
+			if len(h.subscribers[userId]) == 0 {
+				delete(h.subscribers, userId)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish sends the notification as JSON to every connection of the user without waiting for
+// any of them.
+func (h *NotificationHub) Publish(userId string, notification Notification) {
+	message, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("failed to marshal %s notification: %v", notification.Type, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[userId] {
+		select {
+		case ch <- message:
+		default:
+			log.Printf("Dropping %s notification for a slow connection", notification.Type)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationHubPublishesToTheUserOnly(t *testing.T) {
+	hub := NewNotificationHub()
+	mine, unsubscribeMine := hub.Subscribe("user-1")
+	defer unsubscribeMine()
+	other, unsubscribeOther := hub.Subscribe("user-2")
+	defer unsubscribeOther()
+
+	hub.Publish("user-1", Notification{Type: "receipt_job", Data: map[string]int{"id": 7}})
+
+	assert.Len(t, mine, 1)
+	assert.JSONEq(t, `{"type": "receipt_job", "data": {"id": 7}}`, string(<-mine))
+	assert.Len(t, other, 0)
+}
+
+func TestNotificationHubUnsubscribe(t *testing.T) {
+	hub := NewNotificationHub()
+	messages, unsubscribe := hub.Subscribe("user-1")
+	unsubscribe()
+	unsubscribe() // twice is fine
+
+	hub.Publish("user-1", Notification{Type: "receipt_job"})
+
+	_, open := <-messages
+	assert.False(t, open)
+	assert.Empty(t, hub.subscribers)
+}
+
+func TestNotificationHubDoesNotBlockOnASlowConnection(t *testing.T) {
+	hub := NewNotificationHub()
+	messages, unsubscribe := hub.Subscribe("user-1")
+	defer unsubscribe()
+
+	for i := 0; i < notificationBuffer+5; i++ {
+		hub.Publish("user-1", Notification{Type: "receipt_job"})
+	}
+	assert.Len(t, messages, notificationBuffer)
+}
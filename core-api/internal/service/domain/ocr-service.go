@@ -0,0 +1,275 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// OCRResult is one variant of the text the ocr-service read, every variant comes from a
+// differently preprocessed copy of the image.
+type OCRResult struct {
+	Version     string    `json:"version"`
+	Text        string    `json:"text"`
+	Confidence  float64   `json:"confidence,omitempty"`
+	Description string    `json:"description"`
+	Fiscal      *FiscalQR `json:"fiscal,omitempty"`
+}
+
+// FiscalQR is what the ocr-service read from the QR code of a fiscal receipt, the "qr"
+// variant. Fields the code does not carry are empty.
+type FiscalQR struct {
+	Payload       string `json:"payload"`
+	Total         string `json:"total,omitempty"` // decimal with a dot
+	Date          string `json:"date,omitempty"`  // local time, 2006-01-02T15:04:05
+	TaxNumber     string `json:"tax_number,omitempty"`
+	ReceiptNumber string `json:"receipt_number,omitempty"`
+	DeviceId      string `json:"device_id,omitempty"`
+}
+
+// OCRReading is what the ocr-service made of an image: the best text variant and the fiscal
+// QR code, either may be missing.
+type OCRReading struct {
+	Best   *OCRResult
+	Fiscal *FiscalQR
+}
+
+// qrVersion is the variant the ocr-service returns for a decoded QR code, its text is the
+// payload rather than the receipt.
+const qrVersion = "qr"
+
+type OCRResponse struct {
+	Results []OCRResult `json:"results"`
+	Best    *OCRResult  `json:"best,omitempty"` // the variant, or consensus of them, the service trusts most
+}
+
+// ErrOCRUnavailable is returned when the ocr-service is not configured or could not be
+// reached, receipts are then read from the image alone.
+var ErrOCRUnavailable = errors.New("ocr service unavailable")
+
+type IOCRService interface {
+	// Recognize returns the best of the text variants the ocr-service read from the image and
+	// the fiscal QR code when it found one.
+	Recognize(ctx context.Context, filename string, image []byte) (*OCRReading, error)
+}
+
+type OCRService struct {
+	url       string
+	client    *http.Client
+	retries   int
+	backoff   time.Duration
+	consensus bool
+}
+
+// NewOCRService talks to the ocr-service at OCR_URL. OCR_TIMEOUT (a duration, 30s by
+// default) bounds every attempt; Tesseract runs several passes, so it is generous. With
+// OCR_CONSENSUS=true the service merges the lines of its variants by voting.
+func NewOCRService() *OCRService {
+	timeout := 30 * time.Second
+	if value := os.Getenv("OCR_TIMEOUT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			timeout = parsed
+		} else {
+			log.Printf("invalid OCR_TIMEOUT %q, using %s", value, timeout)
+		}
+	}
+	service := NewOCRClient(os.Getenv("OCR_URL"), timeout, 2)
+	service.consensus, _ = strconv.ParseBool(os.Getenv("OCR_CONSENSUS"))
+	return service
+}
+
+// NewOCRClient talks to the ocr-service at url, retrying failed requests up to retries times.
+func NewOCRClient(url string, timeout time.Duration, retries int) *OCRService {
+	return &OCRService{
+		url:     strings.TrimSuffix(url, "/"),
+		client:  &http.Client{Timeout: timeout},
+		retries: retries,
+		backoff: 500 * time.Millisecond,
+	}
+}
+
+func (s *OCRService) Recognize(ctx context.Context, filename string, image []byte) (*OCRReading, error) {
+	if s.url == "" {
+		return nil, ErrOCRUnavailable
+	}
+
+	var response *OCRResponse
+	var err error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			wait := s.backoff * time.Duration(attempt)
+			var busy *ocrBusyError
+			if errors.As(err, &busy) {
+				wait = busy.retryAfter
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		var retry bool
+		response, retry, err = s.post(ctx, filename, image)
+		if err == nil || !retry {
+			break
+		}
+		log.Printf("OCR attempt %d of request %s failed: %v", attempt+1, RequestID(ctx), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// older services send no best variant and one is picked here
+	reading := &OCRReading{Best: response.Best}
+	if reading.Best == nil || reading.Best.Version == qrVersion || strings.TrimSpace(reading.Best.Text) == "" {
+		reading.Best = BestOCRResult(response.Results)
+	}
+	for _, result := range response.Results {
+		if result.Version == qrVersion && result.Fiscal != nil {
+			reading.Fiscal = result.Fiscal
+		}
+	}
+	if reading.Best == nil && reading.Fiscal == nil {
+		return nil, fmt.Errorf("ocr service returned no text")
+	}
+	return reading, nil
+}
+
+// ocrBusyError is the ocr-service turning the image away while its queue is full.
+type ocrBusyError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *ocrBusyError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ocrBusyError) Unwrap() error {
+	return ErrOCRUnavailable
+}
+
+// retryAfter reads the seconds of a Retry-After header, capped at ten so a receipt job does
+// not sleep through its lease.
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return fallback
+	}
+	return min(time.Duration(seconds)*time.Second, 10*time.Second)
+}
+
+// post sends the image once with the request ID of ctx, retry tells whether trying again may
+// help.
+func (s *OCRService) post(ctx context.Context, filename string, image []byte) (*OCRResponse, bool, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", filename)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := part.Write(image); err != nil {
+		return nil, false, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, false, err
+	}
+
+	url := s.url + "/ocr"
+	if s.consensus {
+		url += "?consensus=true"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %v", ErrOCRUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("ocr service responded %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, true, &ocrBusyError{err: err, retryAfter: retryAfter(resp.Header.Get("Retry-After"), s.backoff)}
+		}
+		if resp.StatusCode >= 500 {
+			return nil, true, fmt.Errorf("%w: %v", ErrOCRUnavailable, err)
+		}
+		return nil, false, err
+	}
+
+	var response OCRResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, false, fmt.Errorf("failed to decode ocr response: %v", err)
+	}
+	return &response, false, nil
+}
+
+var ocrPricePattern = regexp.MustCompile(`\d+[.,]\d{2}\b`)
+
+// BestOCRResult picks the variant most likely to be read correctly. Tesseract's confidence
+// decides when the service reports it, otherwise the variant with the most prices and the
+// least noise wins. The QR code is not a reading of the text and never wins. It returns nil
+// when no variant has any text.
+func BestOCRResult(results []OCRResult) *OCRResult {
+	withConfidence := false
+	for _, result := range results {
+		withConfidence = withConfidence || result.Confidence > 0
+	}
+
+	var best *OCRResult
+	bestScore := 0.0
+	for i := range results {
+		result := &results[i]
+		if result.Version == qrVersion || strings.TrimSpace(result.Text) == "" {
+			continue
+		}
+		score := result.Confidence
+		if !withConfidence {
+			score = ocrTextScore(result.Text)
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = result, score
+		}
+	}
+	return best
+}
+
+// ocrTextScore rates text without a confidence: prices are what a receipt is read for, and
+// letters and digits count against the stray symbols Tesseract makes of noise.
+func ocrTextScore(text string) float64 {
+	var readable, other int
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			readable++
+		case !unicode.IsSpace(r):
+			other++
+		}
+	}
+	if readable+other == 0 {
+		return 0
+	}
+	prices := len(ocrPricePattern.FindAllString(text, -1))
+	return float64(prices) + float64(readable)/float64(readable+other)
+}
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBestOCRResultPrefersConfidence(t *testing.T) {
+	best := BestOCRResult([]OCRResult{
+		{Version: "enhanced", Text: "МЛЕКО 65,00", Confidence: 71},
+		{Version: "dilated", Text: "МЛЕКО 65,00 ЛЕБ 30,00", Confidence: 54},
+	})
+	assert.Equal(t, "enhanced", best.Version)
+}
+
+func TestBestOCRResultWithoutConfidence(t *testing.T) {
+	best := BestOCRResult([]OCRResult{
+		{Version: "enhanced", Text: "~~ |/ МЛЕКО 65,00"},
+		{Version: "dilated", Text: "МЛЕКО 65,00\nЛЕБ 30,00"},
+		{Version: "denoised", Text: "  "},
+	})
+	assert.Equal(t, "dilated", best.Version)
+
+	assert.Nil(t, BestOCRResult([]OCRResult{{Version: "basic", Text: ""}}))
+}
+
+func TestBestOCRResultSkipsQRCode(t *testing.T) {
+	best := BestOCRResult([]OCRResult{
+		{Version: "enhanced", Text: "МЛЕКО 65,00", Confidence: 40},
+		{Version: "qr", Text: "https://e-smetka.ujp.gov.mk/?iznos=65,00", Confidence: 100},
+	})
+	assert.Equal(t, "enhanced", best.Version)
+}
+
+func TestOCRServiceRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		file, header, err := r.FormFile("image")
+		assert.NoError(t, err)
+		defer file.Close()
+		assert.Equal(t, "receipt.jpg", header.Filename)
+		assert.Equal(t, "upload-1", r.Header.Get("X-Request-ID"))
+
+		json.NewEncoder(w).Encode(OCRResponse{Results: []OCRResult{{Version: "enhanced", Text: "ВКУПНО 95,00"}}})
+	}))
+	defer server.Close()
+
+	client := NewOCRClient(server.URL, time.Second, 2)
+	client.backoff = time.Millisecond
+	reading, err := client.Recognize(WithRequestID(context.Background(), "upload-1"), "receipt.jpg", []byte("image"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ВКУПНО 95,00", reading.Best.Text)
+	assert.Nil(t, reading.Fiscal)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestOCRServicePrefersBestOfService(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.URL.Query().Get("consensus"))
+		json.NewEncoder(w).Encode(OCRResponse{
+			Results: []OCRResult{
+				{Version: "enhanced", Text: "МЛЕК0 65,00", Confidence: 71},
+				{Version: "dilated", Text: "МЛЕКО 65,00", Confidence: 64},
+			},
+			Best: &OCRResult{Version: "consensus", Text: "МЛЕКО 65,00", Confidence: 71},
+		})
+	}))
+	defer server.Close()
+
+	client := NewOCRClient(server.URL, time.Second, 0)
+	client.consensus = true
+	reading, err := client.Recognize(context.Background(), "receipt.jpg", []byte("image"))
+	assert.NoError(t, err)
+	assert.Equal(t, "consensus", reading.Best.Version)
+	assert.Equal(t, "МЛЕКО 65,00", reading.Best.Text)
+}
+
+func TestOCRServiceWaitsWhenBusy(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(OCRResponse{Results: []OCRResult{{Version: "enhanced", Text: "ВКУПНО 95,00"}}})
+	}))
+	defer server.Close()
+
+	client := NewOCRClient(server.URL, time.Second, 1)
+	client.backoff = time.Hour // Retry-After decides
+	reading, err := client.Recognize(context.Background(), "receipt.jpg", []byte("image"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ВКУПНО 95,00", reading.Best.Text)
+	assert.Equal(t, int32(2), calls.Load())
+
+	assert.Equal(t, 10*time.Second, retryAfter("120", time.Second))
+	assert.Equal(t, time.Second, retryAfter("soon", time.Second))
+}
+
+func TestOCRServiceStopsWaitingWhenCanceled(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewOCRClient(server.URL, time.Second, 2)
+	client.backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := client.Recognize(ctx, "receipt.jpg", []byte("image"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 5*time.Second)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestOCRServiceDoesNotRetryBadRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "Failed to decode image", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewOCRClient(server.URL, time.Second, 2)
+	client.backoff = time.Millisecond
+	_, err := client.Recognize(context.Background(), "receipt.jpg", []byte("image"))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrOCRUnavailable))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestOCRServiceUnavailable(t *testing.T) {
+	_, err := NewOCRClient("", time.Second, 2).Recognize(context.Background(), "receipt.jpg", nil)
+	assert.ErrorIs(t, err, ErrOCRUnavailable)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	client := NewOCRClient(server.URL, time.Second, 1)
+	client.backoff = time.Millisecond
+	_, err = client.Recognize(context.Background(), "receipt.jpg", nil)
+	assert.ErrorIs(t, err, ErrOCRUnavailable)
+}
@@ -0,0 +1,224 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OFXStatementParser reads OFX and QFX statements of bank and credit card accounts, both the
+// SGML based OFX 1.x and the XML based OFX 2.x.
+type OFXStatementParser struct{}
+
+func NewOFXStatementParser() *OFXStatementParser {
+	return &OFXStatementParser{}
+}
+
+// ofxTransaction is a STMTTRN aggregate with the fields the import needs.
+type ofxTransaction struct {
+	Type     string `xml:"TRNTYPE"`
+	Posted   string `xml:"DTPOSTED"`
+	Amount   string `xml:"TRNAMT"`
+	FitId    string `xml:"FITID"`
+	Name     string `xml:"NAME"`
+	Payee    string `xml:"PAYEE>NAME"`
+	Memo     string `xml:"MEMO"`
+	Currency string `xml:"CURRENCY>CURSYM"`
+}
+
+func (p *OFXStatementParser) Parse(r io.Reader) ([]StatementRow, error) {
+	reader := bufio.NewReader(r)
+	start, err := reader.Peek(64)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var transactions []ofxTransaction
+	var currency string
+	// OFX 1.x files start with a plain text header, OFX 2.x with an XML declaration
+	if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(start, []byte("\ufeff"))), []byte("OFXHEADER:")) {
+		transactions, currency, err = parseOFXSGML(reader)
+	} else {
+		transactions, currency, err = parseOFXXML(reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OFX statement: %v", err)
+	}
+
+	rows := make([]StatementRow, len(transactions))
+	for i, t := range transactions {
+		rows[i].Line = i + 1
+		rows[i].Err = t.toTransaction(&rows[i], currency)
+	}
+	return rows, nil
+}
+
+func parseOFXXML(r io.Reader) ([]ofxTransaction, string, error) {
+	decoder := xml.NewDecoder(r)
+	var transactions []ofxTransaction
+	var currency string
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return transactions, currency, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "CURDEF":
+			if err := decoder.DecodeElement(&currency, &start); err != nil {
+				return nil, "", err
+			}
+		case "STMTTRN":
+			var t ofxTransaction
+			if err := decoder.DecodeElement(&t, &start); err != nil {
+				return nil, "", err
+			}
+			transactions = append(transactions, t)
+		}
+	}
+}
+
+// parseOFXSGML reads OFX 1.x, where elements holding a value are usually not closed:
+// <STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250701<TRNAMT>-12.50...</STMTTRN>
+func parseOFXSGML(r io.Reader) ([]ofxTransaction, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	body := string(data)
+	if i := strings.Index(body, "<OFX>"); i >= 0 {
+		body = body[i:]
+	} else {
+		return nil, "", fmt.Errorf("missing <OFX> element")
+	}
+
+	var transactions []ofxTransaction
+	var currency string
+	var current *ofxTransaction
+	var path []string // open aggregates within the current transaction, e.g. PAYEE
+	for len(body) > 0 {
+		open := strings.IndexByte(body, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(body[open:], '>')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(body[open+1 : open+end]))
+		body = body[open+end+1:]
+
+		next := strings.IndexByte(body, '<')
+		if next < 0 {
+			next = len(body)
+		}
+		value := strings.TrimSpace(body[:next])
+
+		switch {
+		case tag == "STMTTRN":
+			current = &ofxTransaction{}
+			path = nil
+		case tag == "/STMTTRN":
+			if current != nil {
+				transactions = append(transactions, *current)
+			}
+			current = nil
+		case strings.HasPrefix(tag, "/"):
+			if len(path) > 0 && path[len(path)-1] == tag[1:] {
+				path = path[:len(path)-1]
+			}
+		case value == "":
+			if current != nil {
+				path = append(path, tag)
+			}
+		case tag == "CURDEF":
+			currency = value
+		case current != nil:
+			current.set(strings.Join(append(path, tag), ">"), value)
+		}
+	}
+	return transactions, currency, nil
+}
+
+func (t *ofxTransaction) set(field string, value string) {
+	value = unescapeSGML(value)
+	switch field {
+	case "TRNTYPE":
+		t.Type = value
+	case "DTPOSTED":
+		t.Posted = value
+	case "TRNAMT":
+		t.Amount = value
+	case "FITID":
+		t.FitId = value
+	case "NAME":
+		t.Name = value
+	case "PAYEE>NAME":
+		t.Payee = value
+	case "MEMO":
+		t.Memo = value
+	case "CURRENCY>CURSYM":
+		t.Currency = value
+	}
+}
+
+var sgmlEntities = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'")
+
+func unescapeSGML(value string) string {
+	return sgmlEntities.Replace(value)
+}
+
+// toTransaction fills the transaction of row. OFX signs TRNAMT, a positive amount is a credit.
+func (t ofxTransaction) toTransaction(row *StatementRow, currency string) error {
+	// DTPOSTED is YYYYMMDD optionally followed by the time and the time zone
+	if len(t.Posted) < 8 {
+		return fmt.Errorf("invalid date %q", t.Posted)
+	}
+	date, err := time.Parse("20060102", t.Posted[:8])
+	if err != nil {
+		return fmt.Errorf("invalid date %q", t.Posted)
+	}
+	row.Transaction.DateMade = date
+
+	amount, err := decimal.NewFromString(strings.Replace(strings.TrimSpace(t.Amount), ",", ".", 1))
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", t.Amount)
+	}
+	if t.Currency != "" {
+		currency = t.Currency
+	}
+	if err := setSignedAmount(&row.Transaction, amount, enum.Currency(strings.ToUpper(currency))); err != nil {
+		return err
+	}
+
+	row.Transaction.Title = firstNonEmpty(t.Name, t.Payee, t.Memo, t.Type)
+	if t.FitId != "" {
+		fitId := t.FitId
+		row.Transaction.ExternalId = &fitId
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
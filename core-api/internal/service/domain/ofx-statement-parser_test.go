@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ofxSGMLStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>EUR
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20250701120000.000[-5:EST]
+<TRNAMT>-12.50
+<FITID>2025070101
+<NAME>Coffee &amp; Co
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20250702
+<TRNAMT>1500.00
+<FITID>2025070201
+<MEMO>Salary July
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXMLStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CURDEF>USD</CURDEF>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>POS</TRNTYPE>
+        <DTPOSTED>20250703</DTPOSTED>
+        <TRNAMT>-30.00</TRNAMT>
+        <FITID>CC-1</FITID>
+        <PAYEE><NAME>Book Store</NAME></PAYEE>
+      </STMTTRN>
+      <STMTTRN>
+        <TRNTYPE>FEE</TRNTYPE>
+        <DTPOSTED>2025</DTPOSTED>
+        <TRNAMT>-1.00</TRNAMT>
+        <FITID>CC-2</FITID>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestOFXStatementParserSGML(t *testing.T) {
+	rows, err := NewOFXStatementParser().Parse(strings.NewReader(ofxSGMLStatement))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, enum.Expense, rows[0].Transaction.Type)
+	assert.Equal(t, "12.50 EUR", rows[0].Transaction.Price.String())
+	assert.Equal(t, "Coffee & Co", rows[0].Transaction.Title)
+	assert.Equal(t, "2025-07-01", rows[0].Transaction.DateMade.Format("2006-01-02"))
+	assert.Equal(t, "2025070101", *rows[0].Transaction.ExternalId)
+
+	assert.Equal(t, enum.Income, rows[1].Transaction.Type)
+	assert.Equal(t, "Salary July", rows[1].Transaction.Title)
+}
+
+func TestOFXStatementParserXML(t *testing.T) {
+	rows, err := NewOFXStatementParser().Parse(strings.NewReader(ofxXMLStatement))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, enum.Expense, rows[0].Transaction.Type)
+	assert.Equal(t, "30.00 USD", rows[0].Transaction.Price.String())
+	assert.Equal(t, "Book Store", rows[0].Transaction.Title)
+	assert.Equal(t, "CC-1", *rows[0].Transaction.ExternalId)
+
+	assert.Error(t, rows[1].Err)
+	assert.Equal(t, 2, rows[1].Line)
+}
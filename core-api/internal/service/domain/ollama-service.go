@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/model"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OllamaService reads receipts with a local Ollama server, no data leaves the machine.
+type OllamaService struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+}
+
+func NewOllamaService(baseURL string, model string, client *http.Client) *OllamaService {
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "llama3.2-vision"
+	}
+	return &OllamaService{baseURL: strings.TrimSuffix(baseURL, "/"), model: model, client: client}
+}
+
+func (o *OllamaService) ExtractTransaction(ctx context.Context, extractedTextOCR string, imageBase64 string) (*model.Transaction, error) {
+	payload := map[string]interface{}{
+		"model":  o.model,
+		"stream": false,
+		"format": "json",
+		"messages": []map[string]interface{}{
+			{
+				"role":    "user",
+				"content": receiptPrompt(extractedTextOCR),
+				"images":  []string{imageBase64},
+			},
+		},
+	}
+
+	var response ollamaResponse
+	if err := postJSON(ctx, o.client, o.baseURL+"/api/chat", nil, payload, &response); err != nil {
+		return nil, fmt.Errorf("Ollama: %w", err)
+	}
+
+	return parseTransactionJSON(response.Message.Content)
+}
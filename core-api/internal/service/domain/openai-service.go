@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/model"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAIService reads receipts with an OpenAI compatible chat completions API, which many
+// hosted and self-hosted servers (vLLM, LM Studio, OpenRouter, ...) offer.
+type OpenAIService struct {
+	baseURL string
+	model   string
+	apiKey  string
+	client  *http.Client
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+func NewOpenAIService(baseURL string, model string, apiKey string, client *http.Client) *OpenAIService {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return &OpenAIService{baseURL: strings.TrimSuffix(baseURL, "/"), model: model, apiKey: apiKey, client: client}
+}
+
+func (o *OpenAIService) ExtractTransaction(ctx context.Context, extractedTextOCR string, imageBase64 string) (*model.Transaction, error) {
+	payload := map[string]interface{}{
+		"model": o.model,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": receiptPrompt(extractedTextOCR)},
+					{"type": "image_url", "image_url": map[string]string{"url": "data:image/jpeg;base64," + imageBase64}},
+				},
+			},
+		},
+	}
+
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		headers["Authorization"] = "Bearer " + o.apiKey
+	}
+
+	var response openAIResponse
+	if err := postJSON(ctx, o.client, o.baseURL+"/chat/completions", headers, payload, &response); err != nil {
+		return nil, fmt.Errorf("OpenAI: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("OpenAI response did not contain a choice")
+	}
+
+	return parseTransactionJSON(response.Choices[0].Message.Content)
+}
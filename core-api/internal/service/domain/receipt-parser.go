@@ -0,0 +1,431 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// lookalikes maps the Latin letters Tesseract confuses with Cyrillic ones, ВКУПНО is often
+// read as BKYПHO. Text and keywords are both folded before they are compared.
+var lookalikes = map[rune]rune{
+	'A': 'А', 'B': 'В', 'C': 'С', 'E': 'Е', 'H': 'Н', 'K': 'К', 'M': 'М',
+	'O': 'О', 'P': 'Р', 'T': 'Т', 'X': 'Х', 'Y': 'У', 'J': 'Ј',
+}
+
+// unfoldLookalikes maps the folded letters back, for values that are Latin like "MK".
+var unfoldLookalikes = func() map[rune]rune {
+	m := make(map[rune]rune, len(lookalikes))
+	for latin, cyrillic := range lookalikes {
+		m[cyrillic] = latin
+	}
+	return m
+}()
+
+func foldLookalikes(s string) string {
+	return strings.Map(func(r rune) rune {
+		if folded, ok := lookalikes[r]; ok {
+			return folded
+		}
+		return r
+	}, strings.ToUpper(s))
+}
+
+func foldAll(keywords ...string) []string {
+	for i, keyword := range keywords {
+		keywords[i] = foldLookalikes(keyword)
+	}
+	return keywords
+}
+
+var (
+	totalKeywords    = foldAll("ВКУПНО", "ВКУПЕН ИЗНОС", "ЗА НАПЛАТА", "ЗА ПЛАЌАЊЕ", "СУМА", "TOTAL", "AMOUNT DUE", "BALANCE DUE", "TO PAY")
+	notTotalKeywords = foldAll("SUBTOTAL", "SUB TOTAL", "SUB-TOTAL", "МЕЃУЗБИР", "ДДВ", "VAT", "TAX", "ПОПУСТ", "DISCOUNT", "SAVINGS", "ЗАШТЕДА")
+	vatKeywords      = foldAll("ДДВ", "VAT", "TAX", "ПДВ", "ДАНОК")
+	dateKeywords     = foldAll("ДАТА", "DATE", "ДАТУМ")
+	taxKeywords      = foldAll("ЕДБ", "EDB", "ДАНОЧЕН БРОЈ", "ДДВ БРОЈ", "ДДВ БР", "VAT NO", "VAT REG", "VAT ID", "VAT NUMBER", "TAX ID")
+	merchantSkips    = foldAll("ФИСКАЛНА", "СМЕТКА", "ДДВ", "ЕДБ", "ДАТА", "ТЕЛ", "TEL", "PHONE", "УЛ.", "УЛИЦА", "STREET", "RECEIPT", "INVOICE", "WELCOME", "ДОБРЕДОЈДОВТЕ", "БЛАГОДАРИМЕ", "THANK")
+	companySuffix    = regexp.MustCompile(`(^|[^\p{L}])(` + strings.Join(foldAll("ДООЕЛ", "ДОО", "АД", "ТП", "DOOEL", "DOO", "LTD", "LLC", "INC", "GMBH", "PLC"), "|") + `)([^\p{L}]|$)`)
+
+	// receiptAmount matches amounts with two decimals, with or without thousands separators. The
+	// second group is set when the match goes on into a rate, a date or a longer number.
+	receiptAmount  = regexp.MustCompile(`(\d{1,3}(?:[.,]\d{3})+[.,]\d{2}|\d+[.,]\d{2})(\d|[.,/:-]\d|\s*%)?`)
+	receiptRate    = regexp.MustCompile(`(?:^|[^\d.,])(\d{1,2}(?:[.,]\d{1,3})?)\s*%`)
+	receiptDMY     = regexp.MustCompile(`\b(\d{1,2})([./-])(\d{1,2})[./-](\d{4}|\d{2})\b`)
+	receiptYMD     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	receiptTime    = regexp.MustCompile(`\b(\d{1,2}):(\d{2})(?::(\d{2}))?\b`)
+	receiptTaxNo   = regexp.MustCompile(`(МК\s?\d{13}|\d{13}|[A-ZА-Я]{2}\s?\d{8,12}|\d{8,12})`)
+	receiptMKTaxNo = regexp.MustCompile(`МК\s?\d{13}`)
+)
+
+// ParseReceiptText reads the total, date, merchant, tax number and VAT breakdown out of the OCR
+// text of a Macedonian or English receipt. Dates without a zone are in loc.
+func ParseReceiptText(text string, loc *time.Location) model.ParsedReceipt {
+	var lines, folded []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+			folded = append(folded, foldLookalikes(line))
+		}
+	}
+
+	receipt := model.ParsedReceipt{VAT: []model.ReceiptVAT{}, Currency: receiptCurrency(strings.Join(folded, "\n"))}
+	receipt.VAT = parseReceiptVAT(folded)
+	receipt.Total, receipt.Confidence.Total = parseReceiptTotal(folded, receipt.VAT)
+	receipt.Date, receipt.Confidence.Date = parseReceiptDate(folded, loc)
+	receipt.Merchant, receipt.Confidence.Merchant = parseReceiptMerchant(lines, folded)
+	receipt.TaxNumber, receipt.Confidence.TaxNumber = parseReceiptTaxNumber(folded)
+	return receipt
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func receiptCurrency(folded string) enum.Currency {
+	switch {
+	case containsAny(folded, foldAll("ДЕН", "MKD")):
+		return enum.MKD
+	case containsAny(folded, foldAll("€", "EUR")):
+		return enum.EUR
+	case containsAny(folded, foldAll("$", "USD")):
+		return enum.USD
+	default:
+		return ""
+	}
+}
+
+// receiptAmountAt is an amount of a line and where it ends.
+type receiptAmountAt struct {
+	value decimal.Decimal
+	end   int
+}
+
+// receiptAmounts returns the amounts of the line, rates and dates left out.
+func receiptAmounts(line string) []receiptAmountAt {
+	var amounts []receiptAmountAt
+	for _, match := range receiptAmount.FindAllStringSubmatchIndex(line, -1) {
+		if match[4] != -1 { // not an amount
+			continue
+		}
+		raw := line[match[2]:match[3]]
+		amount, err := parseAmount(raw, raw[len(raw)-3:len(raw)-2])
+		if err != nil {
+			continue
+		}
+		amounts = append(amounts, receiptAmountAt{value: amount, end: match[3]})
+	}
+	return amounts
+}
+
+// parseReceiptTotal takes the amount of the strongest total line. A total that the VAT lines
+// add up to is all but certain; without any total line the largest amount is a guess.
+func parseReceiptTotal(folded []string, vat []model.ReceiptVAT) (*decimal.Decimal, float64) {
+	var total *decimal.Decimal
+	confidence := 0.0
+	for i, line := range folded {
+		if !containsAny(line, totalKeywords) || containsAny(line, notTotalKeywords) {
+			continue
+		}
+		candidate, candidateConfidence := (*decimal.Decimal)(nil), 0.0
+		if amounts := receiptAmounts(line); len(amounts) > 0 {
+			candidate, candidateConfidence = &amounts[len(amounts)-1].value, 0.9
+		} else if i+1 < len(folded) {
+			if amounts := receiptAmounts(folded[i+1]); len(amounts) > 0 {
+				candidate, candidateConfidence = &amounts[0].value, 0.75
+			}
+		}
+		if candidate == nil {
+			continue
+		}
+		if candidateConfidence > confidence || (candidateConfidence == confidence && candidate.GreaterThan(*total)) {
+			total, confidence = candidate, candidateConfidence
+		}
+	}
+
+	if total == nil {
+		for _, line := range folded {
+			for _, amount := range receiptAmounts(line) {
+				if total == nil || amount.value.GreaterThan(*total) {
+					value := amount.value
+					total, confidence = &value, 0.3
+				}
+			}
+		}
+		return total, confidence
+	}
+
+	gross := decimal.Zero
+	complete := len(vat) > 0
+	for _, line := range vat {
+		if line.Base == nil {
+			complete = false
+			break
+		}
+		gross = gross.Add(*line.Base).Add(line.Amount)
+	}
+	if complete && gross.Sub(*total).Abs().LessThanOrEqual(decimal.NewFromFloat(0.05)) {
+		confidence = 0.98
+	}
+	return total, confidence
+}
+
+// parseReceiptVAT reads the lines with a VAT rate. With two amounts the one that is the rate
+// of the other is the VAT, the other the net or the gross amount.
+func parseReceiptVAT(folded []string) []model.ReceiptVAT {
+	vat := []model.ReceiptVAT{}
+	hundred := decimal.NewFromInt(100)
+	tolerance := decimal.NewFromFloat(0.05)
+	matches := func(base decimal.Decimal, rate decimal.Decimal, amount decimal.Decimal) bool {
+		return base.Mul(rate).Div(hundred).Sub(amount).Abs().LessThanOrEqual(tolerance)
+	}
+
+	for _, line := range folded {
+		if !containsAny(line, vatKeywords) {
+			continue
+		}
+		rateMatch := receiptRate.FindStringSubmatchIndex(line)
+		if rateMatch == nil {
+			continue
+		}
+		rate, err := parseAmount(line[rateMatch[2]:rateMatch[3]], ",")
+		if err != nil || rate.GreaterThanOrEqual(hundred) {
+			continue
+		}
+
+		var amounts []decimal.Decimal
+		for _, amount := range receiptAmounts(line) {
+			if amount.end > rateMatch[1] {
+				amounts = append(amounts, amount.value)
+			}
+		}
+
+		switch {
+		case len(amounts) == 0:
+			continue
+		case len(amounts) == 1:
+			vat = append(vat, model.ReceiptVAT{Rate: rate, Amount: amounts[0], Confidence: 0.5})
+		default:
+			a, b := amounts[0], amounts[len(amounts)-1]
+			line := model.ReceiptVAT{Rate: rate, Base: &a, Amount: b, Confidence: 0.4}
+			switch {
+			case matches(a, rate, b):
+				line.Confidence = 0.95
+			case matches(b, rate, a):
+				line.Base, line.Amount, line.Confidence = &b, a, 0.95
+			case matches(a, rate.Div(hundred.Add(rate)).Mul(hundred), b): // gross and VAT
+				base := a.Sub(b)
+				line.Base, line.Confidence = &base, 0.85
+			}
+			vat = append(vat, line)
+		}
+	}
+	return vat
+}
+
+// parseReceiptDate takes the most certain date of the receipt, day first like in Europe. A date
+// on a DATE line or with a time next to it is more likely the date of the purchase.
+func parseReceiptDate(folded []string, loc *time.Location) (*time.Time, float64) {
+	var best *time.Time
+	confidence := 0.0
+	for i, line := range folded {
+		year, month, day, candidateConfidence, ok := findReceiptDate(line)
+		if !ok {
+			continue
+		}
+		if containsAny(line, dateKeywords) {
+			candidateConfidence += 0.2
+		}
+
+		hour, minute, second := 0, 0, 0
+		timeMatch := receiptTime.FindStringSubmatch(line)
+		if timeMatch == nil && i+1 < len(folded) {
+			timeMatch = receiptTime.FindStringSubmatch(folded[i+1])
+		}
+		if timeMatch != nil {
+			hour, _ = strconv.Atoi(timeMatch[1])
+			minute, _ = strconv.Atoi(timeMatch[2])
+			if timeMatch[3] != "" {
+				second, _ = strconv.Atoi(timeMatch[3])
+			}
+			if hour < 24 && minute < 60 && second < 60 {
+				candidateConfidence += 0.15
+			} else {
+				hour, minute, second = 0, 0, 0
+			}
+		}
+
+		if candidateConfidence > confidence {
+			date := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+			best, confidence = &date, min(candidateConfidence, 0.95)
+		}
+	}
+	return best, confidence
+}
+
+// findReceiptDate returns the first valid date of the line and how certain it is.
+func findReceiptDate(line string) (int, int, int, float64, bool) {
+	if match := receiptYMD.FindStringSubmatch(line); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		day, _ := strconv.Atoi(match[3])
+		if validReceiptDate(year, month, day) {
+			return year, month, day, 0.65, true
+		}
+	}
+
+	for _, match := range receiptDMY.FindAllStringSubmatch(line, -1) {
+		day, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[3])
+		year, _ := strconv.Atoi(match[4])
+		confidence := 0.6
+		if len(match[4]) == 2 {
+			year += 2000
+			confidence -= 0.1
+		}
+		if match[2] == "/" && day <= 12 && month <= 12 && day != month {
+			confidence -= 0.1 // could be the month first
+		}
+		if validReceiptDate(year, month, day) {
+			return year, month, day, confidence, true
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+func validReceiptDate(year int, month int, day int) bool {
+	if year < 2000 || year > time.Now().Year()+1 || month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	return day <= time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// parseReceiptMerchant takes the name from the top of the receipt, where the shop prints it
+// above its address. A line with a company form like ДООЕЛ or LTD is the name for sure. A
+// Cyrillic name is given without the Latin letters OCR mixed into it.
+func parseReceiptMerchant(lines []string, folded []string) (string, float64) {
+	first := ""
+	for i := 0; i < len(lines) && i < 8; i++ {
+		if containsAny(folded[i], merchantSkips) || !looksLikeName(lines[i]) {
+			continue
+		}
+		name := lines[i]
+		if strings.IndexFunc(name, func(r rune) bool { return unicode.Is(unicode.Cyrillic, r) }) >= 0 {
+			name = folded[i]
+		}
+		name = strings.Trim(name, " .,:;-*=")
+		if companySuffix.MatchString(folded[i]) {
+			return name, 0.85
+		}
+		if first == "" {
+			first = name
+		}
+	}
+	if first == "" {
+		return "", 0
+	}
+	return first, 0.5
+}
+
+// looksLikeName reports whether the line is mostly letters, unlike prices, codes and rulers.
+func looksLikeName(line string) bool {
+	letters, digits := 0, 0
+	for _, r := range line {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsDigit(r):
+			digits++
+		}
+	}
+	return letters >= 3 && digits*3 <= letters
+}
+
+// parseReceiptTaxNumber finds the ЕДБ or VAT number, the 13 digits of a Macedonian ЕДБ after
+// its label are the most certain.
+func parseReceiptTaxNumber(folded []string) (string, float64) {
+	for _, line := range folded {
+		for _, keyword := range taxKeywords {
+			index := strings.Index(line, keyword)
+			if index < 0 {
+				continue
+			}
+			number := receiptTaxNo.FindString(line[index+len(keyword):])
+			if number == "" {
+				continue
+			}
+			number = canonicalTaxNumber(number)
+			if len(strings.TrimLeft(number, "MK")) == 13 {
+				return number, 0.95
+			}
+			return number, 0.8
+		}
+	}
+	for _, line := range folded {
+		if number := receiptMKTaxNo.FindString(line); number != "" {
+			return canonicalTaxNumber(number), 0.7
+		}
+	}
+	return "", 0
+}
+
+// canonicalTaxNumber drops the spaces and writes the country prefix in Latin letters.
+func canonicalTaxNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		if latin, ok := unfoldLookalikes[r]; ok {
+			return latin
+		}
+		return r
+	}, number)
+}
+
+// receiptFieldThreshold is the confidence below which RuleBasedReceiptProvider leaves a field
+// for the user to fill in instead of guessing.
+const receiptFieldThreshold = 0.5
+
+// RuleBasedReceiptProvider reads receipts with ParseReceiptText, without a language model.
+// It needs the text of the ocr-service and does not read items. Everything the parser found
+// is kept on the transaction, fields it was not sure of only there.
+type RuleBasedReceiptProvider struct {
+	location *time.Location
+}
+
+func NewRuleBasedReceiptProvider(location *time.Location) *RuleBasedReceiptProvider {
+	return &RuleBasedReceiptProvider{location: location}
+}
+
+func (p *RuleBasedReceiptProvider) ExtractTransaction(_ context.Context, extractedTextOCR string, imageBase64 string) (*model.Transaction, error) {
+	if strings.TrimSpace(extractedTextOCR) == "" {
+		return nil, fmt.Errorf("the rule-based receipt parser needs the text of the ocr-service")
+	}
+	parsed := ParseReceiptText(extractedTextOCR, p.location)
+
+	tx := &model.Transaction{Title: "Receipt", Type: enum.Expense, Parsed: &parsed}
+	if parsed.Merchant != "" && parsed.Confidence.Merchant >= receiptFieldThreshold {
+		tx.Title = parsed.Merchant
+	}
+	if parsed.Total != nil && parsed.Confidence.Total >= receiptFieldThreshold {
+		tx.Price = money.New(*parsed.Total, parsed.Currency)
+	}
+	if parsed.Date != nil && parsed.Confidence.Date >= receiptFieldThreshold {
+		tx.DateMade = *parsed.Date
+	}
+	return tx, nil
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the receipt parser")
+
+// TestParseReceiptTextGolden parses every OCR sample in testdata/receipts and compares the result
+// with the .golden.json next to it. Run with -update after a deliberate change and review the diff.
+func TestParseReceiptTextGolden(t *testing.T) {
+	samples, err := filepath.Glob(filepath.Join("testdata", "receipts", "*.txt"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, samples)
+
+	for _, sample := range samples {
+		name := strings.TrimSuffix(filepath.Base(sample), ".txt")
+		t.Run(name, func(t *testing.T) {
+			text, err := os.ReadFile(sample)
+			assert.NoError(t, err)
+
+			parsed, err := json.MarshalIndent(ParseReceiptText(string(text), time.UTC), "", "  ")
+			assert.NoError(t, err)
+
+			golden := strings.TrimSuffix(sample, ".txt") + ".golden.json"
+			if *updateGolden {
+				assert.NoError(t, os.WriteFile(golden, append(parsed, '\n'), 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(want), string(parsed))
+		})
+	}
+}
+
+func TestFoldLookalikes(t *testing.T) {
+	assert.Equal(t, foldLookalikes("ВКУПНО"), foldLookalikes("BKYПHO"))
+	assert.Equal(t, foldLookalikes("total"), foldLookalikes("TOTAL"))
+}
+
+func TestReceiptAmountsSkipRatesAndDates(t *testing.T) {
+	var values []string
+	for _, amount := range receiptAmounts("12.03.2024 Tax 8.875% 18,00% 1.234,50 $0.89") {
+		values = append(values, amount.value.String())
+	}
+	assert.Equal(t, []string{"1234.5", "0.89"}, values)
+}
+
+func TestRuleBasedReceiptProvider(t *testing.T) {
+	provider := NewRuleBasedReceiptProvider(time.UTC)
+
+	tx, err := provider.ExtractTransaction(context.Background(), "ВЕРО ДООЕЛ\nВКУПНО 708,50 ден\n12.03.2024 14:22", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "ВЕРО ДООЕЛ", tx.Title)
+	assert.Equal(t, "708.5", tx.Price.Amount.String())
+	assert.Equal(t, "MKD", string(tx.Price.Currency))
+	assert.Equal(t, time.Date(2024, 3, 12, 14, 22, 0, 0, time.UTC), tx.DateMade)
+
+	assert.Equal(t, "708.5", tx.Parsed.Total.String())
+	assert.Greater(t, tx.Parsed.Confidence.Total, receiptFieldThreshold)
+
+	// a guessed total is left for the user, what was guessed is kept with its confidence
+	tx, err = provider.ExtractTransaction(context.Background(), "КАФЕ\nКафе 60,00\nСок 120,00", "")
+	assert.NoError(t, err)
+	assert.True(t, tx.Price.IsZero())
+	if assert.NotNil(t, tx.Parsed) && assert.NotNil(t, tx.Parsed.Total) {
+		assert.Equal(t, "120", tx.Parsed.Total.String())
+		assert.Less(t, tx.Parsed.Confidence.Total, receiptFieldThreshold)
+	}
+
+	_, err = provider.ExtractTransaction(context.Background(), "  ", "aW1hZ2U=")
+	assert.Error(t, err)
+}
+
+func TestNewReceiptProviderFallsBackToRules(t *testing.T) {
+	provider, err := NewReceiptProvider(ReceiptProviderConfig{})
+	assert.NoError(t, err)
+	assert.IsType(t, &RuleBasedReceiptProvider{}, provider)
+}
@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"SmartSpend/internal/database"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/repository"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	database_          database.Service               = database.New()
+	categoryRepository repository.ICategoryRepository = repository.NewCategoryRepository(database_)
+	categoryService    ICategoryService               = NewCategoryService(categoryRepository)
+	prompt                                            = fmt.Sprintf("Based on the image, which is a receipt, try to create me a Transaction json.\nRules:\n\nList every line of the receipt in \"items\", the price is the sum of their totals.\nHow the response should look like:\n{\n\"id\": 0, // leave 0, its autoincremented\n\"title\": \"\", //Based on the items which you have extracted suggest me a title for the transaction made in English\n\"price\": 0, //Calculate the total price from the receipt, as a number\n\"date_made\": null, //The date and time printed on the receipt like 2006-01-02T15:04:05Z, null when there is none\n\"owner_id\": \"\",\n\"category_id\": \"From the list of categories: %v choose one where you think the current transaction falls best into, but add the id\"\n\"type: \"Expense\",\n\"items\": [{\"name\": \"\", \"quantity\": 1, \"unit_price\": 0.00, \"total\": 0.00, \"vat_rate\": null}] // name of the product, total is quantity times unit price, vat_rate in percent if the receipt shows it\n}\nDo not let your model fail to prioritize a semantically correct and common product name over a literal, but flawed, character transcription\n", categoryService.FindAll("", false))
+	promptOCR                                         = fmt.Sprintf("Based on the data extracted below from an OCR service in Tesseract in Macedonian try to extract the item names, if multiple items are tried to be written but in different matter (letters are shuffled) try to predict / find the real item in Macedonian Markets.\nRules:\n1. Output in JSON only.\n2. Create me a Transaction model which JSON looks like this\n3. List every line of the receipt in \"items\", the price is the sum of their totals.\nHow the response should look like:\n{\n\"id\": 0, // leave 0, its autoincremented\n\"title\": \"\", //Based on the items which you have extracted suggest me a title for the transaction made in English\n\"price\": 0, //Calculate the total price from the receipt, as a number\n\"date_made\": null, //The date and time printed on the receipt like 2006-01-02T15:04:05Z, null when there is none\n\"owner_id\": \"\",\n\"category_id\": \"From the list of categories: %v choose one where you think the current transaction falls best into, but add the id\"\n\"type: \"Expense\",\n\"items\": [{\"name\": \"\", \"quantity\": 1, \"unit_price\": 0.00, \"total\": 0.00, \"vat_rate\": null}] // name of the product, total is quantity times unit price, vat_rate in percent if the receipt shows it\n}\n4. Only include items that make sense in a Macedonian market.\n5. Dont just trust the text blindly, if there are multiple of the 'same' items display them in the result.\n6. If there are '{number}x' before of what you think is an Item, multiply the price and update the quantity accordingly.\nDo not let your model fail to prioritize a semantically correct and common product name over a literal, but flawed, character transcription\n", categoryService.FindAll("", false))
+)
+
+// IReceiptProvider is a language model that turns a receipt into a transaction.
+type IReceiptProvider interface {
+	// ExtractTransaction reads the receipt image (base64 encoded JPEG), with the text the
+	// ocr-service read from it when there is any. The call is given up once ctx is done.
+	ExtractTransaction(ctx context.Context, extractedTextOCR string, imageBase64 string) (*model.Transaction, error)
+}
+
+// ReceiptProviderConfig chooses and configures the receipt provider, empty fields take the
+// defaults of the provider.
+type ReceiptProviderConfig struct {
+	Provider string // gemini (default), openai, ollama or rules
+	BaseURL  string
+	Model    string
+	APIKey   string
+	Timeout  time.Duration
+}
+
+// ReceiptProviderConfigFromEnv reads RECEIPT_PROVIDER, LLM_BASE_URL, LLM_MODEL, LLM_API_KEY
+// and LLM_TIMEOUT. GEMINI_API_KEY and OPENAI_API_KEY are still honoured.
+func ReceiptProviderConfigFromEnv() ReceiptProviderConfig {
+	config := ReceiptProviderConfig{
+		Provider: strings.ToLower(os.Getenv("RECEIPT_PROVIDER")),
+		BaseURL:  os.Getenv("LLM_BASE_URL"),
+		Model:    os.Getenv("LLM_MODEL"),
+		APIKey:   os.Getenv("LLM_API_KEY"),
+		Timeout:  60 * time.Second,
+	}
+	if value := os.Getenv("LLM_TIMEOUT"); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil {
+			config.Timeout = timeout
+		}
+	}
+	if config.APIKey == "" {
+		switch config.Provider {
+		case "", "gemini":
+			config.APIKey = os.Getenv("GEMINI_API_KEY")
+		case "openai":
+			config.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+	}
+	return config
+}
+
+// NewReceiptProvider creates the configured provider. Without a Gemini key no language model is
+// configured and receipts are read by the rule-based parser.
+func NewReceiptProvider(config ReceiptProviderConfig) (IReceiptProvider, error) {
+	client := &http.Client{Timeout: config.Timeout}
+	switch config.Provider {
+	case "", "gemini":
+		if config.APIKey == "" {
+			log.Println("No Gemini API key, reading receipts with the rule-based parser")
+			return NewRuleBasedReceiptProvider(time.Local), nil
+		}
+		return NewGeminiService(config.BaseURL, config.Model, config.APIKey, client), nil
+	case "openai":
+		return NewOpenAIService(config.BaseURL, config.Model, config.APIKey, client), nil
+	case "ollama":
+		return NewOllamaService(config.BaseURL, config.Model, client), nil
+	case "rules":
+		return NewRuleBasedReceiptProvider(time.Local), nil
+	default:
+		return nil, fmt.Errorf("unknown receipt provider %q, use gemini, openai, ollama or rules", config.Provider)
+	}
+}
+
+// receiptPrompt is the instruction for the model, the OCR text is appended when there is any.
+// With text from the ocr-service the image only backs it up, without it the image is all there is.
+func receiptPrompt(extractedTextOCR string) string {
+	if extractedTextOCR == "" {
+		return prompt
+	}
+	return promptOCR + "\n" + extractedTextOCR
+}
+
+// postJSON sends payload to url and decodes the answer into response.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload any, response any) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("provider responded %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("failed to unmarshal provider response: %w", err)
+	}
+	return nil
+}
+
+// parseTransactionJSON reads the transaction out of the model's answer, which is often
+// wrapped in a markdown code block. A blank price or date is one the model did not find, it is
+// left for the user instead of failing the whole answer.
+func parseTransactionJSON(text string) (*model.Transaction, error) {
+	jsonText := strings.TrimSpace(text)
+	jsonText = strings.TrimPrefix(jsonText, "```json")
+	jsonText = strings.TrimPrefix(jsonText, "```")
+	jsonText = strings.TrimSuffix(jsonText, "```")
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(strings.TrimSpace(jsonText)), &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal extracted JSON text to transaction model: %w", err)
+	}
+	for _, key := range []string{"price", "date_made"} {
+		if value, ok := fields[key]; ok && strings.TrimSpace(string(value)) == `""` {
+			delete(fields, key)
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx model.Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal extracted JSON text to transaction model: %w", err)
+	}
+	return &tx, nil
+}
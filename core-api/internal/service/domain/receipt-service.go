@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"time"
+)
+
+// ErrInvalidImage is returned for uploads that are not a JPEG or PNG image.
+var ErrInvalidImage = errors.New("failed to decode image")
+
+// CheckReceiptImage tells quickly, without decoding the pixels, whether the upload is an image
+// Read can take.
+func CheckReceiptImage(imgBytes []byte) error {
+	if _, _, err := image.DecodeConfig(bytes.NewReader(imgBytes)); err != nil {
+		return ErrInvalidImage
+	}
+	return nil
+}
+
+type IReceiptService interface {
+	// Read turns a photo of a receipt into a transaction, ctx carries the request ID of the upload.
+	Read(ctx context.Context, filename string, image []byte) (*model.Transaction, error)
+}
+
+// ReceiptService reads receipts Tesseract first: the text of the ocr-service goes to the
+// provider together with the image, when the ocr-service is down the provider reads the
+// image on its own. The total and date of a fiscal QR code beat whatever was read from the
+// text.
+type ReceiptService struct {
+	ocrService IOCRService
+	provider   IReceiptProvider
+}
+
+func NewReceiptService(ocrService IOCRService, provider IReceiptProvider) *ReceiptService {
+	return &ReceiptService{ocrService: ocrService, provider: provider}
+}
+
+func (s *ReceiptService) Read(ctx context.Context, filename string, imgBytes []byte) (*model.Transaction, error) {
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	// the providers get a JPEG whatever was uploaded
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, err
+	}
+	imageBase64 := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	extractedText := ""
+	var fiscal *FiscalQR
+	if reading, err := s.ocrService.Recognize(ctx, filename, imgBytes); err != nil {
+		log.Printf("OCR failed, reading the receipt from the image only: %v", err)
+	} else {
+		if reading.Best != nil {
+			log.Printf("Using OCR variant %s", reading.Best.Version)
+			extractedText = reading.Best.Text
+		}
+		fiscal = reading.Fiscal
+	}
+
+	tx, err := s.provider.ExtractTransaction(ctx, extractedText, imageBase64)
+	if err != nil {
+		if fiscal == nil || fiscal.Total == "" {
+			return nil, err
+		}
+		log.Printf("Reading the receipt failed, using its QR code only: %v", err)
+		tx = &model.Transaction{Title: "Receipt", Type: enum.Expense}
+	}
+	applyFiscalQR(tx, fiscal)
+	normalizeReceiptItems(tx)
+	return tx, nil
+}
+
+// applyFiscalQR takes the total and date from the QR code of a fiscal receipt. The code is
+// printed by the fiscal device, so it is right where OCR and the model may not be. The amounts
+// of Macedonian fiscal receipts are in denars.
+func applyFiscalQR(tx *model.Transaction, fiscal *FiscalQR) {
+	if fiscal == nil {
+		return
+	}
+	if total, err := money.FromString(fiscal.Total, enum.MKD); err == nil && !total.IsZero() && !total.IsNegative() {
+		tx.Price = total
+		if tx.Parsed != nil {
+			tx.Parsed.Total, tx.Parsed.Currency, tx.Parsed.Confidence.Total = &total.Amount, enum.MKD, 1
+		}
+	}
+	if date, err := time.ParseInLocation("2006-01-02T15:04:05", fiscal.Date, time.Local); err == nil {
+		tx.DateMade = date
+		if tx.Parsed != nil {
+			tx.Parsed.Date, tx.Parsed.Confidence.Date = &date, 1
+		}
+	}
+}
+
+// normalizeReceiptItems completes the items the model read and drops the ones it could not
+// make sense of. Without a total the items are all there is to go by.
+func normalizeReceiptItems(tx *model.Transaction) {
+	items := make([]model.TransactionItem, 0, len(tx.Items))
+	for _, item := range tx.Items {
+		normalized, err := NormalizeTransactionItem(item, tx.Price.Currency)
+		if err != nil {
+			log.Printf("Dropping receipt item: %v", err)
+			continue
+		}
+		items = append(items, normalized)
+	}
+	tx.Items = items
+
+	if tx.Price.IsZero() && len(items) > 0 {
+		tx.Price, _ = ItemsDifference(tx.Price, items)
+	}
+}
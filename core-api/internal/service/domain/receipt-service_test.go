@@ -0,0 +1,228 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fakeReceiptAnswer = "```json\n{\"title\": \"Groceries\", \"price\": \"95.00\", \"type\": \"Expense\"}\n```"
+
+// fakeProviderRequest is what a fake provider received: the prompt, the image and the key.
+type fakeProviderRequest struct {
+	Path   string
+	Prompt string
+	Image  string
+	Auth   string
+}
+
+// newFakeProvider serves the API of the given provider and answers every request with
+// answer, the requests it got are sent to the returned channel.
+func newFakeProvider(t *testing.T, provider string, answer string) (*httptest.Server, chan fakeProviderRequest) {
+	requests := make(chan fakeProviderRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		received := fakeProviderRequest{Path: r.URL.Path}
+
+		var response any
+		switch provider {
+		case "gemini":
+			received.Auth = r.Header.Get("x-goog-api-key")
+			parts := body["contents"].([]any)[0].(map[string]any)["parts"].([]any)
+			received.Prompt = parts[0].(map[string]any)["text"].(string)
+			received.Image = parts[1].(map[string]any)["inline_data"].(map[string]any)["data"].(string)
+			response = GeminiResponse{Candidates: []Candidate{{Content: Content{Parts: []Part{{Text: answer}}}}}}
+		case "openai":
+			received.Auth = r.Header.Get("Authorization")
+			content := body["messages"].([]any)[0].(map[string]any)["content"].([]any)
+			received.Prompt = content[0].(map[string]any)["text"].(string)
+			received.Image = content[1].(map[string]any)["image_url"].(map[string]any)["url"].(string)
+			response = map[string]any{"choices": []any{map[string]any{"message": map[string]any{"content": answer}}}}
+		case "ollama":
+			message := body["messages"].([]any)[0].(map[string]any)
+			received.Prompt = message["content"].(string)
+			received.Image = message["images"].([]any)[0].(string)
+			response = map[string]any{"message": map[string]any{"content": answer}}
+		}
+
+		requests <- received
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestReceiptProviders(t *testing.T) {
+	for _, tc := range []struct {
+		provider string
+		path     string
+		auth     string
+	}{
+		{"gemini", "/models/gemini-test:generateContent", "secret"},
+		{"openai", "/chat/completions", "Bearer secret"},
+		{"ollama", "/api/chat", ""},
+	} {
+		t.Run(tc.provider, func(t *testing.T) {
+			server, requests := newFakeProvider(t, tc.provider, fakeReceiptAnswer)
+			provider, err := NewReceiptProvider(ReceiptProviderConfig{
+				Provider: tc.provider,
+				BaseURL:  server.URL,
+				Model:    "gemini-test",
+				APIKey:   "secret",
+				Timeout:  time.Second,
+			})
+			assert.NoError(t, err)
+
+			tx, err := provider.ExtractTransaction(context.Background(), "ВКУПНО 95,00", "aW1hZ2U=")
+			assert.NoError(t, err)
+			assert.Equal(t, "Groceries", tx.Title)
+			assert.Equal(t, "95", tx.Price.Amount.String())
+
+			received := <-requests
+			assert.Equal(t, tc.path, received.Path)
+			assert.Equal(t, tc.auth, received.Auth)
+			assert.True(t, strings.HasSuffix(received.Prompt, "\nВКУПНО 95,00"))
+			assert.True(t, strings.HasSuffix(received.Image, "aW1hZ2U="))
+		})
+	}
+}
+
+func TestParseTransactionJSONBlankFields(t *testing.T) {
+	tx, err := parseTransactionJSON("```json\n{\"title\": \"Groceries\", \"price\": \"\", \"date_made\": \"\", \"type\": \"Expense\"}\n```")
+	assert.NoError(t, err)
+	assert.Equal(t, "Groceries", tx.Title)
+	assert.True(t, tx.Price.IsZero())
+	assert.True(t, tx.DateMade.IsZero())
+
+	tx, err = parseTransactionJSON(`{"title": "Groceries", "price": null, "date_made": null}`)
+	assert.NoError(t, err)
+	assert.True(t, tx.Price.IsZero())
+	assert.True(t, tx.DateMade.IsZero())
+
+	_, err = parseTransactionJSON(`{"title": "Groceries", "price": "a lot"}`)
+	assert.Error(t, err)
+}
+
+func TestReceiptProviderCanceled(t *testing.T) {
+	server, _ := newFakeProvider(t, "ollama", fakeReceiptAnswer)
+	provider, err := NewReceiptProvider(ReceiptProviderConfig{Provider: "ollama", BaseURL: server.URL, Timeout: time.Minute})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = provider.ExtractTransaction(ctx, "ВКУПНО 95,00", "aW1hZ2U=")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewReceiptProviderUnknown(t *testing.T) {
+	_, err := NewReceiptProvider(ReceiptProviderConfig{Provider: "claude"})
+	assert.Error(t, err)
+}
+
+func receiptImage(t *testing.T) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))))
+	return buf.Bytes()
+}
+
+func TestReceiptServiceUsesOCRText(t *testing.T) {
+	ocr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(OCRResponse{Results: []OCRResult{
+			{Version: "enhanced", Text: "ЛЕБ 30,00\nВКУПНО 95,00"},
+			{Version: "dilated", Text: "~|~"},
+		}})
+	}))
+	defer ocr.Close()
+	llm, requests := newFakeProvider(t, "openai", fakeReceiptAnswer)
+
+	service := NewReceiptService(NewOCRClient(ocr.URL, time.Second, 0), NewOpenAIService(llm.URL, "", "", http.DefaultClient))
+	tx, err := service.Read(context.Background(), "receipt.png", receiptImage(t))
+	assert.NoError(t, err)
+	assert.Equal(t, "Groceries", tx.Title)
+
+	received := <-requests
+	assert.Equal(t, promptOCR+"\nЛЕБ 30,00\nВКУПНО 95,00", received.Prompt)
+	assert.True(t, strings.HasPrefix(received.Image, "data:image/jpeg;base64,"))
+}
+
+func TestReceiptServicePrefersFiscalQR(t *testing.T) {
+	ocr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(OCRResponse{Results: []OCRResult{
+			{Version: "enhanced", Text: "ЛЕБ 30,00\nВКУПНО 96,00"},
+			{Version: "qr", Text: "EDB=4030996116744;IZNOS=95,00", Fiscal: &FiscalQR{
+				Payload: "EDB=4030996116744;IZNOS=95,00", Total: "95.00", Date: "2025-03-14T18:22:05", TaxNumber: "4030996116744",
+			}},
+		}})
+	}))
+	defer ocr.Close()
+	llm, requests := newFakeProvider(t, "openai", `{"title": "Groceries", "price": "96.00", "currency": "EUR", "type": "Expense"}`)
+
+	service := NewReceiptService(NewOCRClient(ocr.URL, time.Second, 0), NewOpenAIService(llm.URL, "", "", http.DefaultClient))
+	tx, err := service.Read(context.Background(), "receipt.png", receiptImage(t))
+	assert.NoError(t, err)
+	assert.Equal(t, "Groceries", tx.Title)
+	assert.Equal(t, "95.00 MKD", tx.Price.String())
+	assert.Equal(t, time.Date(2025, 3, 14, 18, 22, 5, 0, time.Local), tx.DateMade)
+	assert.Equal(t, promptOCR+"\nЛЕБ 30,00\nВКУПНО 96,00", (<-requests).Prompt)
+}
+
+func TestReceiptServiceReadsFiscalQRWhenProviderFails(t *testing.T) {
+	ocr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(OCRResponse{Results: []OCRResult{
+			{Version: "qr", Text: "95,00", Fiscal: &FiscalQR{Payload: "95,00", Total: "95.00"}},
+		}})
+	}))
+	defer ocr.Close()
+
+	service := NewReceiptService(NewOCRClient(ocr.URL, time.Second, 0), NewRuleBasedReceiptProvider(time.UTC))
+	tx, err := service.Read(context.Background(), "receipt.png", receiptImage(t))
+	assert.NoError(t, err)
+	assert.Equal(t, "Receipt", tx.Title)
+	assert.Equal(t, "95.00 MKD", tx.Price.String())
+}
+
+func TestReceiptServiceNormalizesItems(t *testing.T) {
+	answer := `{"title": "Groceries", "price": 0, "type": "Expense", "items": [
+		{"name": "Milk", "quantity": 2, "unit_price": 65.00},
+		{"name": "Bread", "total": 30},
+		{"name": "", "total": 12}
+	]}`
+	llm, _ := newFakeProvider(t, "ollama", answer)
+
+	service := NewReceiptService(NewOCRClient("", time.Second, 0), NewOllamaService(llm.URL, "", http.DefaultClient))
+	tx, err := service.Read(context.Background(), "receipt.png", receiptImage(t))
+	assert.NoError(t, err)
+	assert.Len(t, tx.Items, 2)
+	assert.Equal(t, "130.00", tx.Items[0].Total.Amount.StringFixed(2))
+	assert.Equal(t, "160", tx.Price.Amount.String())
+}
+
+func TestReceiptServiceFallsBackToImageOnly(t *testing.T) {
+	llm, requests := newFakeProvider(t, "gemini", fakeReceiptAnswer)
+
+	service := NewReceiptService(NewOCRClient("", time.Second, 0), NewGeminiService(llm.URL, "", "", http.DefaultClient))
+	_, err := service.Read(context.Background(), "receipt.png", receiptImage(t))
+	assert.NoError(t, err)
+	assert.Equal(t, prompt, (<-requests).Prompt)
+}
+
+func TestReceiptServiceInvalidImage(t *testing.T) {
+	service := NewReceiptService(NewOCRClient("", time.Second, 0), NewOllamaService("", "", http.DefaultClient))
+	_, err := service.Read(context.Background(), "receipt.txt", []byte("not an image"))
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestCheckReceiptImage(t *testing.T) {
+	assert.NoError(t, CheckReceiptImage(receiptImage(t)))
+	assert.ErrorIs(t, CheckReceiptImage([]byte("not an image")), ErrInvalidImage)
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/repository"
+	"context"
+	"log"
+	"time"
+)
+
+type IRecurringTransactionScheduler interface {
+	Start(ctx context.Context)
+	RunDue(now time.Time)
+}
+
+type RecurringTransactionScheduler struct {
+	recurringRepository   repository.IRecurringTransactionRepository
+	transactionRepository repository.ITransactionRepository
+	interval              time.Duration
+}
+
+func NewRecurringTransactionScheduler(recurringRepo repository.IRecurringTransactionRepository, transactionRepo repository.ITransactionRepository, interval time.Duration) *RecurringTransactionScheduler {
+	return &RecurringTransactionScheduler{
+		recurringRepository:   recurringRepo,
+		transactionRepository: transactionRepo,
+		interval:              interval,
+	}
+}
+
+// OccurrenceAt returns the n-th (zero based) occurrence of the rule. Months and years
+// are always added to the start date instead of to the previous occurrence, so a rule
+// starting on the 31st lands on the last day of shorter months and goes back to the 31st after.
+func OccurrenceAt(r model.RecurringTransaction, n int32) time.Time {
+	step := int(r.Interval) * int(n)
+	switch r.Frequency {
+	case enum.Weekly:
+		return r.StartDate.AddDate(0, 0, 7*step)
+	case enum.Monthly:
+		return addMonthsClamped(r.StartDate, step)
+	case enum.Yearly:
+		return addMonthsClamped(r.StartDate, 12*step)
+	default: // Daily
+		return r.StartDate.AddDate(0, 0, step)
+	}
+}
+
+// NextOccurrence returns the occurrence following the ones already materialized,
+// or nil when the rule has reached its end date or count.
+func NextOccurrence(r model.RecurringTransaction) *time.Time {
+	if r.MaxOccurrences != nil && r.Occurrences >= *r.MaxOccurrences {
+		return nil
+	}
+	next := OccurrenceAt(r, r.Occurrences)
+	if r.EndDate != nil && next.After(*r.EndDate) {
+		return nil
+	}
+	return &next
+}
+
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// Start runs the scheduler in the background until ctx is cancelled. The first run
+// happens immediately so occurrences missed while the server was down are caught up.
+func (s *RecurringTransactionScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.RunDue(time.Now())
+
+			select {
+			case <-ctx.Done():
+				log.Println("Recurring transaction scheduler stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *RecurringTransactionScheduler) RunDue(now time.Time) {
+	for _, r := range s.recurringRepository.FindDue(now) {
+		s.materialize(r, now)
+	}
+}
+
+// materialize creates every occurrence of r that is due by now. Transactions go through
+// ITransactionRepository.Save so the balance is adjusted the same way as for manual ones;
+// an occurrence that already exists (unique on recurring_id + date_made) is skipped.
+func (s *RecurringTransactionScheduler) materialize(r model.RecurringTransaction, now time.Time) {
+	for r.NextRun != nil && !r.NextRun.After(now) {
+		recurringId := r.ID
+		transaction := model.Transaction{
+			Title:       r.Title,
+			Price:       r.Price,
+			DateMade:    *r.NextRun,
+			OwnerId:     r.OwnerId,
+			CategoryId:  r.CategoryId,
+			Type:        r.Type,
+			RecurringId: &recurringId,
+		}
+
+		err := s.transactionRepository.Save(transaction)
+		if err != nil && !repository.IsUniqueViolation(err) {
+			log.Printf("failed to materialize recurring transaction %d: %v", r.ID, err)
+			return
+		}
+
+		r.Occurrences++
+		r.NextRun = NextOccurrence(r)
+		if err := s.recurringRepository.UpdateSchedule(r.ID, r.Occurrences, r.NextRun); err != nil {
+			log.Printf("failed to update schedule of recurring transaction %d: %v", r.ID, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+func TestOccurrenceAtMonthlyClampsToEndOfMonth(t *testing.T) {
+	rule := model.RecurringTransaction{Frequency: enum.Monthly, Interval: 1, StartDate: date(2025, time.January, 31)}
+
+	assert.Equal(t, date(2025, time.February, 28), OccurrenceAt(rule, 1))
+	assert.Equal(t, date(2025, time.March, 31), OccurrenceAt(rule, 2))
+	assert.Equal(t, date(2025, time.April, 30), OccurrenceAt(rule, 3))
+}
+
+func TestOccurrenceAtUsesInterval(t *testing.T) {
+	weekly := model.RecurringTransaction{Frequency: enum.Weekly, Interval: 2, StartDate: date(2025, time.March, 3)}
+	yearly := model.RecurringTransaction{Frequency: enum.Yearly, Interval: 1, StartDate: date(2024, time.February, 29)}
+
+	assert.Equal(t, date(2025, time.March, 17), OccurrenceAt(weekly, 1))
+	assert.Equal(t, date(2025, time.February, 28), OccurrenceAt(yearly, 1))
+	assert.Equal(t, date(2028, time.February, 29), OccurrenceAt(yearly, 4))
+}
+
+func TestNextOccurrenceStopsAtCount(t *testing.T) {
+	count := int32(3)
+	rule := model.RecurringTransaction{Frequency: enum.Daily, Interval: 1, StartDate: date(2025, time.May, 1), MaxOccurrences: &count}
+
+	rule.Occurrences = 2
+	assert.Equal(t, date(2025, time.May, 3), *NextOccurrence(rule))
+
+	rule.Occurrences = 3
+	assert.Nil(t, NextOccurrence(rule))
+}
+
+func TestNextOccurrenceStopsAtEndDate(t *testing.T) {
+	end := date(2025, time.June, 15)
+	rule := model.RecurringTransaction{Frequency: enum.Monthly, Interval: 1, StartDate: date(2025, time.April, 15), EndDate: &end}
+
+	rule.Occurrences = 2
+	assert.Equal(t, end, *NextOccurrence(rule))
+
+	rule.Occurrences = 3
+	assert.Nil(t, NextOccurrence(rule))
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID keeps the ID of the request the work in ctx is done for, it is sent on with
+// the calls to other services.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID is the ID WithRequestID kept in ctx, empty when there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
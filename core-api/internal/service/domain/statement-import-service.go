@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// IStatementParser reads the transactions of a bank statement. The returned error is about
+// the file as a whole, problems with single transactions are reported on their row.
+type IStatementParser interface {
+	Parse(r io.Reader) ([]StatementRow, error)
+}
+
+// StatementRow is one transaction of an imported bank statement, Line is its line in a CSV
+// file or its position in the other formats. The transaction has a positive price, its type
+// carries the direction; owner and account are left to the importer, so is the currency
+// unless the statement names it. A row that could not be read has Err set.
+type StatementRow struct {
+	Line        int
+	Transaction model.Transaction
+	Err         error
+}
+
+// SignConvention tells how a bank statement marks money going out.
+type SignConvention string
+
+const (
+	NegativeIsExpense SignConvention = "negative_expense" // -12.50 is money spent, the usual bank export
+	PositiveIsExpense SignConvention = "positive_expense" // 12.50 is money spent, e.g. credit card statements
+	DebitCredit       SignConvention = "debit_credit"     // separate debit (out) and credit (in) columns
+)
+
+// CSVMapping describes the layout of a bank's CSV export. Columns are given by their header
+// name or by their 1-based position.
+type CSVMapping struct {
+	DateColumn        string
+	AmountColumn      string
+	DescriptionColumn string
+	DebitColumn       string
+	CreditColumn      string
+	SignConvention    SignConvention
+	DateFormat        string // e.g. DD.MM.YYYY, defaults to YYYY-MM-DD
+	DecimalSeparator  string // "." or ","
+	Delimiter         rune
+	HasHeader         bool
+}
+
+var dateFormatTokens = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02")
+
+// goDateLayout turns DD.MM.YYYY style formats into a Go layout, Go layouts are kept as they are.
+func goDateLayout(format string) string {
+	if format == "" {
+		return time.DateOnly
+	}
+	return dateFormatTokens.Replace(format)
+}
+
+// columnIndex resolves a column of the mapping to its index, -1 when the column is not mapped.
+func columnIndex(header []string, column string) (int, error) {
+	column = strings.TrimSpace(column)
+	if column == "" {
+		return -1, nil
+	}
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return 0, fmt.Errorf("column %d does not exist, columns start at 1", n)
+		}
+		return n - 1, nil
+	}
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("column %q not found in the header", column)
+}
+
+// parseAmount reads amounts like "1.234,56", "-12.50", "12.50-" and "(12.50)". An empty value is zero.
+func parseAmount(value string, decimalSeparator string) (decimal.Decimal, error) {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return decimal.Zero, nil
+	}
+
+	negative := false
+	if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") {
+		negative = true
+		v = v[1 : len(v)-1]
+	}
+	if strings.HasSuffix(v, "-") {
+		negative = true
+		v = strings.TrimSuffix(v, "-")
+	}
+
+	thousandsSeparator := ","
+	if decimalSeparator == "," {
+		thousandsSeparator = "."
+	}
+	v = strings.NewReplacer(thousandsSeparator, "", " ", "", "\u00a0", "", "'", "").Replace(v)
+	if decimalSeparator == "," {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+
+	amount, err := decimal.NewFromString(v)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// CSVStatementParser reads CSV exports laid out as described by its mapping.
+type CSVStatementParser struct {
+	mapping CSVMapping
+}
+
+func NewCSVStatementParser(mapping CSVMapping) *CSVStatementParser {
+	return &CSVStatementParser{mapping: mapping}
+}
+
+func (p *CSVStatementParser) Parse(r io.Reader) ([]StatementRow, error) {
+	mapping := p.mapping
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if mapping.Delimiter != 0 {
+		reader.Comma = mapping.Delimiter
+	}
+
+	line := 0
+	var header []string
+	if mapping.HasHeader {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the header: %v", err)
+		}
+		line++
+		record[0] = strings.TrimPrefix(record[0], "\ufeff") // Excel writes a byte order mark
+		header = record
+	}
+
+	dateIndex, err := columnIndex(header, mapping.DateColumn)
+	if err != nil {
+		return nil, err
+	}
+	descriptionIndex, err := columnIndex(header, mapping.DescriptionColumn)
+	if err != nil {
+		return nil, err
+	}
+	if dateIndex < 0 {
+		return nil, fmt.Errorf("the date column is required")
+	}
+
+	var amountIndex, debitIndex, creditIndex int
+	if mapping.SignConvention == DebitCredit {
+		if debitIndex, err = columnIndex(header, mapping.DebitColumn); err != nil {
+			return nil, err
+		}
+		if creditIndex, err = columnIndex(header, mapping.CreditColumn); err != nil {
+			return nil, err
+		}
+		if debitIndex < 0 || creditIndex < 0 {
+			return nil, fmt.Errorf("the debit and credit columns are required")
+		}
+	} else {
+		if amountIndex, err = columnIndex(header, mapping.AmountColumn); err != nil {
+			return nil, err
+		}
+		if amountIndex < 0 {
+			return nil, fmt.Errorf("the amount column is required")
+		}
+	}
+
+	layout := goDateLayout(mapping.DateFormat)
+	field := func(record []string, index int) (string, error) {
+		if index < 0 {
+			return "", nil
+		}
+		if index >= len(record) {
+			return "", fmt.Errorf("line has %d columns, column %d is missing", len(record), index+1)
+		}
+		return strings.TrimSpace(record[index]), nil
+	}
+
+	var rows []StatementRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			rows = append(rows, StatementRow{Line: line, Err: err})
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // blank line
+		}
+
+		row := StatementRow{Line: line}
+		row.Err = func() error {
+			dateValue, err := field(record, dateIndex)
+			if err != nil {
+				return err
+			}
+			row.Transaction.DateMade, err = time.Parse(layout, dateValue)
+			if err != nil {
+				return fmt.Errorf("invalid date %q, expected format %s", dateValue, mapping.DateFormat)
+			}
+
+			if row.Transaction.Title, err = field(record, descriptionIndex); err != nil {
+				return err
+			}
+
+			var signed decimal.Decimal
+			if mapping.SignConvention == DebitCredit {
+				debit, err := field(record, debitIndex)
+				if err != nil {
+					return err
+				}
+				credit, err := field(record, creditIndex)
+				if err != nil {
+					return err
+				}
+				debitAmount, err := parseAmount(debit, mapping.DecimalSeparator)
+				if err != nil {
+					return err
+				}
+				creditAmount, err := parseAmount(credit, mapping.DecimalSeparator)
+				if err != nil {
+					return err
+				}
+				signed = creditAmount.Sub(debitAmount.Abs())
+			} else {
+				value, err := field(record, amountIndex)
+				if err != nil {
+					return err
+				}
+				if signed, err = parseAmount(value, mapping.DecimalSeparator); err != nil {
+					return err
+				}
+				if mapping.SignConvention == PositiveIsExpense {
+					signed = signed.Neg()
+				}
+			}
+
+			return setSignedAmount(&row.Transaction, signed, "")
+		}()
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// setSignedAmount sets the price of transaction from an amount that is negative for money
+// going out, an empty currency is left to the importer.
+func setSignedAmount(transaction *model.Transaction, signed decimal.Decimal, currency enum.Currency) error {
+	if signed.IsZero() {
+		return fmt.Errorf("amount is zero")
+	}
+	transaction.Type = enum.Income
+	if signed.IsNegative() {
+		transaction.Type = enum.Expense
+	}
+	transaction.Price = money.New(signed.Abs(), currency)
+	return nil
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVStatementParserNegativeIsExpense(t *testing.T) {
+	csv := "\ufeffDate,Description,Amount\n" +
+		"2025-07-01,Salary,\"1,500.00\"\n" +
+		"2025-07-02,Groceries,-42.10\n" +
+		"2025-07-03,Refund,(5.00)\n"
+
+	rows, err := NewCSVStatementParser(CSVMapping{
+		DateColumn:        "date",
+		AmountColumn:      "Amount",
+		DescriptionColumn: "Description",
+		HasHeader:         true,
+	}).Parse(strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, enum.Income, rows[0].Transaction.Type)
+	assert.Equal(t, "1500", rows[0].Transaction.Price.Amount.String())
+	assert.Equal(t, "Salary", rows[0].Transaction.Title)
+
+	assert.Equal(t, enum.Expense, rows[1].Transaction.Type)
+	assert.Equal(t, "42.1", rows[1].Transaction.Price.Amount.String())
+
+	assert.Equal(t, enum.Expense, rows[2].Transaction.Type)
+	assert.Equal(t, "5", rows[2].Transaction.Price.Amount.String())
+}
+
+func TestCSVStatementParserEuropeanFormat(t *testing.T) {
+	csv := "03.07.2025;Rent;1.200,50-\n" +
+		"31.02.2025;Broken;10,00\n"
+
+	rows, err := NewCSVStatementParser(CSVMapping{
+		DateColumn:        "1",
+		DescriptionColumn: "2",
+		AmountColumn:      "3",
+		DateFormat:        "DD.MM.YYYY",
+		DecimalSeparator:  ",",
+		Delimiter:         ';',
+	}).Parse(strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, "2025-07-03", rows[0].Transaction.DateMade.Format("2006-01-02"))
+	assert.Equal(t, enum.Expense, rows[0].Transaction.Type)
+	assert.Equal(t, "1200.5", rows[0].Transaction.Price.Amount.String())
+
+	assert.Error(t, rows[1].Err)
+	assert.Equal(t, 2, rows[1].Line)
+}
+
+func TestCSVStatementParserDebitCredit(t *testing.T) {
+	csv := "Booked,Text,Debit,Credit\n" +
+		"2025-07-01,Card payment,12.99,\n" +
+		"2025-07-02,Transfer in,,100\n" +
+		"2025-07-03,Nothing,,\n"
+
+	rows, err := NewCSVStatementParser(CSVMapping{
+		DateColumn:        "Booked",
+		DescriptionColumn: "Text",
+		DebitColumn:       "Debit",
+		CreditColumn:      "Credit",
+		SignConvention:    DebitCredit,
+		HasHeader:         true,
+	}).Parse(strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+
+	assert.Equal(t, enum.Expense, rows[0].Transaction.Type)
+	assert.Equal(t, "12.99", rows[0].Transaction.Price.Amount.String())
+	assert.Equal(t, enum.Income, rows[1].Transaction.Type)
+	assert.Error(t, rows[2].Err)
+}
+
+func TestCSVStatementParserPositiveIsExpense(t *testing.T) {
+	rows, err := NewCSVStatementParser(CSVMapping{
+		DateColumn:     "1",
+		AmountColumn:   "3",
+		SignConvention: PositiveIsExpense,
+	}).Parse(strings.NewReader("2025-07-01,Coffee,3.50\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, enum.Expense, rows[0].Transaction.Type)
+}
+
+func TestCSVStatementParserUnknownColumn(t *testing.T) {
+	_, err := NewCSVStatementParser(CSVMapping{
+		DateColumn:   "Date",
+		AmountColumn: "Betrag",
+		HasHeader:    true,
+	}).Parse(strings.NewReader("Date,Amount\n"))
+	assert.Error(t, err)
+}
@@ -1,15 +1,19 @@
 package domain
 
 import (
+	"SmartSpend/internal/domain/money"
 	"SmartSpend/internal/repository"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 type IStatisticsService interface {
-	FindTotalIncomeAndExpense(userId string, from time.Time, to time.Time) (float32, float32, error)
-	FindPercentageSpentPerCategory(userId string, from time.Time, to time.Time) (map[string]float32, float32, float32, error)
-	FindTotalSpentPerMonth(userId string, from time.Time, to time.Time) (map[int32]float32, error)
-	FindAverage(userId string, from time.Time, to time.Time) (float32, float32, error)
+	FindTotalIncomeAndExpense(userId string, from time.Time, to time.Time) (money.Money, money.Money, error)
+	FindPercentageSpentPerCategory(userId string, from time.Time, to time.Time, rollup bool) (map[string]decimal.Decimal, money.Money, money.Money, error)
+	FindTotalSpentPerCategory(userId string, from time.Time, to time.Time) (map[int64]money.Money, error)
+	FindTotalSpentPerMonth(userId string, from time.Time, to time.Time) (map[int32]money.Money, error)
+	FindAverage(userId string, from time.Time, to time.Time) (money.Money, money.Money, error)
 }
 
 type StatisticsService struct {
@@ -20,18 +24,22 @@ func NewStatisticsService(statisticsRepository repository.IStatisticsRepository)
 	return &StatisticsService{statisticsRepository: statisticsRepository}
 }
 
-func (s *StatisticsService) FindTotalIncomeAndExpense(userId string, from time.Time, to time.Time) (float32, float32, error) {
+func (s *StatisticsService) FindTotalIncomeAndExpense(userId string, from time.Time, to time.Time) (money.Money, money.Money, error) {
 	return s.statisticsRepository.FindTotalIncomeAndExpense(userId, from, to)
 }
 
-func (s *StatisticsService) FindPercentageSpentPerCategory(userId string, from time.Time, to time.Time) (map[string]float32, float32, float32, error) {
-	return s.statisticsRepository.FindPercentageSpentPerCategory(userId, from, to)
+func (s *StatisticsService) FindPercentageSpentPerCategory(userId string, from time.Time, to time.Time, rollup bool) (map[string]decimal.Decimal, money.Money, money.Money, error) {
+	return s.statisticsRepository.FindPercentageSpentPerCategory(userId, from, to, rollup)
+}
+
+func (s *StatisticsService) FindTotalSpentPerCategory(userId string, from time.Time, to time.Time) (map[int64]money.Money, error) {
+	return s.statisticsRepository.FindTotalSpentPerCategory(userId, from, to)
 }
 
-func (s *StatisticsService) FindTotalSpentPerMonth(userId string, from time.Time, to time.Time) (map[int32]float32, error) {
+func (s *StatisticsService) FindTotalSpentPerMonth(userId string, from time.Time, to time.Time) (map[int32]money.Money, error) {
 	return s.statisticsRepository.FindTotalSpentPerMonth(userId, from, to)
 }
 
-func (s *StatisticsService) FindAverage(userId string, from time.Time, to time.Time) (float32, float32, error) {
+func (s *StatisticsService) FindAverage(userId string, from time.Time, to time.Time) (money.Money, money.Money, error) {
 	return s.statisticsRepository.FindAverage(userId, from, to)
 }
@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ITransactionExportWriter writes exported transactions one at a time, Close finishes the
+// document. Nothing is kept in memory that does not have to be.
+type ITransactionExportWriter interface {
+	Write(transaction model.TransactionExport) error
+	Close() error
+}
+
+// ExportFormats maps the supported export formats to their content type.
+var ExportFormats = map[string]string{
+	"csv":  "text/csv",
+	"json": "application/json",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+var exportHeader = []string{
+	"Date", "Title", "Type", "Amount", "Currency", "Converted amount", "Preferred currency",
+	"Category", "Account", "Transfer account", "Running balance",
+}
+
+func NewTransactionExportWriter(format string, w io.Writer) (ITransactionExportWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVExportWriter(w)
+	case "json":
+		return &jsonExportWriter{w: w}, nil
+	case "xlsx":
+		return newXLSXExportWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q, use csv, json or xlsx", format)
+	}
+}
+
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	return &csvExportWriter{writer: writer}, nil
+}
+
+func (e *csvExportWriter) Write(t model.TransactionExport) error {
+	return e.writer.Write([]string{
+		t.DateMade.Format(time.RFC3339),
+		t.Title,
+		string(t.Type),
+		t.Price.Amount.StringFixed(2),
+		string(t.Price.Currency),
+		t.ConvertedPrice.Amount.StringFixed(2),
+		string(t.ConvertedPrice.Currency),
+		valueOrEmpty(t.CategoryName),
+		t.AccountName,
+		valueOrEmpty(t.TransferAccountName),
+		t.RunningBalance.Amount.StringFixed(2),
+	})
+}
+
+func (e *csvExportWriter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonExportWriter writes a JSON array, one element per transaction.
+type jsonExportWriter struct {
+	w       io.Writer
+	written int
+}
+
+type jsonExportRecord struct {
+	ID              int64       `json:"id"`
+	DateMade        time.Time   `json:"date_made"`
+	Title           string      `json:"title"`
+	Type            string      `json:"type"`
+	Price           money.Money `json:"price"`
+	ConvertedPrice  money.Money `json:"converted_price"`
+	Category        *string     `json:"category"`
+	Account         string      `json:"account"`
+	TransferAccount *string     `json:"transfer_account"`
+	ExternalId      *string     `json:"external_id,omitempty"`
+	RunningBalance  money.Money `json:"running_balance"`
+}
+
+func (e *jsonExportWriter) Write(t model.TransactionExport) error {
+	data, err := json.Marshal(jsonExportRecord{
+		ID:              t.ID,
+		DateMade:        t.DateMade,
+		Title:           t.Title,
+		Type:            string(t.Type),
+		Price:           t.Price,
+		ConvertedPrice:  t.ConvertedPrice,
+		Category:        t.CategoryName,
+		Account:         t.AccountName,
+		TransferAccount: t.TransferAccountName,
+		ExternalId:      t.ExternalId,
+		RunningBalance:  t.RunningBalance,
+	})
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if e.written == 0 {
+		separator = "[\n"
+	}
+	e.written++
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExportWriter) Close() error {
+	end := "\n]\n"
+	if e.written == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// xlsxExportWriter streams the rows into a single sheet, excelize spills them to a
+// temporary file once they no longer fit in its buffer.
+type xlsxExportWriter struct {
+	w         io.Writer
+	file      *excelize.File
+	stream    *excelize.StreamWriter
+	dateStyle int
+	row       int
+}
+
+func newXLSXExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		return nil, err
+	}
+	dateStyle, err := file.NewStyle(&excelize.Style{NumFmt: 22}) // m/d/yy h:mm
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(exportHeader))
+	for i, title := range exportHeader {
+		header[i] = title
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		return nil, err
+	}
+	return &xlsxExportWriter{w: w, file: file, stream: stream, dateStyle: dateStyle, row: 1}, nil
+}
+
+func (e *xlsxExportWriter) Write(t model.TransactionExport) error {
+	e.row++
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, []interface{}{
+		excelize.Cell{StyleID: e.dateStyle, Value: t.DateMade},
+		t.Title,
+		string(t.Type),
+		t.Price.Amount.InexactFloat64(),
+		string(t.Price.Currency),
+		t.ConvertedPrice.Amount.InexactFloat64(),
+		string(t.ConvertedPrice.Currency),
+		valueOrEmpty(t.CategoryName),
+		t.AccountName,
+		valueOrEmpty(t.TransferAccountName),
+		t.RunningBalance.Amount.InexactFloat64(),
+	})
+}
+
+func (e *xlsxExportWriter) Close() error {
+	defer e.file.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func exportedTransactions() []model.TransactionExport {
+	groceries := "Groceries"
+	return []model.TransactionExport{
+		{
+			Transaction: model.Transaction{
+				ID:             1,
+				Title:          "Salary",
+				Price:          money.MustFromString("1000", enum.EUR),
+				ConvertedPrice: money.MustFromString("61495", enum.MKD),
+				DateMade:       time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC),
+				Type:           enum.Income,
+			},
+			AccountName:    "Main",
+			RunningBalance: money.MustFromString("61495", enum.MKD),
+		},
+		{
+			Transaction: model.Transaction{
+				ID:             2,
+				Title:          "Market, fresh",
+				Price:          money.MustFromString("495", enum.MKD),
+				ConvertedPrice: money.MustFromString("495", enum.MKD),
+				DateMade:       time.Date(2025, 7, 2, 18, 30, 0, 0, time.UTC),
+				Type:           enum.Expense,
+			},
+			CategoryName:   &groceries,
+			AccountName:    "Main",
+			RunningBalance: money.MustFromString("61000", enum.MKD),
+		},
+	}
+}
+
+func export(t *testing.T, format string, transactions []model.TransactionExport) []byte {
+	var buf bytes.Buffer
+	writer, err := NewTransactionExportWriter(format, &buf)
+	assert.NoError(t, err)
+	for _, transaction := range transactions {
+		assert.NoError(t, writer.Write(transaction))
+	}
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestExportCSV(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(export(t, "csv", exportedTransactions()))), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "2025-07-02T18:30:00Z,\"Market, fresh\",Expense,495.00,MKD,495.00,MKD,Groceries,Main,,61000.00", lines[2])
+}
+
+func TestExportJSON(t *testing.T) {
+	var records []map[string]any
+	assert.NoError(t, json.Unmarshal(export(t, "json", exportedTransactions()), &records))
+	assert.Len(t, records, 2)
+	assert.Equal(t, "Groceries", records[1]["category"])
+	assert.Equal(t, map[string]any{"amount": "61000.00", "currency": "MKD"}, records[1]["running_balance"])
+
+	assert.JSONEq(t, "[]", string(export(t, "json", nil)))
+}
+
+func TestExportXLSX(t *testing.T) {
+	file, err := excelize.OpenReader(bytes.NewReader(export(t, "xlsx", exportedTransactions())))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	rows, err := file.GetRows("Sheet1")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "Salary", rows[1][1])
+	assert.Equal(t, "61000", rows[2][10])
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	_, err := NewTransactionExportWriter("pdf", &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// NormalizeTransactionItem completes an item as it comes from a receipt or a user: the
+// quantity defaults to 1 and the unit price and the total are derived from each other.
+// The amounts take the currency of the transaction. Negative amounts are discounts.
+func NormalizeTransactionItem(item model.TransactionItem, currency enum.Currency) (model.TransactionItem, error) {
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" {
+		return item, fmt.Errorf("item name is required")
+	}
+	if item.Quantity.IsZero() {
+		item.Quantity = decimal.NewFromInt(1)
+	}
+	if !item.Quantity.IsPositive() {
+		return item, fmt.Errorf("quantity of %s must be positive", item.Name)
+	}
+	if item.VatRate != nil && (item.VatRate.IsNegative() || item.VatRate.GreaterThan(hundred)) {
+		return item, fmt.Errorf("VAT rate of %s must be between 0 and 100", item.Name)
+	}
+
+	switch {
+	case item.Total.IsZero() && !item.UnitPrice.IsZero():
+		item.Total.Amount = item.UnitPrice.Amount.Mul(item.Quantity)
+	case item.UnitPrice.IsZero() && !item.Total.IsZero():
+		item.UnitPrice.Amount = item.Total.Amount.Div(item.Quantity)
+	}
+	item.UnitPrice = money.New(item.UnitPrice.Amount, currency).Round()
+	item.Total = money.New(item.Total.Amount, currency).Round()
+	return item, nil
+}
+
+// ItemsDifference returns the sum of the items and how much the price differs from it,
+// zero when the items add up.
+func ItemsDifference(price money.Money, items []model.TransactionItem) (money.Money, money.Money) {
+	total := money.Zero(price.Currency)
+	for _, item := range items {
+		total.Amount = total.Amount.Add(item.Total.Amount)
+	}
+	return total, money.New(price.Amount.Sub(total.Amount), price.Currency)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"SmartSpend/internal/domain/enum"
+	"SmartSpend/internal/domain/model"
+	"SmartSpend/internal/domain/money"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTransactionItemDerivesAmounts(t *testing.T) {
+	item, err := NormalizeTransactionItem(model.TransactionItem{
+		Name:      " Milk ",
+		Quantity:  decimal.NewFromInt(3),
+		UnitPrice: money.MustFromString("65", ""),
+	}, enum.MKD)
+	assert.NoError(t, err)
+	assert.Equal(t, "Milk", item.Name)
+	assert.Equal(t, "195.00 MKD", item.Total.String())
+
+	item, err = NormalizeTransactionItem(model.TransactionItem{
+		Name:  "Bread",
+		Total: money.MustFromString("90", ""),
+	}, enum.MKD)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", item.Quantity.String())
+	assert.Equal(t, "90.00 MKD", item.UnitPrice.String())
+}
+
+func TestNormalizeTransactionItemRejectsInvalid(t *testing.T) {
+	_, err := NormalizeTransactionItem(model.TransactionItem{Name: " "}, enum.MKD)
+	assert.Error(t, err)
+
+	_, err = NormalizeTransactionItem(model.TransactionItem{Name: "Milk", Quantity: decimal.NewFromInt(-1)}, enum.MKD)
+	assert.Error(t, err)
+
+	vat := decimal.NewFromInt(180)
+	_, err = NormalizeTransactionItem(model.TransactionItem{Name: "Milk", VatRate: &vat}, enum.MKD)
+	assert.Error(t, err)
+}
+
+func TestItemsDifference(t *testing.T) {
+	items := []model.TransactionItem{
+		{Name: "Milk", Total: money.MustFromString("65", enum.MKD)},
+		{Name: "Bread", Total: money.MustFromString("30", enum.MKD)},
+		{Name: "Discount", Total: money.MustFromString("-5", enum.MKD)},
+	}
+
+	total, difference := ItemsDifference(money.MustFromString("90", enum.MKD), items)
+	assert.Equal(t, "90.00 MKD", total.String())
+	assert.True(t, difference.IsZero())
+
+	_, difference = ItemsDifference(money.MustFromString("100", enum.MKD), items)
+	assert.Equal(t, "10.00 MKD", difference.String())
+}
@@ -1,12 +1,47 @@
 package main
 
 import (
-	"log"
+	"flag"
+	"log/slog"
 	"net/http"
 	"ocr-service/handlers"
+	"os"
 )
 
+const address = ":5000"
+
 func main() {
-	http.HandleFunc("/ocr", handlers.OcrHandler) // not using Gin, need it to be as light as possible!
-	log.Fatal(http.ListenAndServe(":5000", nil))
+	healthcheck := flag.Bool("healthcheck", false, "ask the running service for /healthz and exit with 0 when it is healthy")
+	flag.Parse()
+	if *healthcheck {
+		// the runtime image has no curl, docker-compose checks the health with the binary itself
+		response, err := http.Get("http://localhost" + address + "/healthz")
+		if err != nil || response.StatusCode != http.StatusOK {
+			os.Exit(1)
+		}
+		return
+	}
+
+	slog.SetDefault(handlers.Logger)
+
+	// OCR_PIPELINES is a JSON file of pipelines like handlers/pipelines.json, to read other
+	// receipts without a new build
+	if err := handlers.LoadPipelines(os.Getenv("OCR_PIPELINES")); err != nil {
+		slog.Error("failed to load pipelines", "error", err)
+		os.Exit(1)
+	}
+
+	// not using Gin, need it to be as light as possible!
+	mux := http.NewServeMux()
+	mux.Handle("/ocr", handlers.WithRequestID(http.HandlerFunc(handlers.OcrHandler)))
+	mux.Handle("/admin/cache", handlers.WithRequestID(http.HandlerFunc(handlers.PurgeCacheHandler)))
+	mux.HandleFunc("/healthz", handlers.HealthzHandler)
+	mux.HandleFunc("/readyz", handlers.ReadyzHandler)
+	mux.Handle("/metrics", handlers.MetricsHandler())
+
+	slog.Info("listening", "address", address)
+	if err := http.ListenAndServe(address, mux); err != nil {
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
+	}
 }
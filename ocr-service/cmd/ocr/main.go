@@ -0,0 +1,376 @@
+// Command ocr reads receipts with the pipelines of the ocr-service without its HTTP server,
+// to tune the preprocessing against an archive of receipts:
+//
+//	go run ./cmd/ocr -pipelines enhanced,dilated -format tsv receipts/
+//
+// Every image and PDF of the folders and files given is read as OcrHandler reads an upload
+// of it. A receipt with its ground truth next to it, photo.txt for photo.jpg, or in the
+// -truth folder, is scored by the character error rate of every variant and of the best
+// text, and the mean of each over all scored receipts closes the output and goes to stderr.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"ocr-service/handlers"
+)
+
+// receiptExtensions are the files a folder is searched for.
+var receiptExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".heic": true, ".heif": true, ".pdf": true}
+
+// bestVariant is how the text the service would answer with is named among the variants.
+const bestVariant = "best"
+
+// receiptResult is what was read from one receipt.
+type receiptResult struct {
+	File    string             `json:"file"`
+	Error   string             `json:"error,omitempty"`
+	Seconds float64            `json:"seconds"`
+	Text    string             `json:"text"`
+	Pages   []handlers.OCRPage `json:"pages,omitempty"`
+	// Variants are the text of every variant and the best one over all pages, and how well
+	// they match the truth when there is one.
+	Variants []variantResult `json:"variants,omitempty"`
+	Scored   bool            `json:"scored"`
+}
+
+type variantResult struct {
+	Name       string   `json:"name"`
+	Text       string   `json:"-"`
+	Confidence float64  `json:"confidence"` // mean of the pages
+	CER        *float64 `json:"cer,omitempty"`
+}
+
+// summary is the mean character error rate of every variant over the scored receipts.
+type summary struct {
+	Files  int                `json:"files"`
+	Failed int                `json:"failed"`
+	Scored int                `json:"scored"`
+	CER    map[string]float64 `json:"cer"`
+}
+
+func main() {
+	var (
+		pipelineNames = flag.String("pipelines", "", "configured pipelines to read with, comma separated, the default ones when empty")
+		steps         = flag.String("steps", "", "read with a pipeline of these steps instead, e.g. deskew,grayscale,threshold:otsu")
+		language      = flag.String("language", "", "languages of the -steps pipeline, e.g. eng+sqi, mkd when empty")
+		psm           = flag.Int("psm", 0, "page segmentation mode of the -steps pipeline, 6 when 0")
+		whitelist     = flag.String("whitelist", "", "characters the -steps pipeline may read, all when empty")
+		config        = flag.String("config", os.Getenv("OCR_PIPELINES"), "pipeline config like handlers/pipelines.json, the embedded one when empty")
+		consensus     = flag.Bool("consensus", false, "take the lines the variants agree on as the best text")
+		format        = flag.String("format", "json", "output: json, or tsv with a row for every variant of every receipt")
+		output        = flag.String("o", "", "file to write to, stdout when empty")
+		truthDir      = flag.String("truth", "", "folder of the ground truth, name.txt for every receipt name.*; next to the receipts when empty")
+		parallel      = flag.Int("parallel", 1, "receipts read at once, the variants of each run in parallel anyway")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file-or-folder...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || (*format != "json" && *format != "tsv") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// stdout is for the results
+	handlers.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	slog.SetDefault(handlers.Logger)
+
+	if err := handlers.LoadPipelines(*config); err != nil {
+		fatal("failed to load pipelines: %v", err)
+	}
+	var selected []handlers.Pipeline
+	var err error
+	if *steps != "" {
+		var custom handlers.Pipeline
+		custom, err = handlers.CustomPipeline(*steps, *language, *psm, *whitelist)
+		selected = []handlers.Pipeline{custom}
+	} else {
+		var names []string
+		if *pipelineNames != "" {
+			names = strings.Split(*pipelineNames, ",")
+		}
+		selected, err = handlers.SelectPipelines(names)
+	}
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	files, err := receiptFiles(flag.Args())
+	if err != nil {
+		fatal("%v", err)
+	}
+	if len(files) == 0 {
+		fatal("no receipts in %s", strings.Join(flag.Args(), ", "))
+	}
+
+	results := readReceipts(files, *parallel, func(file string) receiptResult {
+		return readReceipt(file, truthFile(file, *truthDir), selected, *consensus)
+	})
+	total := summarize(results)
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fatal("%v", err)
+		}
+		defer out.Close()
+	}
+	if *format == "tsv" {
+		err = writeTSV(out, results, total)
+	} else {
+		err = writeJSON(out, results, total)
+	}
+	if err != nil {
+		fatal("failed to write the results: %v", err)
+	}
+	writeSummary(os.Stderr, total)
+
+	if total.Failed > 0 {
+		out.Close()
+		os.Exit(1)
+	}
+}
+
+func fatal(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "ocr: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// receiptFiles are the files given and the receipts in the folders given, in order.
+func receiptFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && receiptExtensions[strings.ToLower(filepath.Ext(file))] {
+				found = append(found, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// truthFile is where the ground truth of the receipt is, it need not exist.
+func truthFile(file, truthDir string) string {
+	stem := strings.TrimSuffix(file, filepath.Ext(file))
+	if truthDir != "" {
+		stem = filepath.Join(truthDir, filepath.Base(stem))
+	}
+	return stem + ".txt"
+}
+
+// readReceipts reads the files, parallel at once, the results keep the order of the files.
+func readReceipts(files []string, parallel int, read func(string) receiptResult) []receiptResult {
+	results := make([]receiptResult, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range max(parallel, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = read(files[i])
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// readReceipt reads the file with the pipelines and scores the variants when truth exists.
+func readReceipt(file, truth string, selected []handlers.Pipeline, consensus bool) (result receiptResult) {
+	result.File = file
+	started := time.Now()
+	defer func() { result.Seconds = time.Since(started).Seconds() }()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	pages, err := handlers.ReadFile(context.Background(), filepath.Base(file), data, selected, consensus)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Pages = pages
+	result.Text = handlers.DocumentText(pages)
+	result.Variants = variants(pages)
+
+	expected, err := os.ReadFile(truth)
+	if err != nil {
+		return result
+	}
+	result.Scored = true
+	for i := range result.Variants {
+		cer := handlers.CharacterErrorRate(string(expected), result.Variants[i].Text)
+		result.Variants[i].CER = &cer
+	}
+	return result
+}
+
+// variants gathers the text of every variant over the pages, and the best text of each page
+// as bestVariant. The QR code is not a reading of the text and is left out.
+func variants(pages []handlers.OCRPage) []variantResult {
+	type gathered struct {
+		texts       []string
+		confidences float64
+	}
+	byName := map[string]*gathered{}
+	var order []string
+	add := func(name, text string, confidence float64) {
+		if byName[name] == nil {
+			byName[name] = &gathered{}
+			order = append(order, name)
+		}
+		byName[name].texts = append(byName[name].texts, text)
+		byName[name].confidences += confidence
+	}
+
+	for _, page := range pages {
+		for _, result := range page.Results {
+			if result.Version != "qr" {
+				add(result.Version, result.Text, result.Confidence)
+			}
+		}
+		if page.Best != nil {
+			add(bestVariant, page.Best.Text, page.Best.Confidence)
+		} else {
+			add(bestVariant, "", 0)
+		}
+	}
+
+	result := make([]variantResult, 0, len(order))
+	for _, name := range order {
+		g := byName[name]
+		result = append(result, variantResult{
+			Name:       name,
+			Text:       strings.Join(g.texts, "\n\n"),
+			Confidence: g.confidences / float64(len(g.texts)),
+		})
+	}
+	return result
+}
+
+func summarize(results []receiptResult) summary {
+	total := summary{Files: len(results), CER: map[string]float64{}}
+	counts := map[string]int{}
+	for _, result := range results {
+		if result.Error != "" {
+			total.Failed++
+			fmt.Fprintf(os.Stderr, "ocr: %s: %s\n", result.File, result.Error)
+			continue
+		}
+		if !result.Scored {
+			continue
+		}
+		total.Scored++
+		for _, variant := range result.Variants {
+			total.CER[variant.Name] += *variant.CER
+			counts[variant.Name]++
+		}
+	}
+	for name, count := range counts {
+		total.CER[name] /= float64(count)
+	}
+	return total
+}
+
+func writeJSON(w io.Writer, results []receiptResult, total summary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Receipts []receiptResult `json:"receipts"`
+		Summary  summary         `json:"summary"`
+	}{results, total})
+}
+
+// writeTSV writes a row for every variant of every receipt, the cer column is empty for
+// receipts without truth, and a row with the mean of every variant as the file "*".
+func writeTSV(out io.Writer, results []receiptResult, total summary) error {
+	w := bufio.NewWriter(out)
+	clean := strings.NewReplacer("\t", " ", "\n", `\n`)
+	fmt.Fprintln(w, "file\tvariant\tconfidence\tcer\tseconds\ttext")
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(w, "%s\t\t\t\t%.2f\terror: %s\n", clean.Replace(result.File), result.Seconds, clean.Replace(result.Error))
+			continue
+		}
+		for _, variant := range result.Variants {
+			cer := ""
+			if variant.CER != nil {
+				cer = fmt.Sprintf("%.4f", *variant.CER)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\t%.2f\t%s\n",
+				clean.Replace(result.File), variant.Name, variant.Confidence, cer, result.Seconds, clean.Replace(variant.Text))
+		}
+	}
+	for _, name := range sortedNames(total.CER) {
+		fmt.Fprintf(w, "*\t%s\t\t%.4f\t\t\n", name, total.CER[name])
+	}
+	return w.Flush()
+}
+
+// writeSummary writes the mean error rates as a table, best variant first.
+func writeSummary(w io.Writer, total summary) {
+	fmt.Fprintf(w, "%d receipts, %d failed, %d scored against truth\n", total.Files, total.Failed, total.Scored)
+	if total.Scored == 0 {
+		return
+	}
+	names := sortedNames(total.CER)
+	sort.SliceStable(names, func(i, j int) bool { return total.CER[names[i]] < total.CER[names[j]] })
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "variant\tmean CER")
+	for _, name := range names {
+		fmt.Fprintf(table, "%s\t%.2f%%\n", name, 100*total.CER[name])
+	}
+	table.Flush()
+}
+
+func sortedNames(cer map[string]float64) []string {
+	names := make([]string, 0, len(cer))
+	for name := range cer {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// cache keeps what uploads were read as, a photo sent again after a failed save is
+	// answered without Tesseract. OCR_CACHE_SIZE entries in memory, OCR_CACHE_TTL long, also
+	// written to OCR_CACHE_DIR when it is set so they outlive a restart.
+	cache = newResultCache(envInt("OCR_CACHE_SIZE", 256), envDuration("OCR_CACHE_TTL", 24*time.Hour), os.Getenv("OCR_CACHE_DIR"))
+	// adminToken lets a request purge the cache with Authorization: Bearer OCR_ADMIN_TOKEN,
+	// without one the admin endpoint is off.
+	adminToken = os.Getenv("OCR_ADMIN_TOKEN")
+)
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		Logger.Warn("invalid setting, using the default", "name", name, "value", value, "default", fallback.String())
+		return fallback
+	}
+	return parsed
+}
+
+// cacheKey is the SHA-256 of the uploaded files, in order, and of everything else the pages
+// read from them depend on: the pipelines with their steps and settings, and consensus.
+func cacheKey(files []*multipart.FileHeader, selected []Pipeline, consensus bool) (string, error) {
+	hash := sha256.New()
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "\x00%d\x00", header.Size) // files apart
+	}
+
+	config, err := json.Marshal(selected)
+	if err != nil {
+		return "", err
+	}
+	hash.Write(config)
+	fmt.Fprintf(hash, "\x00consensus=%t", consensus)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type cacheEntry struct {
+	key    string
+	pages  []OCRPage
+	stored time.Time
+}
+
+// resultCache is a least recently used cache of pages, optionally backed by a directory with a
+// JSON file per entry.
+type resultCache struct {
+	size int
+	ttl  time.Duration
+	dir  string
+
+	mu      sync.Mutex
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+func newResultCache(size int, ttl time.Duration, dir string) *resultCache {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			errorsTotal.WithLabelValues("cache").Inc()
+			Logger.Error("failed to create the cache directory, caching in memory only", "dir", dir, "error", err)
+			dir = ""
+		}
+	}
+	return &resultCache{
+		size:    size,
+		ttl:     ttl,
+		dir:     dir,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// get returns a copy of the pages stored under key, from memory or else from disk.
+func (c *resultCache) get(key string) ([]OCRPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		if time.Since(entry.stored) < c.ttl {
+			c.order.MoveToFront(element)
+			return clonePages(entry.pages), true
+		}
+		c.remove(element)
+	}
+
+	entry, ok := c.load(key)
+	if !ok {
+		return nil, false
+	}
+	c.add(entry)
+	return clonePages(entry.pages), true
+}
+
+// put stores the pages under key, the least recently used entry makes room.
+func (c *resultCache) put(key string, pages []OCRPage) {
+	entry := &cacheEntry{key: key, pages: clonePages(pages), stored: time.Now()}
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.add(entry)
+	c.mu.Unlock()
+
+	c.save(entry)
+}
+
+// purge drops every entry, from memory and from disk, and returns how many there were in memory.
+func (c *resultCache) purge() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	purged := c.order.Len()
+	c.order.Init()
+	c.entries = map[string]*list.Element{}
+	if c.dir == "" {
+		return purged, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return purged, err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+func (c *resultCache) add(entry *cacheEntry) {
+	c.entries[entry.key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops an entry from memory, its file stays until it expires or the cache is purged.
+func (c *resultCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
+
+func (c *resultCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// load reads an entry from disk, the modification time of its file is when it was stored.
+func (c *resultCache) load(key string) (*cacheEntry, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	info, err := os.Stat(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(info.ModTime()) >= c.ttl {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var pages []OCRPage
+	if err := json.Unmarshal(data, &pages); err != nil {
+		errorsTotal.WithLabelValues("cache").Inc()
+		Logger.Error("failed to read a cached result", "key", key, "error", err)
+		return nil, false
+	}
+	return &cacheEntry{key: key, pages: pages, stored: info.ModTime()}, true
+}
+
+// save writes an entry to disk through a temporary file, a reader never sees half of one.
+func (c *resultCache) save(entry *cacheEntry) {
+	if c.dir == "" {
+		return
+	}
+	data, err := json.Marshal(entry.pages)
+	if err != nil {
+		errorsTotal.WithLabelValues("cache").Inc()
+		Logger.Error("failed to cache a result", "key", entry.key, "error", err)
+		return
+	}
+	file, err := os.CreateTemp(c.dir, entry.key+".*.tmp")
+	if err != nil {
+		errorsTotal.WithLabelValues("cache").Inc()
+		Logger.Error("failed to cache a result", "key", entry.key, "error", err)
+		return
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.path(entry.key))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		errorsTotal.WithLabelValues("cache").Inc()
+		Logger.Error("failed to cache a result", "key", entry.key, "error", err)
+	}
+}
+
+// clonePages copies the pages down to their words, what the handler trims off an answer must
+// not be trimmed off the cache.
+func clonePages(pages []OCRPage) []OCRPage {
+	cloned := make([]OCRPage, len(pages))
+	for i, page := range pages {
+		cloned[i] = page
+		cloned[i].Results = make([]OCRResult, len(page.Results))
+		for j, result := range page.Results {
+			cloned[i].Results[j] = cloneResult(result)
+		}
+		if page.Best != nil {
+			best := cloneResult(*page.Best)
+			cloned[i].Best = &best
+		}
+	}
+	return cloned
+}
+
+func cloneResult(result OCRResult) OCRResult {
+	result.Words = append([]Box(nil), result.Words...)
+	result.Lines = append([]Box(nil), result.Lines...)
+	if result.Fiscal != nil {
+		fiscal := *result.Fiscal
+		result.Fiscal = &fiscal
+	}
+	return result
+}
+
+// PurgeCacheHandler empties the result cache, DELETE with the bearer token of
+// OCR_ADMIN_TOKEN. It answers how many entries were in memory.
+func PurgeCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if adminToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", http.MethodDelete)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	purged, err := cache.purge()
+	if err != nil {
+		errorsTotal.WithLabelValues("cache").Inc()
+		Logger.ErrorContext(r.Context(), "failed to purge the cache", "error", err)
+		http.Error(w, "Failed to purge the cache", http.StatusInternalServerError)
+		return
+	}
+	Logger.InfoContext(r.Context(), "cache purged", "entries", purged)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"purged": purged})
+}
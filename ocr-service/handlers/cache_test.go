@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cachedPages(text string) []OCRPage {
+	result := OCRResult{Version: "enhanced", Text: text, Words: []Box{{Text: text}}}
+	return []OCRPage{{Page: 1, Source: "receipt.jpg", Results: []OCRResult{result}, Best: &result}}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResultCache(2, time.Hour, "")
+	cache.put("a", cachedPages("a"))
+	cache.put("b", cachedPages("b"))
+	_, ok := cache.get("a")
+	assert.True(t, ok)
+	cache.put("c", cachedPages("c"))
+
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	pages, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", pages[0].Best.Text)
+
+	// trimming an answer leaves the cache as it was
+	pages[0].Best.Words = nil
+	pages, _ = cache.get("a")
+	assert.Len(t, pages[0].Best.Words, 1)
+}
+
+func TestResultCacheOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	newResultCache(8, time.Hour, dir).put("a", cachedPages("a"))
+
+	// a restarted service finds it on disk
+	restarted := newResultCache(8, time.Hour, dir)
+	pages, ok := restarted.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, cachedPages("a"), pages)
+
+	old := time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "a.json"), old, old))
+	_, ok = newResultCache(8, time.Hour, dir).get("a")
+	assert.False(t, ok)
+
+	restarted.put("b", cachedPages("b"))
+	purged, err := restarted.purge()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	_, ok = newResultCache(8, time.Hour, dir).get("b")
+	assert.False(t, ok)
+}
+
+func TestCacheKey(t *testing.T) {
+	upload := func(contents ...string) []*multipart.FileHeader {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for _, content := range contents {
+			part, _ := writer.CreateFormFile("image", "receipt.jpg")
+			part.Write([]byte(content))
+		}
+		writer.Close()
+		r := httptest.NewRequest(http.MethodPost, "/ocr", body)
+		r.Header.Set("Content-Type", writer.FormDataContentType())
+		assert.NoError(t, r.ParseMultipartForm(1<<20))
+		return r.MultipartForm.File["image"]
+	}
+	selected := pipelines.Pipelines[:1]
+	key := func(files []*multipart.FileHeader, selected []Pipeline, consensus bool) string {
+		k, err := cacheKey(files, selected, consensus)
+		assert.NoError(t, err)
+		return k
+	}
+
+	same := key(upload("photo"), selected, false)
+	assert.Equal(t, same, key(upload("photo"), selected, false))
+	assert.NotEqual(t, same, key(upload("other photo"), selected, false))
+	assert.NotEqual(t, same, key(upload("pho", "to"), selected, false))
+	assert.NotEqual(t, same, key(upload("photo"), selected, true))
+	assert.NotEqual(t, same, key(upload("photo"), pipelines.Pipelines[1:2], false))
+}
+
+func TestPurgeCacheHandler(t *testing.T) {
+	previous := adminToken
+	defer func() { adminToken = previous }()
+
+	purge := func(token string) int {
+		r := httptest.NewRequest(http.MethodDelete, "/admin/cache", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		PurgeCacheHandler(w, r)
+		return w.Code
+	}
+
+	adminToken = ""
+	assert.Equal(t, http.StatusNotFound, purge(""))
+	adminToken = "secret"
+	assert.Equal(t, http.StatusUnauthorized, purge("guess"))
+	assert.Equal(t, http.StatusOK, purge("secret"))
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"strings"
+)
+
+// bestResult returns the text variant Tesseract was most confident about. The QR code is not a
+// reading of the text and is left out, nil means no variant read anything.
+func bestResult(results []OCRResult) *OCRResult {
+	var best *OCRResult
+	for i := range results {
+		result := &results[i]
+		if result.Version == "qr" || strings.TrimSpace(result.Text) == "" {
+			continue
+		}
+		if best == nil || result.Confidence > best.Confidence {
+			best = result
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	copied := *best
+	return &copied
+}
+
+// consensusResult merges the text variants line by line. The lines of the best variant are the
+// skeleton, every other variant votes with its closest line and its confidence as the weight,
+// so a line one preprocessing garbled is taken from the variants that read it alike.
+func consensusResult(results []OCRResult) *OCRResult {
+	best := bestResult(results)
+	if best == nil {
+		return nil
+	}
+
+	var others []OCRResult
+	for _, result := range results {
+		if result.Version != "qr" && result.Version != best.Version && strings.TrimSpace(result.Text) != "" {
+			others = append(others, result)
+		}
+	}
+
+	lines := splitLines(best.Text)
+	merged := make([]string, len(lines))
+	for i, line := range lines {
+		votes := map[string]float64{line: voteWeight(best.Confidence)}
+		for _, other := range others {
+			if candidate, ok := closestLine(line, splitLines(other.Text)); ok {
+				votes[candidate] += voteWeight(other.Confidence)
+			}
+		}
+
+		merged[i] = line
+		for candidate, weight := range votes {
+			if weight > votes[merged[i]] {
+				merged[i] = candidate
+			}
+		}
+	}
+
+	return &OCRResult{
+		Version:     "consensus",
+		Text:        strings.Join(merged, "\n"),
+		Confidence:  best.Confidence,
+		Description: "Lines voted on across the variants, " + best.Version + " as the base",
+	}
+}
+
+// voteWeight keeps a variant without a confidence in the vote.
+func voteWeight(confidence float64) float64 {
+	return max(confidence, 1)
+}
+
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// closestLine finds the line of candidates that reads most like line. Lines that differ in
+// more than a third of their characters are a different line, not another reading of it.
+func closestLine(line string, candidates []string) (string, bool) {
+	target := []rune(line)
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		distance := levenshtein(target, []rune(candidate))
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if bestDistance < 0 || bestDistance*3 > max(len(target), len([]rune(best))) {
+		return "", false
+	}
+	return best, true
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// CharacterErrorRate is the edit distance from truth to text over the length of truth, with
+// runs of whitespace taken as one space, 0 when text is exactly the truth. Text that is
+// mostly made up can score above 1.
+func CharacterErrorRate(truth, text string) float64 {
+	expected := []rune(strings.Join(strings.Fields(truth), " "))
+	read := []rune(strings.Join(strings.Fields(text), " "))
+	if len(expected) == 0 {
+		if len(read) == 0 {
+			return 0
+		}
+		return 1
+	}
+	return float64(levenshtein(expected, read)) / float64(len(expected))
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/otiai10/gosseract/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBestResult(t *testing.T) {
+	results := []OCRResult{
+		{Version: "qr", Text: "https://e-smetka.ujp.gov.mk/verify?iznos=708,50", Confidence: 100},
+		{Version: "enhanced", Text: "ВКУПНО 708,50", Confidence: 71},
+		{Version: "dilated", Text: " \n ", Confidence: 95},
+		{Version: "denoised", Text: "ВКУПНО 708,5О", Confidence: 64},
+	}
+	best := bestResult(results)
+	if assert.NotNil(t, best) {
+		assert.Equal(t, "enhanced", best.Version)
+	}
+
+	best.Text = "changed"
+	assert.Equal(t, "ВКУПНО 708,50", results[1].Text)
+
+	assert.Nil(t, bestResult(results[:1]))
+	assert.Nil(t, bestResult([]OCRResult{{Version: "enhanced", Text: ""}}))
+}
+
+func TestConsensusResult(t *testing.T) {
+	results := []OCRResult{
+		{Version: "qr", Text: "ВКУПНО 95,OO", Confidence: 100},
+		{Version: "enhanced", Text: "ТИНЕКС\nВКУПН0 95,0O\nДДВ 18%", Confidence: 80},
+		{Version: "dilated", Text: "ТИНЕКС\nВКУПНО 95,00", Confidence: 70},
+		{Version: "denoised", Text: "ТNНЕКС\n  ВКУПНО   95,00\nДДВ 18%", Confidence: 60},
+		{Version: "sharpened", Text: "", Confidence: 99},
+	}
+	consensus := consensusResult(results)
+	if assert.NotNil(t, consensus) {
+		assert.Equal(t, "consensus", consensus.Version)
+		assert.Equal(t, "ТИНЕКС\nВКУПНО 95,00\nДДВ 18%", consensus.Text)
+		assert.Equal(t, 80.0, consensus.Confidence)
+	}
+
+	assert.Nil(t, consensusResult([]OCRResult{{Version: "qr", Text: "payload"}}))
+}
+
+func TestClosestLine(t *testing.T) {
+	line, ok := closestLine("ВКУПНО 95,00", []string{"ДДВ 18%", "ВКУПН0 95,0O", "ТИНЕКС"})
+	assert.True(t, ok)
+	assert.Equal(t, "ВКУПН0 95,0O", line)
+
+	_, ok = closestLine("ВКУПНО", []string{"ВКУ"})
+	assert.False(t, ok, "three of six characters differ")
+	line, ok = closestLine("ВКУПНО", []string{"ВКУП"})
+	assert.True(t, ok, "two of six characters differ")
+	assert.Equal(t, "ВКУП", line)
+
+	_, ok = closestLine("ВКУПНО", nil)
+	assert.False(t, ok)
+}
+
+func TestMeanConfidence(t *testing.T) {
+	words := []gosseract.BoundingBox{
+		{Word: "ВКУПНО", Confidence: 90},
+		{Word: " ", Confidence: 0},
+		{Word: "95,00", Confidence: 70},
+		{Word: "", Confidence: 5},
+	}
+	assert.Equal(t, 80.0, meanConfidence(words))
+	assert.Equal(t, 0.0, meanConfidence(nil))
+}
+
+func TestCharacterErrorRate(t *testing.T) {
+	assert.Equal(t, 0.0, CharacterErrorRate("ВКУПНО 95,00", "ВКУПНО   95,00\n"))
+	assert.InDelta(t, 2.0/12, CharacterErrorRate("ВКУПНО 95,00", "BКУПНО 96,00"), 1e-9)
+	assert.Equal(t, 1.0, CharacterErrorRate("ВКУПНО", ""))
+	assert.Equal(t, 1.0, CharacterErrorRate("", "noise"))
+	assert.Equal(t, 0.0, CharacterErrorRate("", ""))
+}
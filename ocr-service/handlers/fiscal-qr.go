@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"fmt"
+	"image"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// FiscalReceipt is what the QR code of a fiscal receipt says. Fields the code does not carry
+// are empty, Payload is always the raw content.
+type FiscalReceipt struct {
+	Payload       string `json:"payload"`
+	Total         string `json:"total,omitempty"`          // decimal with a dot, e.g. "708.50"
+	Date          string `json:"date,omitempty"`           // local time of the receipt, 2006-01-02T15:04:05
+	TaxNumber     string `json:"tax_number,omitempty"`     // ЕДБ of the shop, e.g. MK4030996116744
+	ReceiptNumber string `json:"receipt_number,omitempty"` // number of the receipt on the fiscal device
+	DeviceId      string `json:"device_id,omitempty"`      // id of the fiscal device
+}
+
+func performQRDecode(img image.Image) *OCRResult {
+	payload, err := decodeQR(img)
+	if err != nil {
+		return nil
+	}
+	fiscal := parseFiscalQR(payload)
+
+	return &OCRResult{
+		Version:     "qr",
+		Text:        payload,
+		Description: "QR code of the fiscal receipt",
+		Fiscal:      &fiscal,
+	}
+}
+
+// decodeQR reads the first QR code of the image. Photos of receipts are often too large or too
+// soft for the detector, a smaller sharpened copy is tried as well.
+func decodeQR(img image.Image) (string, error) {
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER:    true,
+		gozxing.DecodeHintType_CHARACTER_SET: "UTF-8",
+	}
+
+	candidates := []image.Image{img}
+	if img.Bounds().Dx() > 1600 {
+		candidates = append(candidates, imaging.Resize(img, 1600, 0, imaging.Lanczos))
+	}
+	candidates = append(candidates, imaging.Sharpen(imaging.Grayscale(img), 1.5))
+
+	var lastErr error
+	for _, candidate := range candidates {
+		bitmap, err := gozxing.NewBinaryBitmapFromImage(candidate)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if text := strings.TrimSpace(result.GetText()); text != "" {
+			return text, nil
+		}
+	}
+	return "", fmt.Errorf("no QR code found: %v", lastErr)
+}
+
+var (
+	fiscalTaxNumber = regexp.MustCompile(`^(?:MK|МК)?\d{13}$`)
+	fiscalAmount    = regexp.MustCompile(`^(?:\d{1,3}(?:[.,' \x{a0}]\d{3})+|\d+)[.,]\d{1,2}$`)
+	fiscalDecimal   = regexp.MustCompile(`^\d+(?:\.\d{1,2})?$`)
+	fiscalInteger   = regexp.MustCompile(`^\d{1,12}$`)
+	fiscalTime      = regexp.MustCompile(`^\d{1,2}:\d{2}(?::\d{2})?$`)
+	fiscalSeparator = regexp.MustCompile(`[;|\n\r\t]+`)
+
+	fiscalDateLayouts = []string{
+		"02.01.2006 15:04:05", "02.01.2006 15:04", "02.01.2006",
+		"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02",
+		"20060102150405", "20060102",
+	}
+
+	// the keys fiscal devices and the URLs of the tax office use for the fields
+	fiscalKeys = map[string][]string{
+		"total":          {"total", "amount", "iznos", "износ", "vkupno", "вкупно", "suma", "сума", "tot", "ukupno"},
+		"date":           {"date", "datetime", "datum", "датум", "data", "дата", "dt"},
+		"time":           {"time", "vreme", "време", "cas", "час"},
+		"tax_number":     {"edb", "едб", "tin", "taxid", "pib"},
+		"receipt_number": {"receipt", "number", "no", "br", "broj", "број", "smetka", "сметка", "racun", "fn", "sn", "invoice"},
+		"device_id":      {"device", "fu", "ufn", "kasa", "каса", "idfu", "esir"},
+	}
+)
+
+// fiscalField returns the field a key stands for.
+func fiscalField(key string) string {
+	key = strings.ToLower(strings.Trim(key, " _-."))
+	for field, keys := range fiscalKeys {
+		for _, k := range keys {
+			if key == k {
+				return field
+			}
+		}
+	}
+	return ""
+}
+
+// parseFiscalQR reads the fields of a fiscal QR code. Devices lay the payload out in different
+// ways, as a link to the tax office, as key=value pairs or as bare values separated by
+// semicolons, so named fields are taken by their key and bare values by their shape. A field
+// goes to the first key that claims it with a value of the right shape, the query of a link is
+// walked in the order of its keys.
+func parseFiscalQR(payload string) FiscalReceipt {
+	receipt := FiscalReceipt{Payload: payload}
+	var named [][2]string
+	var bare []string
+
+	if u, err := url.Parse(payload); err == nil && u.Scheme != "" && u.Host != "" {
+		query := u.Query()
+		keys := make([]string, 0, len(query))
+		for key := range query {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, value := range query[key] {
+				named = append(named, [2]string{key, value})
+			}
+		}
+		for _, segment := range strings.Split(u.Path, "/") {
+			bare = append(bare, segment)
+		}
+	} else {
+		for _, token := range fiscalSeparator.Split(payload, -1) {
+			for _, part := range strings.Split(token, "&") {
+				if key, value, ok := strings.Cut(part, "="); ok {
+					named = append(named, [2]string{key, value})
+				} else {
+					bare = append(bare, part)
+				}
+			}
+		}
+	}
+
+	var date, clock string
+	for _, pair := range named {
+		value := strings.TrimSpace(pair[1])
+		if value == "" {
+			continue
+		}
+		switch fiscalField(pair[0]) {
+		case "total":
+			if receipt.Total == "" {
+				receipt.Total = normalizeFiscalAmount(value)
+			}
+		case "date":
+			if date == "" && parseFiscalDate(value) != nil {
+				date = value
+			}
+		case "time":
+			if clock == "" && fiscalTime.MatchString(value) {
+				clock = value
+			}
+		case "tax_number":
+			if receipt.TaxNumber == "" && fiscalTaxNumber.MatchString(normalizeFiscalTaxNumber(value)) {
+				receipt.TaxNumber = normalizeFiscalTaxNumber(value)
+			}
+		case "receipt_number":
+			if receipt.ReceiptNumber == "" {
+				receipt.ReceiptNumber = value
+			}
+		case "device_id":
+			if receipt.DeviceId == "" {
+				receipt.DeviceId = value
+			}
+		}
+	}
+
+	// the VAT may be printed too, a bare total is the largest amount
+	largest, bareTotal := 0.0, ""
+	for _, value := range bare {
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+		case receipt.TaxNumber == "" && fiscalTaxNumber.MatchString(value):
+			receipt.TaxNumber = normalizeFiscalTaxNumber(value)
+		case date == "" && parseFiscalDate(value) != nil:
+			date = value
+		case clock == "" && fiscalTime.MatchString(value):
+			clock = value
+		case fiscalAmount.MatchString(value):
+			normalized := normalizeFiscalAmount(value)
+			if amount, err := strconv.ParseFloat(normalized, 64); err == nil && amount > largest {
+				largest, bareTotal = amount, normalized
+			}
+		case receipt.ReceiptNumber == "" && fiscalInteger.MatchString(value):
+			receipt.ReceiptNumber = value
+		}
+	}
+
+	if receipt.Total == "" {
+		receipt.Total = bareTotal
+	}
+	if clock != "" && !strings.Contains(date, ":") {
+		date = strings.TrimSpace(date + " " + clock)
+	}
+	if parsed := parseFiscalDate(date); parsed != nil {
+		receipt.Date = parsed.Format("2006-01-02T15:04:05")
+	}
+	return receipt
+}
+
+func parseFiscalDate(value string) *time.Time {
+	for _, layout := range fiscalDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed
+		}
+	}
+	return nil
+}
+
+// normalizeFiscalAmount writes an amount as a decimal with a dot, empty when it is not one. The
+// last dot or comma with one or two digits after it is the decimal separator, the others group
+// thousands like spaces and apostrophes do, so 1.234,50 and 1,234.50 are both 1234.50.
+func normalizeFiscalAmount(value string) string {
+	value = strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(strings.TrimSpace(value))
+	decimals := ""
+	if i := strings.LastIndexAny(value, ".,"); i >= 0 && len(value)-i-1 <= 2 {
+		value, decimals = value[:i], value[i+1:]
+	}
+	value = strings.NewReplacer(".", "", ",", "").Replace(value)
+	if decimals != "" {
+		value += "." + decimals
+	}
+	if !fiscalDecimal.MatchString(value) {
+		return ""
+	}
+	return value
+}
+
+func normalizeFiscalTaxNumber(value string) string {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	return strings.Replace(value, "МК", "MK", 1)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFiscalQR(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    FiscalReceipt
+	}{
+		{
+			name:    "link",
+			payload: "https://e-smetka.ujp.gov.mk/verify?edb=MK4030996116744&iznos=708,50&datum=14.03.2025&vreme=18:22:05&br=1234&fu=ZZ00123",
+			want: FiscalReceipt{
+				Total:         "708.50",
+				Date:          "2025-03-14T18:22:05",
+				TaxNumber:     "MK4030996116744",
+				ReceiptNumber: "1234",
+				DeviceId:      "ZZ00123",
+			},
+		},
+		{
+			name:    "key=value",
+			payload: "edb=4030996116744;total=1520.00;vat=18.00;date=2025-03-14 09:05:00;no=77;device=KS01",
+			want: FiscalReceipt{
+				Total:         "1520.00",
+				Date:          "2025-03-14T09:05:00",
+				TaxNumber:     "4030996116744",
+				ReceiptNumber: "77",
+				DeviceId:      "KS01",
+			},
+		},
+		{
+			name:    "bare values",
+			payload: "MK4030996116744;14.03.2025;18:22;112,71;708,50;000451",
+			want: FiscalReceipt{
+				Total:         "708.50",
+				Date:          "2025-03-14T18:22:00",
+				TaxNumber:     "MK4030996116744",
+				ReceiptNumber: "000451",
+			},
+		},
+		{
+			name:    "cyrillic keys",
+			payload: "ЕДБ=МК4030996116744|ИЗНОС=95,00|ДАТУМ=01.02.2025|ВРЕМЕ=07:45:10|БРОЈ=9|КАСА=ФУ-12",
+			want: FiscalReceipt{
+				Total:         "95.00",
+				Date:          "2025-02-01T07:45:10",
+				TaxNumber:     "MK4030996116744",
+				ReceiptNumber: "9",
+				DeviceId:      "ФУ-12",
+			},
+		},
+		{
+			name:    "thousands separators",
+			payload: "total=1,234.50;4030996116744;1.234,50;2.345,00;15.03.2025 12:00:00;88",
+			want: FiscalReceipt{
+				Total:         "1234.50",
+				Date:          "2025-03-15T12:00:00",
+				TaxNumber:     "4030996116744",
+				ReceiptNumber: "88",
+			},
+		},
+		{
+			name:    "bare thousands separator",
+			payload: "4030996116744;15.03.2025;1.234,50;88",
+			want: FiscalReceipt{
+				Total:         "1234.50",
+				Date:          "2025-03-15T00:00:00",
+				TaxNumber:     "4030996116744",
+				ReceiptNumber: "88",
+			},
+		},
+		{
+			name:    "tax number of the wrong shape",
+			payload: "total=10,00;tin=123;tax=18,00",
+			want:    FiscalReceipt{Total: "10.00"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.want.Payload = test.payload
+			assert.Equal(t, test.want, parseFiscalQR(test.payload))
+		})
+	}
+}
+
+func TestNormalizeFiscalAmount(t *testing.T) {
+	assert.Equal(t, "708.50", normalizeFiscalAmount("708,50"))
+	assert.Equal(t, "1234.50", normalizeFiscalAmount("1.234,50"))
+	assert.Equal(t, "1234.50", normalizeFiscalAmount("1,234.50"))
+	assert.Equal(t, "1234567.5", normalizeFiscalAmount("1 234 567,5"))
+	assert.Equal(t, "1234", normalizeFiscalAmount("1.234"))
+	assert.Equal(t, "", normalizeFiscalAmount("18%"))
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otiai10/gosseract/v2"
+)
+
+// healthTTL is how long a Tesseract check holds, loading every traineddata takes a while and
+// the health check of docker-compose comes every few seconds.
+const healthTTL = time.Minute
+
+// tesseractHealth is the last check of Tesseract and the traineddata of the pipelines.
+var tesseractHealth struct {
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// checkTesseract loads every language the pipelines use and reads a blank page with it, at
+// most once every healthTTL.
+func checkTesseract() error {
+	tesseractHealth.mu.Lock()
+	defer tesseractHealth.mu.Unlock()
+	if time.Since(tesseractHealth.checked) < healthTTL {
+		return tesseractHealth.err
+	}
+
+	tesseractHealth.err = loadLanguages(pipelineLanguages())
+	tesseractHealth.checked = time.Now()
+	return tesseractHealth.err
+}
+
+func pipelineLanguages() []string {
+	seen := map[string]bool{}
+	var languages []string
+	for _, pipeline := range pipelines.Pipelines {
+		if !seen[pipeline.Language] {
+			seen[pipeline.Language] = true
+			languages = append(languages, pipeline.Language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+func loadLanguages(languages []string) error {
+	blank := image.NewGray(image.Rect(0, 0, 64, 32))
+	for i := range blank.Pix {
+		blank.Pix[i] = 255
+	}
+	page, err := imageToBytes(blank, "png")
+	if err != nil {
+		return err
+	}
+
+	for _, language := range languages {
+		client := gosseract.NewClient()
+		client.SetLanguage(strings.Split(language, "+")...)
+		client.SetImageFromBytes(page)
+		_, err := client.Text()
+		client.Close()
+		if err != nil {
+			return fmt.Errorf("Tesseract cannot load %s: %w", language, err)
+		}
+	}
+	return nil
+}
+
+// HealthzHandler answers 200 while Tesseract loads the traineddata of every pipeline, and 503
+// with the error once it does not.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	if err := checkTesseract(); err != nil {
+		writeStatus(w, http.StatusServiceUnavailable, map[string]any{"status": "failing", "error": err.Error()})
+		return
+	}
+	writeStatus(w, http.StatusOK, map[string]any{"status": "ok", "languages": pipelineLanguages()})
+}
+
+// ReadyzHandler answers 200 while the service takes requests: Tesseract is healthy and the
+// request queue has room, a full one would answer 429 anyway.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if err := checkTesseract(); err != nil {
+		writeStatus(w, http.StatusServiceUnavailable, map[string]any{"status": "not ready", "error": err.Error()})
+		return
+	}
+	if requests.full() {
+		writeStatus(w, http.StatusServiceUnavailable, map[string]any{"status": "not ready", "error": "the request queue is full"})
+		return
+	}
+	writeStatus(w, http.StatusOK, map[string]any{"status": "ready", "queued": requests.length()})
+}
+
+func writeStatus(w http.ResponseWriter, status int, body map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otiai10/gosseract/v2"
+
+	"ocr-service/preprocess"
+)
+
+// Box is a word or a line a variant read and where it is on the uploaded image, turned the way
+// its EXIF says.
+type Box struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"` // mean of the words for a line
+	Left       int     `json:"left"`
+	Top        int     `json:"top"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Block      int     `json:"block"`
+	Paragraph  int     `json:"paragraph"`
+	Line       int     `json:"line"`
+	Word       int     `json:"word,omitempty"` // 0 for a line
+}
+
+func (b Box) rect() image.Rectangle {
+	return image.Rect(b.Left, b.Top, b.Left+b.Width, b.Top+b.Height)
+}
+
+func boxAt(r image.Rectangle) Box {
+	return Box{Left: r.Min.X, Top: r.Min.Y, Width: r.Dx(), Height: r.Dy()}
+}
+
+// layoutWords places the words Tesseract found on the prepared image back on the upload.
+func layoutWords(words []gosseract.BoundingBox, mapping preprocess.Mapping) []Box {
+	var boxes []Box
+	for _, word := range words {
+		if strings.TrimSpace(word.Word) == "" {
+			continue
+		}
+		box := boxAt(mapping.Rect(word.Box))
+		box.Text = word.Word
+		box.Confidence = word.Confidence
+		box.Block, box.Paragraph, box.Line, box.Word = word.BlockNum, word.ParNum, word.LineNum, word.WordNum
+		boxes = append(boxes, box)
+	}
+	return boxes
+}
+
+// layoutLines joins the words of every line, in reading order.
+func layoutLines(words []Box) []Box {
+	type key struct{ block, paragraph, line int }
+	lines := map[key]*Box{}
+	var order []key
+	counts := map[key]int{}
+	for _, word := range words {
+		k := key{word.Block, word.Paragraph, word.Line}
+		line, ok := lines[k]
+		if !ok {
+			box := boxAt(word.rect())
+			box.Block, box.Paragraph, box.Line = word.Block, word.Paragraph, word.Line
+			line = &box
+			lines[k] = line
+			order = append(order, k)
+		} else {
+			merged := boxAt(line.rect().Union(word.rect()))
+			line.Left, line.Top, line.Width, line.Height = merged.Left, merged.Top, merged.Width, merged.Height
+			line.Text += " "
+		}
+		line.Text += word.Text
+		line.Confidence += word.Confidence
+		counts[k]++
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if a.block != b.block {
+			return a.block < b.block
+		}
+		if a.paragraph != b.paragraph {
+			return a.paragraph < b.paragraph
+		}
+		return a.line < b.line
+	})
+	result := make([]Box, 0, len(order))
+	for _, k := range order {
+		line := *lines[k]
+		line.Confidence /= float64(counts[k])
+		result = append(result, line)
+	}
+	return result
+}
+
+// writeTSV writes the layout the way `tesseract ... tsv` does: a row for every page, one for
+// every line (level 4) followed by its words (level 5). Pages of a PDF text layer have no
+// layout and only their row.
+func writeTSV(w io.Writer, pages []OCRPage) {
+	fmt.Fprintln(w, "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext")
+	for _, page := range pages {
+		fmt.Fprintf(w, "1\t%d\t0\t0\t0\t0\t0\t0\t%d\t%d\t-1\t\n", page.Page, page.Width, page.Height)
+		if page.Best == nil {
+			continue
+		}
+		for _, line := range page.Best.Lines {
+			fmt.Fprintf(w, "4\t%d\t%d\t%d\t%d\t0\t%d\t%d\t%d\t%d\t-1\t\n",
+				page.Page, line.Block, line.Paragraph, line.Line, line.Left, line.Top, line.Width, line.Height)
+			for _, word := range page.Best.Words {
+				if word.Block != line.Block || word.Paragraph != line.Paragraph || word.Line != line.Line {
+					continue
+				}
+				text := strings.NewReplacer("\t", " ", "\n", " ").Replace(word.Text)
+				fmt.Fprintf(w, "5\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.2f\t%s\n",
+					page.Page, word.Block, word.Paragraph, word.Line, word.Word, word.Left, word.Top, word.Width, word.Height, word.Confidence, text)
+			}
+		}
+	}
+}
+
+// writeHOCR writes the layout as hOCR, an ocr_page for every page with the lines as ocr_line
+// and the words as ocrx_word with their bbox and x_wconf.
+func writeHOCR(w io.Writer, pages []OCRPage) {
+	bbox := func(b Box) string {
+		return fmt.Sprintf("bbox %d %d %d %d", b.Left, b.Top, b.Left+b.Width, b.Top+b.Height)
+	}
+
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="mk" lang="mk">
+<head>
+<title></title>
+<meta http-equiv="Content-Type" content="text/html;charset=utf-8"/>
+<meta name="ocr-system" content="tesseract"/>
+<meta name="ocr-capabilities" content="ocr_page ocr_line ocrx_word"/>
+</head>
+<body>
+`)
+	for _, page := range pages {
+		fmt.Fprintf(w, "<div class=\"ocr_page\" id=\"page_%d\" title=\"image %s; %s; ppageno %d\">\n",
+			page.Page, html.EscapeString(strconv.Quote(page.Source)), bbox(Box{Width: page.Width, Height: page.Height}), page.Page-1)
+		if page.Best != nil {
+			for i, line := range page.Best.Lines {
+				fmt.Fprintf(w, "<span class=\"ocr_line\" id=\"line_%d_%d\" title=\"%s\">", page.Page, i+1, bbox(line))
+				n := 0
+				for _, word := range page.Best.Words {
+					if word.Block != line.Block || word.Paragraph != line.Paragraph || word.Line != line.Line {
+						continue
+					}
+					n++
+					if n > 1 {
+						fmt.Fprint(w, " ")
+					}
+					fmt.Fprintf(w, "<span class=\"ocrx_word\" id=\"word_%d_%d_%d\" title=\"%s; x_wconf %.0f\">%s</span>",
+						page.Page, i+1, n, bbox(word), word.Confidence, html.EscapeString(word.Text))
+				}
+				fmt.Fprint(w, "</span>\n")
+			}
+		}
+		fmt.Fprint(w, "</div>\n")
+	}
+	fmt.Fprint(w, "</body>\n</html>\n")
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receiptWords are four words of two lines, the second line's first word comes first.
+var receiptWords = []Box{
+	{Text: "ДДВ", Confidence: 80, Left: 10, Top: 60, Width: 40, Height: 20, Block: 1, Paragraph: 1, Line: 2, Word: 1},
+	{Text: "ВКУПНО", Confidence: 90, Left: 10, Top: 20, Width: 60, Height: 20, Block: 1, Paragraph: 1, Line: 1, Word: 1},
+	{Text: "95,00", Confidence: 70, Left: 80, Top: 22, Width: 50, Height: 20, Block: 1, Paragraph: 1, Line: 1, Word: 2},
+	{Text: "<R&D>", Confidence: 60, Left: 60, Top: 58, Width: 30, Height: 20, Block: 1, Paragraph: 1, Line: 2, Word: 2},
+}
+
+func receiptPages() []OCRPage {
+	best := OCRResult{Version: "enhanced", Words: receiptWords, Lines: layoutLines(receiptWords)}
+	return []OCRPage{
+		{Page: 1, Source: `a<b>&"c".jpg`, Width: 200, Height: 100, Results: []OCRResult{best}, Best: &best},
+		{Page: 2, Source: "bill.pdf#1", Results: []OCRResult{{Version: "pdf_text", Text: "ВКУПНО 95,00"}}},
+	}
+}
+
+func TestLayoutLines(t *testing.T) {
+	assert.Equal(t, []Box{
+		{Text: "ВКУПНО 95,00", Confidence: 80, Left: 10, Top: 20, Width: 120, Height: 22, Block: 1, Paragraph: 1, Line: 1},
+		{Text: "ДДВ <R&D>", Confidence: 70, Left: 10, Top: 58, Width: 80, Height: 22, Block: 1, Paragraph: 1, Line: 2},
+	}, layoutLines(receiptWords))
+
+	lines := layoutLines([]Box{
+		{Text: "b", Block: 2, Paragraph: 1, Line: 1},
+		{Text: "a", Block: 1, Paragraph: 2, Line: 1},
+		{Text: "c", Block: 1, Paragraph: 1, Line: 3},
+	})
+	var texts []string
+	for _, line := range lines {
+		texts = append(texts, line.Text)
+	}
+	assert.Equal(t, []string{"c", "a", "b"}, texts)
+	assert.Empty(t, layoutLines(nil))
+}
+
+func TestWriteTSV(t *testing.T) {
+	var out bytes.Buffer
+	writeTSV(&out, receiptPages())
+	assert.Equal(t, strings.Join([]string{
+		"level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext",
+		"1\t1\t0\t0\t0\t0\t0\t0\t200\t100\t-1\t",
+		"4\t1\t1\t1\t1\t0\t10\t20\t120\t22\t-1\t",
+		"5\t1\t1\t1\t1\t1\t10\t20\t60\t20\t90.00\tВКУПНО",
+		"5\t1\t1\t1\t1\t2\t80\t22\t50\t20\t70.00\t95,00",
+		"4\t1\t1\t1\t2\t0\t10\t58\t80\t22\t-1\t",
+		"5\t1\t1\t1\t2\t1\t10\t60\t40\t20\t80.00\tДДВ",
+		"5\t1\t1\t1\t2\t2\t60\t58\t30\t20\t60.00\t<R&D>",
+		"1\t2\t0\t0\t0\t0\t0\t0\t0\t0\t-1\t",
+	}, "\n")+"\n", out.String())
+}
+
+func TestWriteHOCR(t *testing.T) {
+	var out bytes.Buffer
+	writeHOCR(&out, receiptPages())
+	hocr := out.String()
+
+	assert.Contains(t, hocr, `<div class="ocr_page" id="page_1" title="image &#34;a&lt;b&gt;&amp;\&#34;c\&#34;.jpg&#34;; bbox 0 0 200 100; ppageno 0">`)
+	assert.Contains(t, hocr, `<span class="ocr_line" id="line_1_1" title="bbox 10 20 130 42">`+
+		`<span class="ocrx_word" id="word_1_1_1" title="bbox 10 20 70 40; x_wconf 90">ВКУПНО</span> `+
+		`<span class="ocrx_word" id="word_1_1_2" title="bbox 80 22 130 42; x_wconf 70">95,00</span></span>`)
+	assert.Contains(t, hocr, `<span class="ocrx_word" id="word_1_2_2" title="bbox 60 58 90 78; x_wconf 60">&lt;R&amp;D&gt;</span>`)
+	assert.Contains(t, hocr, "<div class=\"ocr_page\" id=\"page_2\" title=\"image &#34;bill.pdf#1&#34;; bbox 0 0 0 0; ppageno 1\">\n</div>")
+
+	decoder := xml.NewDecoder(strings.NewReader(hocr))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			break
+		}
+	}
+}
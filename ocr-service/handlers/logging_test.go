@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRequestID(t *testing.T) {
+	var output bytes.Buffer
+	previous := Logger
+	Logger = slog.New(requestIDHandler{slog.NewJSONHandler(&output, nil)})
+	defer func() { Logger = previous }()
+
+	var seen string
+	handler := WithRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/ocr", nil)
+	r.Header.Set("X-Request-ID", "upload-42")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, "upload-42", seen)
+	assert.Equal(t, "upload-42", w.Header().Get("X-Request-ID"))
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &line))
+	assert.Equal(t, "upload-42", line["request_id"])
+	assert.Equal(t, float64(http.StatusTeapot), line["status"])
+
+	// without one the request gets its own
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ocr", nil))
+	assert.Len(t, w.Header().Get("X-Request-ID"), 32)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The metrics of the service, served on /metrics for Prometheus.
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ocr_request_duration_seconds",
+		Help:    "Time to answer an OCR request, by cache result.",
+		Buckets: []float64{0.05, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80},
+	}, []string{"cache"})
+	pipelineDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ocr_pipeline_duration_seconds",
+		Help:    "Time a pipeline takes for one page, preprocessing and Tesseract together.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 16, 32},
+	}, []string{"pipeline"})
+	pipelineConfidence = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ocr_confidence",
+		Help:    "Mean word confidence of what a pipeline read, 0 to 100.",
+		Buckets: prometheus.LinearBuckets(10, 10, 10),
+	}, []string{"pipeline"})
+	uploadBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr_upload_bytes",
+		Help:    "Size of the uploaded files of a request together.",
+		Buckets: prometheus.ExponentialBuckets(64<<10, 2, 10), // 64 KB to 32 MB
+	})
+	imageMegapixels = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ocr_image_megapixels",
+		Help:    "Size of the pages read with OCR, after decoding.",
+		Buckets: []float64{0.5, 1, 2, 4, 8, 12, 16, 24, 48},
+	})
+	pagesRead = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ocr_pages_total",
+		Help: "Pages read, by how: ocr for images, pdf_text for the text layer of a PDF.",
+	}, []string{"source"})
+	errorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ocr_errors_total",
+		Help: "Errors, by where they happened: upload, tesseract, busy, cache.",
+	}, []string{"stage"})
+	queuedRequests = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "ocr_queued_requests",
+		Help: "Requests being read or waiting for a Tesseract worker.",
+	}, func() float64 { return float64(requests.length()) })
+)
+
+// MetricsHandler serves the metrics in the Prometheus text format.
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
@@ -2,302 +2,259 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"image"
-	"image/color"
 	"image/jpeg"
 	"image/png"
-	"log"
 	_ "math"
+	"mime/multipart"
 	"net/http"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/disintegration/imaging"
-	"github.com/jdeng/goheif"
 	"github.com/otiai10/gosseract/v2"
+
+	"ocr-service/preprocess"
 )
 
 type OCRResult struct {
-	Version     string  `json:"version"`
-	Text        string  `json:"text"`
-	Confidence  float64 `json:"confidence,omitempty"`
-	Description string  `json:"description"`
+	Version     string         `json:"version"`
+	Text        string         `json:"text"`
+	Confidence  float64        `json:"confidence,omitempty"`
+	Description string         `json:"description"`
+	Fiscal      *FiscalReceipt `json:"fiscal,omitempty"` // only on the qr variant
+	Words       []Box          `json:"words,omitempty"`  // only with format=json-boxes
+	Lines       []Box          `json:"lines,omitempty"`  // only with format=json-boxes
 }
 
 type OCRResponse struct {
+	// Results and Best are those of the first page, what clients sending one image read.
 	Results []OCRResult `json:"results"`
+	// Best is the text to use: the most confident variant, or in consensus mode the lines the
+	// variants agree on. It is missing when no variant read any text.
+	Best  *OCRResult `json:"best,omitempty"`
+	Pages []OCRPage  `json:"pages"`
+	Text  string     `json:"text"` // the best text of every page, in order
 }
 
+// OcrHandler reads the "image" files of the form, photos or PDFs, as the pages of one
+// document, with the pipelines the request picks (see requestPipelines). The format
+// parameter picks the answer: text (the default) and json-boxes answer with an OCRResponse,
+// the latter with the words and lines of every variant and where they are; hocr and tsv
+// answer with the layout of the best variant of every page. An upload read before with the
+// same pipelines comes from the cache, X-Cache says HIT or MISS.
 func OcrHandler(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // 10 MB
+	started := time.Now()
+	r.Body = http.MaxBytesReader(w, r.Body, 32<<20) // 32 MB, a receipt may come in several photos
 
-	err := r.ParseMultipartForm(10 << 20)
+	err := r.ParseMultipartForm(32 << 20)
 	if err != nil {
+		errorsTotal.WithLabelValues("upload").Inc()
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 
-	file, header, err := r.FormFile("image")
+	format := r.FormValue("format")
+	switch format {
+	case "":
+		format = "text"
+	case "text", "json-boxes", "hocr", "tsv":
+	default:
+		http.Error(w, "Unknown format, use text, hocr, tsv or json-boxes", http.StatusBadRequest)
+		return
+	}
+	consensus, _ := strconv.ParseBool(r.FormValue("consensus"))
+	selected, err := requestPipelines(r)
 	if err != nil {
-		http.Error(w, "Failed to get image", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	var img image.Image
+	files := r.MultipartForm.File["image"]
+	var size int64
+	for _, header := range files {
+		size += header.Size
+	}
+	uploadBytes.Observe(float64(size))
 
-	if ext == ".heic" || ext == ".heif" {
-		img, err = goheif.Decode(file)
-		if err != nil {
-			http.Error(w, "Failed to decode HEIC image", http.StatusBadRequest)
+	key, err := cacheKey(files, selected, consensus)
+	if err != nil {
+		errorsTotal.WithLabelValues("upload").Inc()
+		http.Error(w, "Failed to read image", http.StatusBadRequest)
+		return
+	}
+	cacheResult := "hit"
+	pages, hit := cache.get(key)
+	if !hit {
+		cacheResult = "miss"
+		// only reading an upload waits for a worker, what is cached is answered right away
+		if !requests.tryEnter() {
+			errorsTotal.WithLabelValues("busy").Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(requests.retryAfter()))
+			http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
 			return
 		}
-	} else {
-		img, _, err = image.Decode(file)
+		entered := time.Now()
+		pages, err = readPages(r.Context(), files, selected, consensus)
+		requests.leave(entered)
 		if err != nil {
-			http.Error(w, "Failed to decode image", http.StatusBadRequest)
+			errorsTotal.WithLabelValues("upload").Inc()
+			Logger.WarnContext(r.Context(), "upload refused", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		// a document nothing was read from may be a pass that failed, it is read again next time
+		if DocumentText(pages) != "" {
+			cache.put(key, pages)
+		}
 	}
+	w.Header().Set("X-Cache", strings.ToUpper(cacheResult))
+	defer func() {
+		requestDuration.WithLabelValues(cacheResult).Observe(time.Since(started).Seconds())
+	}()
 
-	results := []OCRResult{
-		//performOCRBasic(img),
-		performOCREnhanced(img),
-		//performOCRHighContrast(img),
-		performOCRDilated(img),
-		performOCRWithDenoising(img),
-		//performOCRScaled(img),
+	switch format {
+	case "hocr":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writeHOCR(w, pages)
+		return
+	case "tsv":
+		w.Header().Set("Content-Type", "text/tab-separated-values; charset=utf-8")
+		writeTSV(w, pages)
+		return
+	case "text":
+		for i := range pages {
+			for j := range pages[i].Results {
+				pages[i].Results[j].Words, pages[i].Results[j].Lines = nil, nil
+			}
+			if pages[i].Best != nil {
+				pages[i].Best.Words, pages[i].Best.Lines = nil, nil
+			}
+		}
 	}
 
-	response := OCRResponse{Results: results}
+	response := OCRResponse{
+		Results: pages[0].Results,
+		Best:    pages[0].Best,
+		Pages:   pages,
+		Text:    DocumentText(pages),
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func performOCRBasic(img image.Image) OCRResult {
-	grayscale := imaging.Grayscale(img)
-	sharpened := imaging.Sharpen(grayscale, 2.0)
-
-	text := performOCRWithConfig(sharpened, "mkd", map[string]string{})
-
-	return OCRResult{
-		Version:     "basic",
-		Text:        text,
-		Description: "Basic grayscale + sharpen processing",
-	}
-}
-
-func performOCREnhanced(img image.Image) OCRResult {
-	grayscale := imaging.Grayscale(img)
-	contrasted := imaging.AdjustContrast(grayscale, 20)
-	sharpened := imaging.Sharpen(contrasted, 1.5)
-	brightened := imaging.AdjustBrightness(sharpened, 5)
-
-	text := performOCRWithConfig(brightened, "mkd", map[string]string{})
-
-	return OCRResult{
-		Version:     "enhanced",
-		Text:        text,
-		Description: "Enhanced contrast + brightness + character whitelist",
+// readPages reads every page of the upload.
+func readPages(ctx context.Context, files []*multipart.FileHeader, selected []Pipeline, consensus bool) ([]OCRPage, error) {
+	inputs, err := readUpload(ctx, files)
+	if err != nil {
+		return nil, err
 	}
+	return readInputs(ctx, inputs, selected, consensus), nil
 }
 
-func performOCRHighContrast(img image.Image) OCRResult {
-	grayscale := imaging.Grayscale(img)
-	thresholded := applyThreshold(grayscale, 0.6)
-
-	text := performOCRWithConfig(thresholded, "eng+mkd", map[string]string{})
-
-	return OCRResult{
-		Version:     "high_contrast",
-		Text:        text,
-		Description: "High contrast thresholding",
+// performOCR reads the image with every pipeline at once and looks for the QR code of a fiscal
+// receipt meanwhile. The Tesseract pool bounds how many passes run together.
+func performOCR(ctx context.Context, img image.Image, selected []Pipeline) []OCRResult {
+	results := make([]OCRResult, len(selected))
+	var qr *OCRResult
+	var wg sync.WaitGroup
+	for i, pipeline := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runPipeline(ctx, img, pipeline)
+		}()
 	}
-}
-
-func performOCRDilated(img image.Image) OCRResult {
-	grayscale := imaging.Grayscale(img)
-	contrasted := imaging.AdjustContrast(grayscale, 15)
-	dilated := applyDilationParallel(contrasted, 1)
-
-	text := performOCRWithConfig(dilated, "mkd", map[string]string{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		qr = performQRDecode(img)
+	}()
+	wg.Wait()
 
-	return OCRResult{
-		Version:     "dilated",
-		Text:        text,
-		Description: "Contrast + morphological dilation",
+	// a receipt without a readable QR code is read from its text alone
+	if qr != nil {
+		results = append(results, *qr)
 	}
+	return results
 }
 
-func performOCRWithDenoising(img image.Image) OCRResult {
-	grayscale := imaging.Grayscale(img)
-
-	denoised := imaging.Blur(grayscale, 0.5)
-
-	sharpened := imaging.Sharpen(denoised, 3.0)
-
-	contrasted := imaging.AdjustContrast(sharpened, 25)
-
-	text := performOCRWithConfig(contrasted, "mkd", map[string]string{})
+// runPipeline takes the steps of the pipeline on the image and reads what they leave.
+func runPipeline(ctx context.Context, img image.Image, pipeline Pipeline) OCRResult {
+	started := time.Now()
+	prepared, mapping := pipeline.Steps.Apply(img)
+	text, confidence, words := performOCRWithConfig(ctx, prepared, pipeline)
+	pipelineDuration.WithLabelValues(pipeline.Name).Observe(time.Since(started).Seconds())
+	pipelineConfidence.WithLabelValues(pipeline.Name).Observe(confidence)
 
 	return OCRResult{
-		Version:     "denoised",
+		Version:     pipeline.Name,
 		Text:        text,
-		Description: "Gaussian blur denoising + aggressive sharpening",
-	}
+		Confidence:  confidence,
+		Description: pipeline.Description,
+	}.withLayout(words, mapping)
 }
 
-func performOCRScaled(img image.Image) OCRResult {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	scaled := imaging.Resize(img, width*2, height*2, imaging.Lanczos)
-
-	grayscale := imaging.Grayscale(scaled)
-	sharpened := imaging.Sharpen(grayscale, 1.2)
-
-	text := performOCRWithConfig(sharpened, "mkd", map[string]string{})
-
-	return OCRResult{
-		Version:     "scaled_2x",
-		Text:        text,
-		Description: "2x upscaled with Lanczos resampling",
-	}
+// withLayout places the words Tesseract found on the prepared image on the upload.
+func (r OCRResult) withLayout(words []gosseract.BoundingBox, mapping preprocess.Mapping) OCRResult {
+	r.Words = layoutWords(words, mapping)
+	r.Lines = layoutLines(r.Words)
+	return r
 }
 
-func performOCRWithConfig(img image.Image, language string, config map[string]string) string {
+// performOCRWithConfig returns the text Tesseract read, its mean word confidence, 0 to 100,
+// and the words with where they are.
+func performOCRWithConfig(ctx context.Context, img image.Image, pipeline Pipeline) (string, float64, []gosseract.BoundingBox) {
 	imgBytes, err := imageToBytes(img, "png")
 	if err != nil {
-		log.Printf("Failed to convert image to bytes: %v", err)
-		return ""
+		errorsTotal.WithLabelValues("tesseract").Inc()
+		Logger.ErrorContext(ctx, "failed to encode the image for Tesseract", "pipeline", pipeline.Name, "error", err)
+		return "", 0, nil
 	}
 
-	client := gosseract.NewClient()
-	defer client.Close()
-
-	client.SetLanguage("mkd")
-	client.SetPageSegMode(gosseract.PSM_SINGLE_BLOCK)
-	client.SetVariable("tessedit_char_whitelist",
-		"АБВГДЕЖЗИЈКЛЉМНЊОПРСТЌУФХЦЧЏШабвгдежзијклљмнњопрстќуфхцчџш0123456789.,:/-%")
-	for key, value := range config {
-		client.SetVariable(gosseract.SettableVariable(key), value)
-	}
+	client := tesseract.get(pipeline)
+	defer tesseract.put(pipeline, client)
 
 	client.SetImageFromBytes(imgBytes)
 
 	text, err := client.Text()
 	if err != nil {
-		log.Printf("OCR error: %v", err)
-		return ""
+		errorsTotal.WithLabelValues("tesseract").Inc()
+		Logger.ErrorContext(ctx, "Tesseract failed", "pipeline", pipeline.Name, "language", pipeline.Language, "error", err)
+		return "", 0, nil
 	}
 
-	return strings.TrimSpace(text)
-}
-
-func applyThreshold(img image.Image, threshold float64) image.Image {
-	bounds := img.Bounds()
-	result := image.NewGray(bounds)
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			grayColor := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
-			normalized := float64(grayColor.Y) / 255.0
-
-			var newValue uint8
-			if normalized > threshold {
-				newValue = 255
-			} else {
-				newValue = 0
-			}
-
-			result.SetGray(x, y, color.Gray{Y: newValue})
-		}
+	// the words are already recognized, this only reads them out
+	words, err := client.GetBoundingBoxesVerbose()
+	if err != nil {
+		errorsTotal.WithLabelValues("tesseract").Inc()
+		Logger.ErrorContext(ctx, "failed to read the word boxes", "pipeline", pipeline.Name, "error", err)
+		return strings.TrimSpace(text), 0, nil
 	}
 
-	return result
+	return strings.TrimSpace(text), meanConfidence(words), words
 }
 
-// Simple dilation operation
-func applyDilation(img image.Image, radius int) image.Image {
-	bounds := img.Bounds()
-	result := image.NewGray(bounds)
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			maxValue := uint8(0)
-
-			// Check neighborhood
-			for dy := -radius; dy <= radius; dy++ {
-				for dx := -radius; dx <= radius; dx++ {
-					nx, ny := x+dx, y+dy
-					if nx >= bounds.Min.X && nx < bounds.Max.X && ny >= bounds.Min.Y && ny < bounds.Max.Y {
-						grayColor := color.GrayModel.Convert(img.At(nx, ny)).(color.Gray)
-						if grayColor.Y > maxValue {
-							maxValue = grayColor.Y
-						}
-					}
-				}
-			}
-
-			result.SetGray(x, y, color.Gray{Y: maxValue})
+// meanConfidence averages the confidence of the words, empty boxes are noise Tesseract did
+// not make a word of.
+func meanConfidence(words []gosseract.BoundingBox) float64 {
+	sum, count := 0.0, 0
+	for _, word := range words {
+		if strings.TrimSpace(word.Word) == "" {
+			continue
 		}
+		sum += word.Confidence
+		count++
 	}
-
-	return result
-}
-
-func applyDilationParallel(img image.Image, radius int) image.Image {
-	bounds := img.Bounds()
-	result := image.NewGray(bounds)
-
-	numWorkers := 4
-
-	wg := &sync.WaitGroup{}
-	wg.Add(numWorkers)
-
-	stripHeight := bounds.Dy() / numWorkers
-	if stripHeight < 1 {
-		stripHeight = 1
-	}
-
-	for i := 0; i < numWorkers; i++ {
-		go func(workerID int) {
-			defer wg.Done()
-			startY := bounds.Min.Y + workerID*stripHeight
-			endY := startY + stripHeight
-			if workerID == numWorkers-1 {
-				endY = bounds.Max.Y
-			}
-
-			for y := startY; y < endY; y++ {
-				for x := bounds.Min.X; x < bounds.Max.X; x++ {
-					maxValue := uint8(0)
-
-					for dy := -radius; dy <= radius; dy++ {
-						for dx := -radius; dx <= radius; dx++ {
-							nx, ny := x+dx, y+dy
-							if nx >= bounds.Min.X && nx < bounds.Max.X && ny >= bounds.Min.Y && ny < bounds.Max.Y {
-								grayColor := color.GrayModel.Convert(img.At(nx, ny)).(color.Gray)
-								if grayColor.Y > maxValue {
-									maxValue = grayColor.Y
-								}
-							}
-						}
-					}
-					result.SetGray(x, y, color.Gray{Y: maxValue})
-				}
-			}
-		}(i)
+	if count == 0 {
+		return 0
 	}
-
-	wg.Wait()
-	return result
+	return sum / float64(count)
 }
 
 func imageToBytes(img image.Image, format string) ([]byte, error) {
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"mime/multipart"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jdeng/goheif"
+	"github.com/ledongthuc/pdf"
+
+	"ocr-service/preprocess"
+)
+
+const (
+	// maxPages bounds the pages of one request, images and PDF pages together.
+	maxPages = 20
+	// pdfDPI is what pages without a text layer are rasterized at, receipt print needs 300.
+	pdfDPI = 300
+	// minTextLayer is how much text a PDF page needs to be taken as it is, scanners put a
+	// page number or a stamp on pages that are only a picture.
+	minTextLayer = 20
+)
+
+// OCRPage is what was read from one page of the upload: an image, or a page of a PDF.
+type OCRPage struct {
+	Page    int         `json:"page"`
+	Source  string      `json:"source"` // the file, with the page for a PDF, e.g. bill.pdf#2
+	Width   int         `json:"width,omitempty"`
+	Height  int         `json:"height,omitempty"`
+	Results []OCRResult `json:"results"`
+	Best    *OCRResult  `json:"best,omitempty"`
+}
+
+// pageInput is a page to read: an image, or the text layer of a PDF page.
+type pageInput struct {
+	source string
+	img    image.Image
+	text   string
+}
+
+// upload is a file to read, its name and contents.
+type upload struct {
+	name string
+	data []byte
+}
+
+// readUpload turns the uploaded files into pages in the order they were sent, a long receipt
+// photographed in parts comes as several images.
+func readUpload(ctx context.Context, files []*multipart.FileHeader) ([]pageInput, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("Failed to get image")
+	}
+
+	uploads := make([]upload, 0, len(files))
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get image")
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read image")
+		}
+		uploads = append(uploads, upload{name: header.Filename, data: data})
+	}
+	return readFiles(ctx, uploads)
+}
+
+// readFiles turns images and PDFs into pages, in order.
+func readFiles(ctx context.Context, uploads []upload) ([]pageInput, error) {
+	var pages []pageInput
+	for _, file := range uploads {
+		data := file.data
+		if bytes.HasPrefix(data, []byte("%PDF-")) || strings.EqualFold(filepath.Ext(file.name), ".pdf") {
+			pdfPages, err := readPDF(ctx, file.name, data, maxPages-len(pages))
+			if err != nil {
+				return nil, err
+			}
+			pages = append(pages, pdfPages...)
+			continue
+		}
+
+		if len(pages) >= maxPages {
+			return nil, fmt.Errorf("More than %d pages", maxPages)
+		}
+		img, err := decodeImage(file.name, data)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, pageInput{source: file.name, img: img})
+	}
+	return pages, nil
+}
+
+// decodeImage decodes a photo and turns it the way the camera was held.
+func decodeImage(filename string, data []byte) (image.Image, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".heic" || ext == ".heif" {
+		img, err := goheif.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode HEIC image")
+		}
+		orientation := 1
+		if exif, err := goheif.ExtractExif(bytes.NewReader(data)); err == nil {
+			orientation = preprocess.Orientation(exif)
+		}
+		return preprocess.Orient(img, orientation), nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode image")
+	}
+	return preprocess.Orient(img, preprocess.Orientation(data)), nil
+}
+
+// readPDF takes the text layer of the pages that have one and rasterizes the others for OCR,
+// e-invoices are text while scanned bills are pictures.
+func readPDF(ctx context.Context, filename string, data []byte, limit int) ([]pageInput, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read PDF")
+	}
+	if reader.NumPage() > limit {
+		return nil, fmt.Errorf("More than %d pages", maxPages)
+	}
+
+	var pages []pageInput
+	for i := 1; i <= reader.NumPage(); i++ {
+		source := fmt.Sprintf("%s#%d", filename, i)
+		if text := pdfPageText(reader.Page(i)); len([]rune(strings.TrimSpace(text))) >= minTextLayer {
+			pages = append(pages, pageInput{source: source, text: text})
+			continue
+		}
+
+		img, err := rasterizePDFPage(ctx, data, i)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to rasterize page %d of the PDF: %v", i, err)
+		}
+		pages = append(pages, pageInput{source: source, img: img})
+	}
+	return pages, nil
+}
+
+// pdfPageText returns the text layer of the page line by line, top to bottom. The layer is a
+// list of glyphs with their position, glyphs on about the same height are a line and a gap
+// wider than a quarter of the font size is a space.
+func pdfPageText(page pdf.Page) (text string) {
+	if page.V.IsNull() {
+		return ""
+	}
+	defer func() {
+		if recover() != nil { // a content stream the reader cannot follow
+			text = ""
+		}
+	}()
+
+	glyphs := page.Content().Text
+	// PDF y grows upwards
+	sort.SliceStable(glyphs, func(i, j int) bool { return glyphs[i].Y > glyphs[j].Y })
+
+	var lines [][]pdf.Text
+	for _, glyph := range glyphs {
+		last := len(lines) - 1
+		if last < 0 || math.Abs(lines[last][0].Y-glyph.Y) > math.Max(glyph.FontSize, 1)/2 {
+			lines = append(lines, nil)
+			last++
+		}
+		lines[last] = append(lines[last], glyph)
+	}
+
+	var result []string
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return line[i].X < line[j].X })
+		var builder strings.Builder
+		for i, glyph := range line {
+			if i > 0 && glyph.X > line[i-1].X+line[i-1].W+glyph.FontSize/4 {
+				builder.WriteByte(' ')
+			}
+			builder.WriteString(glyph.S)
+		}
+		if trimmed := strings.Join(strings.Fields(builder.String()), " "); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
+// rasterizePDFPage renders a page with pdftoppm of poppler-utils.
+func rasterizePDFPage(ctx context.Context, data []byte, page int) (image.Image, error) {
+	dir, err := os.MkdirTemp("", "ocr-pdf-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	output := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, "pdftoppm", "-r", fmt.Sprint(pdfDPI), "-gray", "-png", "-singlefile",
+		"-f", fmt.Sprint(page), "-l", fmt.Sprint(page), input, output)
+	if message, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(message)))
+	}
+
+	file, err := os.Open(output + ".png")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// readPage reads one page: the text layer as it is, an image with every pipeline.
+func readPage(ctx context.Context, number int, input pageInput, selected []Pipeline, consensus bool) OCRPage {
+	page := OCRPage{Page: number, Source: input.source}
+	if input.img == nil {
+		pagesRead.WithLabelValues("pdf_text").Inc()
+		page.Results = []OCRResult{{
+			Version:     "pdf_text",
+			Text:        input.text,
+			Confidence:  100,
+			Description: "Text layer of the PDF",
+		}}
+		page.Best = &page.Results[0]
+		return page
+	}
+
+	page.Width, page.Height = input.img.Bounds().Dx(), input.img.Bounds().Dy()
+	pagesRead.WithLabelValues("ocr").Inc()
+	imageMegapixels.Observe(float64(page.Width*page.Height) / 1e6)
+	page.Results = performOCR(ctx, input.img, selected)
+	page.Best = bestResult(page.Results)
+	if consensus {
+		page.Best = consensusResult(page.Results)
+	}
+	return page
+}
+
+// ReadFile reads an image or a PDF the way OcrHandler reads an upload of it, without the cache,
+// for the ocr command.
+func ReadFile(ctx context.Context, name string, data []byte, selected []Pipeline, consensus bool) ([]OCRPage, error) {
+	inputs, err := readFiles(ctx, []upload{{name: name, data: data}})
+	if err != nil {
+		return nil, err
+	}
+	return readInputs(ctx, inputs, selected, consensus), nil
+}
+
+// readInputs reads every page.
+func readInputs(ctx context.Context, inputs []pageInput, selected []Pipeline, consensus bool) []OCRPage {
+	pages := make([]OCRPage, len(inputs))
+	for i, input := range inputs {
+		pages[i] = readPage(ctx, i+1, input, selected, consensus)
+	}
+	return pages
+}
+
+// DocumentText joins the best text of every page, pages apart by an empty line.
+func DocumentText(pages []OCRPage) string {
+	var texts []string
+	for _, page := range pages {
+		if page.Best != nil && page.Best.Text != "" {
+			texts = append(texts, page.Best.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// textPDF builds a PDF with a text layer, a page for every entry with its lines from the top
+// down in Helvetica.
+func textPDF(pages ...[]string) []byte {
+	widths := strings.TrimSpace(strings.Repeat("556 ", 126-32+1))
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // the page tree, once the pages are numbered
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding /FirstChar 32 /LastChar 126 /Widths [" + widths + "] >>",
+	}
+	var kids []string
+	for _, lines := range pages {
+		var content strings.Builder
+		for i, line := range lines {
+			fmt.Fprintf(&content, "BT /F1 12 Tf 72 %d Td (%s) Tj ET\n", 720-20*i, line)
+		}
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", len(objects)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+func pngOf(width, height int) []byte {
+	var out bytes.Buffer
+	png.Encode(&out, image.NewGray(image.Rect(0, 0, width, height)))
+	return out.Bytes()
+}
+
+func TestReadPDFTextLayer(t *testing.T) {
+	data := textPDF(
+		[]string{"TINEX DOOEL SKOPJE", "VKUPNO 95,00 MKD"},
+		[]string{"DDV 18% 14,49", "BROJ NA SMETKA 000451"},
+	)
+	pages, err := readPDF(context.Background(), "bill.pdf", data, maxPages)
+	assert.NoError(t, err)
+	if assert.Len(t, pages, 2) {
+		assert.Equal(t, "bill.pdf#1", pages[0].source)
+		assert.Equal(t, "TINEX DOOEL SKOPJE\nVKUPNO 95,00 MKD", pages[0].text)
+		assert.Nil(t, pages[0].img)
+		assert.Equal(t, "bill.pdf#2", pages[1].source)
+		assert.Equal(t, "DDV 18% 14,49\nBROJ NA SMETKA 000451", pages[1].text)
+	}
+
+	_, err = readPDF(context.Background(), "bill.pdf", data, 1)
+	assert.EqualError(t, err, "More than 20 pages")
+	_, err = readPDF(context.Background(), "bill.pdf", []byte("%PDF-1.4 cut short"), maxPages)
+	assert.EqualError(t, err, "Failed to read PDF")
+}
+
+func TestReadFiles(t *testing.T) {
+	uploads := []upload{
+		{name: "first.png", data: pngOf(30, 10)},
+		{name: "scan", data: textPDF([]string{"VKUPNO 95,00 MKD GOTOVINA"})},
+		{name: "second.png", data: pngOf(20, 40)},
+	}
+	pages, err := readFiles(context.Background(), uploads)
+	assert.NoError(t, err)
+	var sources []string
+	for _, page := range pages {
+		sources = append(sources, page.source)
+	}
+	assert.Equal(t, []string{"first.png", "scan#1", "second.png"}, sources)
+	if assert.Len(t, pages, 3) {
+		assert.Equal(t, image.Rect(0, 0, 30, 10), pages[0].img.Bounds())
+		assert.Equal(t, "VKUPNO 95,00 MKD GOTOVINA", pages[1].text)
+		assert.Equal(t, image.Rect(0, 0, 20, 40), pages[2].img.Bounds())
+	}
+
+	_, err = readFiles(context.Background(), []upload{{name: "photo.jpg", data: []byte("not an image")}})
+	assert.EqualError(t, err, "Failed to decode image")
+}
+
+func TestReadFilesPageLimit(t *testing.T) {
+	uploads := make([]upload, 0, maxPages+1)
+	for i := 0; i <= maxPages; i++ {
+		uploads = append(uploads, upload{name: fmt.Sprintf("page%d.png", i+1), data: pngOf(8, 8)})
+	}
+	_, err := readFiles(context.Background(), uploads)
+	assert.EqualError(t, err, "More than 20 pages")
+
+	pages, err := readFiles(context.Background(), uploads[:maxPages])
+	assert.NoError(t, err)
+	assert.Len(t, pages, maxPages)
+
+	mixed := append(uploads[:maxPages-1:maxPages-1], upload{name: "bill.pdf", data: textPDF(
+		[]string{"VKUPNO 95,00 MKD GOTOVINA"},
+		[]string{"DDV 18% 14,49 BROJ 000451"},
+	)})
+	_, err = readFiles(context.Background(), mixed)
+	assert.EqualError(t, err, "More than 20 pages")
+}
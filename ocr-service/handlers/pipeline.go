@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ocr-service/preprocess"
+)
+
+// Pipeline is one way of reading an image: the preprocessing steps and how Tesseract is set
+// up for what they leave.
+type Pipeline struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Steps       preprocess.Chain  `json:"steps"`
+	Language    string            `json:"language"`            // traineddata joined by +, e.g. eng+sqi, mkd when empty
+	PageSegMode int               `json:"psm,omitempty"`       // 6, a single block of text, when 0
+	Whitelist   string            `json:"whitelist,omitempty"` // the characters it may read, all when empty
+	Variables   map[string]string `json:"variables,omitempty"` // other Tesseract variables
+}
+
+// PipelineConfig are the pipelines a request can pick by name and those it gets when it picks
+// none.
+type PipelineConfig struct {
+	Default   []string   `json:"default"`
+	Pipelines []Pipeline `json:"pipelines"`
+}
+
+//go:embed pipelines.json
+var defaultPipelines []byte
+
+// pipelines is the config the service reads with, the embedded one unless LoadPipelines is given
+// another.
+var pipelines = mustParsePipelines(defaultPipelines)
+
+func mustParsePipelines(data []byte) PipelineConfig {
+	config, err := parsePipelines(data)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+// LoadPipelines reads the pipeline config from a JSON file shaped like pipelines.json, an empty
+// path keeps the embedded one.
+func LoadPipelines(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	config, err := parsePipelines(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	pipelines = config
+	return nil
+}
+
+func parsePipelines(data []byte) (PipelineConfig, error) {
+	var config PipelineConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return PipelineConfig{}, err
+	}
+
+	names := map[string]bool{}
+	for i := range config.Pipelines {
+		pipeline := &config.Pipelines[i]
+		if err := pipeline.normalize(); err != nil {
+			return PipelineConfig{}, err
+		}
+		if names[pipeline.Name] {
+			return PipelineConfig{}, fmt.Errorf("pipeline %q is there twice", pipeline.Name)
+		}
+		names[pipeline.Name] = true
+	}
+
+	if len(config.Default) == 0 {
+		return PipelineConfig{}, fmt.Errorf("no default pipelines")
+	}
+	for _, name := range config.Default {
+		if !names[name] {
+			return PipelineConfig{}, fmt.Errorf("default pipeline %q is not defined", name)
+		}
+	}
+	return config, nil
+}
+
+// normalize checks the pipeline and fills in what was left out.
+func (p *Pipeline) normalize() error {
+	if p.Name == "" || p.Name == "qr" || p.Name == "pdf_text" {
+		return fmt.Errorf("pipeline name %q is empty or taken", p.Name)
+	}
+	if p.Language == "" {
+		p.Language = "mkd"
+	}
+	for _, language := range strings.Split(p.Language, "+") {
+		if language == "" {
+			return fmt.Errorf("pipeline %q: language %q has an empty part", p.Name, p.Language)
+		}
+	}
+	if p.PageSegMode == 0 {
+		p.PageSegMode = 6
+	}
+	if p.PageSegMode < 1 || p.PageSegMode > 13 {
+		return fmt.Errorf("pipeline %q: page segmentation mode %d is not 1 to 13", p.Name, p.PageSegMode)
+	}
+	if p.Description == "" {
+		p.Description = p.Steps.String()
+	}
+	return nil
+}
+
+// settings tells apart pipelines Tesseract has to be set up differently for, a client loaded
+// for one is lent out only to the same settings.
+func (p Pipeline) settings() string {
+	keys := make([]string, 0, len(p.Variables))
+	for key := range p.Variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s\x00%d\x00%s", p.Language, p.PageSegMode, p.Whitelist)
+	for _, key := range keys {
+		fmt.Fprintf(&builder, "\x00%s=%s", key, p.Variables[key])
+	}
+	return builder.String()
+}
+
+// requestPipelines are the pipelines a request asked for. pipelines picks configured ones by
+// name, comma separated. steps instead reads with a pipeline of the request's own, "custom",
+// set up with language, psm and whitelist. Without either the request gets the default ones.
+func requestPipelines(r *http.Request) ([]Pipeline, error) {
+	if steps := r.FormValue("steps"); steps != "" {
+		psm := 0
+		if value := r.FormValue("psm"); value != "" {
+			var err error
+			if psm, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("psm %q is not a number", value)
+			}
+		}
+		custom, err := CustomPipeline(steps, r.FormValue("language"), psm, r.FormValue("whitelist"))
+		if err != nil {
+			return nil, err
+		}
+		return []Pipeline{custom}, nil
+	}
+
+	var names []string
+	if requested := r.FormValue("pipelines"); requested != "" {
+		names = strings.Split(requested, ",")
+	}
+	return SelectPipelines(names)
+}
+
+// CustomPipeline is a pipeline of the given steps that is not in the config, "custom".
+func CustomPipeline(steps, language string, psm int, whitelist string) (Pipeline, error) {
+	chain, err := preprocess.ParseChain(steps)
+	if err != nil {
+		return Pipeline{}, err
+	}
+	custom := Pipeline{Name: "custom", Steps: chain, Language: language, PageSegMode: psm, Whitelist: whitelist}
+	if err := custom.normalize(); err != nil {
+		return Pipeline{}, err
+	}
+	return custom, nil
+}
+
+// SelectPipelines returns the configured pipelines of the names, the default ones for none.
+func SelectPipelines(names []string) ([]Pipeline, error) {
+	config := pipelines
+	if len(names) == 0 {
+		names = config.Default
+	}
+
+	selected := make([]Pipeline, 0, len(names))
+	for _, name := range names {
+		pipeline, ok := config.find(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("Unknown pipeline %q", name)
+		}
+		selected = append(selected, pipeline)
+	}
+	return selected, nil
+}
+
+func (c PipelineConfig) find(name string) (Pipeline, bool) {
+	for _, pipeline := range c.Pipelines {
+		if pipeline.Name == name {
+			return pipeline, true
+		}
+	}
+	return Pipeline{}, false
+}
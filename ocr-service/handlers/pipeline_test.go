@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestPipelines(t *testing.T) {
+	selected, err := requestPipelines(httptest.NewRequest(http.MethodPost, "/ocr", nil))
+	assert.NoError(t, err)
+	var names []string
+	for _, pipeline := range selected {
+		names = append(names, pipeline.Name)
+	}
+	assert.Equal(t, []string{"enhanced", "dilated", "denoised"}, names)
+
+	selected, err = requestPipelines(httptest.NewRequest(http.MethodPost, "/ocr?pipelines=latin", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "eng+sqi+srp_latn", selected[0].Language)
+	assert.Equal(t, 6, selected[0].PageSegMode)
+
+	_, err = requestPipelines(httptest.NewRequest(http.MethodPost, "/ocr?pipelines=enhanced,fancy", nil))
+	assert.Error(t, err)
+
+	selected, err = requestPipelines(httptest.NewRequest(http.MethodPost, "/ocr?steps=deskew,threshold:otsu&language=eng&psm=4", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "custom", selected[0].Name)
+	assert.Equal(t, "deskew,threshold:otsu", selected[0].Description)
+	assert.Equal(t, 4, selected[0].PageSegMode)
+
+	_, err = requestPipelines(httptest.NewRequest(http.MethodPost, "/ocr?steps=deskew&psm=14", nil))
+	assert.Error(t, err)
+}
+
+func TestParsePipelines(t *testing.T) {
+	_, err := parsePipelines([]byte(`{"default": ["a"], "pipelines": [{"name": "a"}, {"name": "a"}]}`))
+	assert.Error(t, err)
+	_, err = parsePipelines([]byte(`{"default": ["b"], "pipelines": [{"name": "a"}]}`))
+	assert.Error(t, err)
+	_, err = parsePipelines([]byte(`{"default": ["a"], "pipelines": [{"name": "a", "steps": ["unsharp"]}]}`))
+	assert.Error(t, err)
+
+	config, err := parsePipelines([]byte(`{"default": ["a"], "pipelines": [{"name": "a", "variables": {"preserve_interword_spaces": "1"}}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "mkd", config.Pipelines[0].Language)
+	assert.NotEqual(t, config.Pipelines[0].settings(), Pipeline{Language: "mkd", PageSegMode: 6}.settings())
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otiai10/gosseract/v2"
+)
+
+var (
+	// ocrWorkers is how many Tesseract passes run at once, OCR_WORKERS or one per CPU.
+	ocrWorkers = envInt("OCR_WORKERS", runtime.NumCPU())
+	tesseract  = newTesseractPool(ocrWorkers, ocrWorkers)
+	// requests admits OCR_QUEUE_SIZE requests at once, those being read and those waiting for
+	// a worker, the rest are turned away with 429.
+	requests = newRequestQueue(envInt("OCR_QUEUE_SIZE", 4*ocrWorkers), ocrWorkers)
+)
+
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		Logger.Warn("invalid setting, using the default", "name", name, "value", value, "default", fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// tesseractPool lends out Tesseract clients, at most size at once. Loading the traineddata
+// is the slow part of a client, so up to idle of them are kept loaded between requests, each
+// for the settings of the pipeline it was loaded for.
+type tesseractPool struct {
+	slots chan struct{}
+
+	mu      sync.Mutex
+	idle    map[string][]*gosseract.Client
+	maxIdle int
+	count   int
+}
+
+func newTesseractPool(size, idle int) *tesseractPool {
+	return &tesseractPool{
+		slots:   make(chan struct{}, size),
+		idle:    map[string][]*gosseract.Client{},
+		maxIdle: idle,
+	}
+}
+
+// get waits for a free slot and returns a client set up for the pipeline.
+func (p *tesseractPool) get(pipeline Pipeline) *gosseract.Client {
+	p.slots <- struct{}{}
+	settings := pipeline.settings()
+	p.mu.Lock()
+	if clients := p.idle[settings]; len(clients) > 0 {
+		client := clients[len(clients)-1]
+		p.idle[settings] = clients[:len(clients)-1]
+		p.count--
+		p.mu.Unlock()
+		return client
+	}
+	p.mu.Unlock()
+
+	client := gosseract.NewClient()
+	client.SetLanguage(strings.Split(pipeline.Language, "+")...)
+	client.SetPageSegMode(gosseract.PageSegMode(pipeline.PageSegMode))
+	if pipeline.Whitelist != "" {
+		client.SetVariable("tessedit_char_whitelist", pipeline.Whitelist)
+	}
+	for key, value := range pipeline.Variables {
+		client.SetVariable(gosseract.SettableVariable(key), value)
+	}
+	return client
+}
+
+// put gives back a client loaded for the pipeline. When the pool is full of idle clients one
+// loaded for other settings makes room, pipelines that are no longer asked for do not keep
+// their clients.
+func (p *tesseractPool) put(pipeline Pipeline, client *gosseract.Client) {
+	defer func() { <-p.slots }()
+	settings := pipeline.settings()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.count >= p.maxIdle {
+		evicted := false
+		for other, clients := range p.idle {
+			if other != settings && len(clients) > 0 {
+				clients[0].Close()
+				p.idle[other] = clients[1:]
+				p.count--
+				evicted = true
+				break
+			}
+		}
+		if !evicted {
+			client.Close()
+			return
+		}
+	}
+	p.idle[settings] = append(p.idle[settings], client)
+	p.count++
+}
+
+// requestQueue bounds the requests being served and tells a client turned away when to come
+// back, from how long requests took lately.
+type requestQueue struct {
+	slots   chan struct{}
+	workers int
+
+	mu      sync.Mutex
+	average time.Duration
+}
+
+func newRequestQueue(size, workers int) *requestQueue {
+	return &requestQueue{
+		slots:   make(chan struct{}, size),
+		workers: workers,
+		average: 5 * time.Second,
+	}
+}
+
+// tryEnter admits a request unless the queue is full.
+func (q *requestQueue) tryEnter() bool {
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// leave frees the slot of a request that began at started.
+func (q *requestQueue) leave(started time.Time) {
+	<-q.slots
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.average = (q.average*4 + time.Since(started)) / 5
+}
+
+// retryAfter is about how many seconds the requests in the queue need, at least one.
+func (q *requestQueue) retryAfter() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	wait := q.average.Seconds() * float64(cap(q.slots)) / float64(max(q.workers, 1))
+	return max(1, int(math.Ceil(wait)))
+}
+
+// length is how many requests are in the queue.
+func (q *requestQueue) length() int {
+	return len(q.slots)
+}
+
+// full tells whether the next request would be turned away.
+func (q *requestQueue) full() bool {
+	return len(q.slots) == cap(q.slots)
+}
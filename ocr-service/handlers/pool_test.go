@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestQueueTurnsAwayWhenFull(t *testing.T) {
+	queue := newRequestQueue(2, 1)
+	assert.True(t, queue.tryEnter())
+	assert.True(t, queue.tryEnter())
+	assert.False(t, queue.tryEnter())
+	assert.Equal(t, 10, queue.retryAfter()) // 5s on average, two requests, one worker
+
+	queue.leave(time.Now())
+	assert.True(t, queue.tryEnter())
+}
+
+func TestTesseractPoolKeepsClientsPerSettings(t *testing.T) {
+	pool := newTesseractPool(2, 1)
+	cyrillic := Pipeline{Language: "mkd", PageSegMode: 6}
+	latin := Pipeline{Language: "eng+sqi", PageSegMode: 6}
+
+	client := pool.get(cyrillic)
+	pool.put(cyrillic, client)
+	assert.Same(t, client, pool.get(cyrillic))
+	pool.put(cyrillic, client)
+
+	// the idle Macedonian client makes room for the Latin one
+	pool.put(latin, pool.get(latin))
+	assert.Empty(t, pool.idle[cyrillic.settings()])
+	assert.Len(t, pool.idle[latin.settings()], 1)
+	assert.Equal(t, 1, pool.count)
+}
+
+func sampleReceipt(b *testing.B) image.Image {
+	img, err := imaging.Open("../preprocess/testdata/samples/tilted+3.png")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return img
+}
+
+// benchmarkOCR reads the sample receipt b.N times with the Tesseract clients of pool.
+func benchmarkOCR(b *testing.B, pool *tesseractPool) {
+	img := sampleReceipt(b)
+	selected, err := requestPipelines(httptest.NewRequest(http.MethodPost, "/ocr", nil))
+	if err != nil {
+		b.Fatal(err)
+	}
+	previous := tesseract
+	tesseract = pool
+	defer func() { tesseract = previous }()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		performOCR(context.Background(), img, selected)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "receipts/s")
+}
+
+// The throughput of the pool against how receipts were read before it, run inside the image
+// with Tesseract: go test -run - -bench OCR -benchtime 10x ./handlers
+
+// BenchmarkOCRFreshClients loads a client for every pipeline and runs one at a time.
+func BenchmarkOCRFreshClients(b *testing.B) {
+	benchmarkOCR(b, newTesseractPool(1, 0))
+}
+
+// BenchmarkOCRPool keeps a loaded client per CPU and runs the pipelines in parallel.
+func BenchmarkOCRPool(b *testing.B) {
+	benchmarkOCR(b, newTesseractPool(runtime.NumCPU(), runtime.NumCPU()))
+}
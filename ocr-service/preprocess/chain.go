@@ -0,0 +1,189 @@
+package preprocess
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Step is one step of a chain, written as its name and, for the steps that take one, an
+// argument after a colon: "deskew", "contrast:20", "threshold:sauvola".
+type Step struct {
+	Name      string
+	Amount    float64   // contrast, brightness, sharpen and blur sigma, dilate radius, scale factor
+	Threshold Threshold // only for threshold
+}
+
+// thresholds are the names of the thresholds a step can ask for.
+var thresholds = map[string]Threshold{
+	"fixed":   FixedThreshold,
+	"otsu":    OtsuThreshold,
+	"sauvola": SauvolaThreshold,
+}
+
+// ParseStep reads a step as it is written in a pipeline.
+func ParseStep(text string) (Step, error) {
+	name, arg, hasArg := strings.Cut(strings.TrimSpace(text), ":")
+	step := Step{Name: name}
+
+	number := func(min, max float64) error {
+		if !hasArg {
+			return fmt.Errorf("step %q needs an amount from %g to %g", name, min, max)
+		}
+		value, err := strconv.ParseFloat(arg, 64)
+		if err != nil || value < min || value > max {
+			return fmt.Errorf("step %q takes an amount from %g to %g, not %q", name, min, max, arg)
+		}
+		step.Amount = value
+		return nil
+	}
+
+	var err error
+	switch name {
+	case "perspective", "deskew", "grayscale":
+		if hasArg {
+			err = fmt.Errorf("step %q takes no amount", name)
+		}
+	case "contrast", "brightness":
+		err = number(-100, 100)
+	case "sharpen", "blur":
+		err = number(0.1, 10)
+	case "dilate":
+		if err = number(1, 5); err == nil && step.Amount != math.Trunc(step.Amount) {
+			err = fmt.Errorf("step %q takes a whole radius, not %q", name, arg)
+		}
+	case "scale":
+		err = number(0.25, 4)
+	case "threshold":
+		threshold, ok := thresholds[arg]
+		if !ok {
+			err = fmt.Errorf("step %q takes fixed, otsu or sauvola, not %q", name, arg)
+		}
+		step.Threshold = threshold
+	default:
+		err = fmt.Errorf("unknown step %q", name)
+	}
+	if err != nil {
+		return Step{}, err
+	}
+	return step, nil
+}
+
+// String writes the step back the way ParseStep reads it.
+func (s Step) String() string {
+	switch s.Name {
+	case "perspective", "deskew", "grayscale":
+		return s.Name
+	case "threshold":
+		for name, threshold := range thresholds {
+			if threshold == s.Threshold {
+				return s.Name + ":" + name
+			}
+		}
+	}
+	return s.Name + ":" + strconv.FormatFloat(s.Amount, 'g', -1, 64)
+}
+
+func (s Step) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Step) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("a step is written as a string, e.g. \"contrast:20\"")
+	}
+	step, err := ParseStep(text)
+	if err != nil {
+		return err
+	}
+	*s = step
+	return nil
+}
+
+// apply takes the step on img, the mapping leads back to img.
+func (s Step) apply(img image.Image) (image.Image, Mapping) {
+	switch s.Name {
+	case "perspective":
+		if quad, ok := FindReceipt(img); ok {
+			return warp(img, quad)
+		}
+	case "deskew":
+		rotated, _, mapping := deskew(img)
+		return rotated, mapping
+	case "grayscale":
+		return imaging.Grayscale(img), Identity
+	case "contrast":
+		return imaging.AdjustContrast(img, s.Amount), Identity
+	case "brightness":
+		return imaging.AdjustBrightness(img, s.Amount), Identity
+	case "sharpen":
+		return imaging.Sharpen(img, s.Amount), Identity
+	case "blur":
+		return imaging.Blur(img, s.Amount), Identity
+	case "dilate":
+		return Dilate(img, int(s.Amount)), Identity
+	case "threshold":
+		return Binarize(img, s.Threshold), Identity
+	case "scale":
+		return scale(img, s.Amount)
+	}
+	return img, Identity
+}
+
+// scale resizes img by factor with Lanczos, small print reads better at twice the size.
+func scale(img image.Image, factor float64) (image.Image, Mapping) {
+	bounds := img.Bounds()
+	width := max(1, int(math.Round(float64(bounds.Dx())*factor)))
+	height := max(1, int(math.Round(float64(bounds.Dy())*factor)))
+	scaled := imaging.Resize(img, width, height, imaging.Lanczos)
+
+	fx, fy := float64(bounds.Dx())/float64(width), float64(bounds.Dy())/float64(height)
+	return scaled, func(x, y float64) (float64, float64) {
+		return float64(bounds.Min.X) + x*fx, float64(bounds.Min.Y) + y*fy
+	}
+}
+
+// Chain is the preprocessing of a pipeline, its steps in order.
+type Chain []Step
+
+// ParseChain reads the steps of a chain, e.g. "deskew,grayscale,threshold:otsu".
+func ParseChain(text string) (Chain, error) {
+	var chain Chain
+	for _, part := range strings.Split(text, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		step, err := ParseStep(part)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, step)
+	}
+	return chain, nil
+}
+
+// Apply takes the steps on img one after the other, the mapping leads back to img.
+func (c Chain) Apply(img image.Image) (image.Image, Mapping) {
+	mapping := Mapping(Identity)
+	for _, step := range c {
+		var stepped Mapping
+		img, stepped = step.apply(img)
+		mapping = mapping.Then(stepped)
+	}
+	return img, mapping
+}
+
+// String writes the chain the way ParseChain reads it.
+func (c Chain) String() string {
+	steps := make([]string, len(c))
+	for i, step := range c {
+		steps[i] = step.String()
+	}
+	return strings.Join(steps, ",")
+}
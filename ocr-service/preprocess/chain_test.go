@@ -0,0 +1,59 @@
+package preprocess
+
+import (
+	"encoding/json"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChain(t *testing.T) {
+	chain, err := ParseChain("perspective, deskew,grayscale,contrast:20,sharpen:1.5,threshold:sauvola,dilate:1,scale:2")
+	assert.NoError(t, err)
+	assert.Equal(t, "perspective,deskew,grayscale,contrast:20,sharpen:1.5,threshold:sauvola,dilate:1,scale:2", chain.String())
+	assert.Equal(t, SauvolaThreshold, chain[5].Threshold)
+
+	for _, text := range []string{"rotate", "contrast", "contrast:x", "contrast:200", "deskew:1", "threshold:mean", "dilate:1.5", "scale:0"} {
+		_, err := ParseChain(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestChainJSON(t *testing.T) {
+	var chain Chain
+	assert.NoError(t, json.Unmarshal([]byte(`["deskew", "threshold:otsu"]`), &chain))
+	assert.Equal(t, Chain{{Name: "deskew"}, {Name: "threshold", Threshold: OtsuThreshold}}, chain)
+
+	data, err := json.Marshal(chain)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `["deskew", "threshold:otsu"]`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`["blur:-1"]`), &chain))
+	assert.Error(t, json.Unmarshal([]byte(`[{"name": "blur"}]`), &chain))
+}
+
+func TestChainMapsScaleBack(t *testing.T) {
+	chain, err := ParseChain("grayscale,scale:2,dilate:1")
+	assert.NoError(t, err)
+
+	prepared, mapping := chain.Apply(image.NewGray(image.Rect(0, 0, 100, 50)))
+	assert.Equal(t, image.Rect(0, 0, 200, 100), prepared.Bounds())
+	assert.Equal(t, image.Rect(10, 5, 30, 15), mapping.Rect(image.Rect(20, 10, 60, 30)))
+}
+
+func TestDilate(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 5, 5))
+	img.Pix[2*5+2] = 255
+
+	dilated := Dilate(img, 1)
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			expected := uint8(0)
+			if x >= 1 && x <= 3 && y >= 1 && y <= 3 {
+				expected = 255
+			}
+			assert.Equal(t, expected, dilated.GrayAt(x, y).Y, "%d,%d", x, y)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// maxSkew is the largest tilt, in degrees, Deskew looks for. A receipt held further off
+	// is a photo of something else.
+	maxSkew = 15.0
+	// skewSampleWidth is the width the skew is estimated at, the lines of text are still
+	// lines there and it is quick.
+	skewSampleWidth = 800
+)
+
+// EstimateSkew returns the angle, in degrees counter-clockwise, the text lines of the image
+// are tilted by. It uses the projection profile: rotated by the right angle the dark pixels
+// fall into few rows, so the row counts vary the most.
+func EstimateSkew(img image.Image) float64 {
+	sample := img
+	if img.Bounds().Dx() > skewSampleWidth {
+		sample = imaging.Resize(img, skewSampleWidth, 0, imaging.Box)
+	}
+	gray := toGray(sample)
+	ink := inkPoints(gray)
+	if len(ink) < 50 {
+		return 0
+	}
+	height := gray.Bounds().Dy() + gray.Bounds().Dx()
+
+	best, bestScore := 0.0, profileScore(ink, 0, height)
+	search := func(from, to, step float64) {
+		for angle := from; angle <= to+step/2; angle += step {
+			if score := profileScore(ink, angle, height); score > bestScore {
+				best, bestScore = angle, score
+			}
+		}
+	}
+	search(-maxSkew, maxSkew, 0.5)
+	search(best-0.5, best+0.5, 0.1)
+	return math.Round(best*10) / 10
+}
+
+// inkPoints are the dark pixels of the image, the text.
+func inkPoints(gray *image.Gray) [][2]float64 {
+	cutoff := Otsu(gray)
+	bounds := gray.Bounds()
+	var points [][2]float64
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			if gray.Pix[y*gray.Stride+x] <= cutoff {
+				points = append(points, [2]float64{float64(x), float64(y)})
+			}
+		}
+	}
+	// a page that is mostly dark is the background, not text
+	if len(points) > bounds.Dx()*bounds.Dy()/2 {
+		return nil
+	}
+	return points
+}
+
+// profileScore is the sum of the squared row counts of the ink rotated back by angle degrees,
+// largest when the lines of text are horizontal.
+func profileScore(points [][2]float64, angle float64, height int) float64 {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	rows := make([]float64, 2*height+1)
+	for _, p := range points {
+		// y of the point rotated by -angle, image y grows downwards
+		row := int(math.Round(p[1]*cos+p[0]*sin)) + height
+		if row >= 0 && row < len(rows) {
+			rows[row]++
+		}
+	}
+	score := 0.0
+	for _, count := range rows {
+		score += count * count
+	}
+	return score
+}
+
+// Deskew rotates the image so its text lines are horizontal and returns the angle it
+// corrected. Tilts under a tenth of a degree are left alone.
+func Deskew(img image.Image) (image.Image, float64) {
+	deskewed, angle, _ := deskew(img)
+	return deskewed, angle
+}
+
+func deskew(img image.Image) (image.Image, float64, Mapping) {
+	angle := EstimateSkew(img)
+	if math.Abs(angle) < 0.1 {
+		return img, 0, Identity
+	}
+	rotated := imaging.Rotate(img, -angle, color.White)
+	return rotated, angle, rotation(img.Bounds(), rotated.Bounds(), -angle)
+}
+
+// rotation maps the pixels of an image imaging.Rotate turned by angle degrees back onto the
+// source, the way imaging.Rotate samples them: around the centres of both.
+func rotation(source, rotated image.Rectangle, angle float64) Mapping {
+	sin, cos := math.Sincos(math.Pi * angle / 180)
+	sourceX, sourceY := float64(source.Dx())/2-0.5, float64(source.Dy())/2-0.5
+	rotatedX, rotatedY := float64(rotated.Dx())/2-0.5, float64(rotated.Dy())/2-0.5
+	return func(x, y float64) (float64, float64) {
+		x, y = x-rotatedX, y-rotatedY
+		return x*cos - y*sin + sourceX + float64(source.Min.X), x*sin + y*cos + sourceY + float64(source.Min.Y)
+	}
+}
@@ -0,0 +1,43 @@
+package preprocess
+
+import (
+	"image"
+	"runtime"
+	"sync"
+)
+
+// Dilate makes every pixel the brightest of its neighbourhood radius pixels around, the
+// white paper grows into thin specks and the strokes of smudged print come apart.
+func Dilate(img image.Image, radius int) *image.Gray {
+	gray := toGray(img)
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	result := image.NewGray(bounds)
+
+	// strips of rows, one per CPU
+	workers := min(runtime.NumCPU(), max(height, 1))
+	strip := (height + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < height; start += strip {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for y := start; y < end; y++ {
+				for x := 0; x < width; x++ {
+					brightest := uint8(0)
+					for ny := max(y-radius, 0); ny <= min(y+radius, height-1); ny++ {
+						row := gray.Pix[ny*width:]
+						for nx := max(x-radius, 0); nx <= min(x+radius, width-1); nx++ {
+							if row[nx] > brightest {
+								brightest = row[nx]
+							}
+						}
+					}
+					result.Pix[y*result.Stride+x] = brightest
+				}
+			}
+		}(start, min(start+strip, height))
+	}
+	wg.Wait()
+	return result
+}
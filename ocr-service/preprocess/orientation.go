@@ -0,0 +1,96 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// Orientation reads the EXIF orientation, 1 to 8, from a JPEG or from a bare EXIF block as
+// HEIC files carry it. Anything it cannot read is 1, the image as stored.
+func Orientation(data []byte) int {
+	if len(data) > 2 && data[0] == 0xFF && data[1] == 0xD8 {
+		data = jpegExif(data)
+	}
+	data = bytes.TrimPrefix(data, []byte("Exif\x00\x00"))
+	if len(data) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(data[4:8]))
+	if offset+2 > len(data) {
+		return 1
+	}
+	entries := int(order.Uint16(data[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(data) {
+			return 1
+		}
+		if order.Uint16(data[entry:]) == 0x0112 {
+			if value := int(order.Uint16(data[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// jpegExif returns the EXIF block of a JPEG, nil when it has none.
+func jpegExif(data []byte) []byte {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // the image data starts, no more metadata
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		if marker == 0xE1 && bytes.HasPrefix(data[i+4:end], []byte("Exif\x00\x00")) {
+			return data[i+4 : end]
+		}
+		i = end
+	}
+	return nil
+}
+
+// Orient turns the image the way the camera was held, phones store receipts sideways and
+// flag it in the EXIF orientation.
+func Orient(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	default:
+		return img
+	}
+}
@@ -0,0 +1,210 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// edgeSampleWidth is the width the receipt is looked for at.
+const edgeSampleWidth = 400
+
+// Quad is the outline of the receipt in the image, its corners clockwise from the top left.
+type Quad [4]image.Point
+
+// FindReceipt looks for the receipt as the largest bright region of the image, paper on a
+// darker table. It reports false when there is none, or when the paper fills the frame and
+// there is nothing to straighten.
+func FindReceipt(img image.Image) (Quad, bool) {
+	bounds := img.Bounds()
+	scale := 1.0
+	sample := img
+	if bounds.Dx() > edgeSampleWidth {
+		scale = float64(bounds.Dx()) / edgeSampleWidth
+		sample = imaging.Resize(img, edgeSampleWidth, 0, imaging.Box)
+	}
+	gray := toGray(imaging.Blur(sample, 2))
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	cutoff := Otsu(gray)
+
+	region := largestRegion(gray, cutoff)
+	if len(region) < w*h/10 {
+		return Quad{}, false
+	}
+
+	// paper that runs off every edge of the frame has no outline to go by
+	var touches [4]bool
+	for _, p := range region {
+		touches[0] = touches[0] || p.Y == 0
+		touches[1] = touches[1] || p.X == w-1
+		touches[2] = touches[2] || p.Y == h-1
+		touches[3] = touches[3] || p.X == 0
+	}
+	if touches == [4]bool{true, true, true, true} {
+		return Quad{}, false
+	}
+
+	// the corners are the points furthest along the diagonals
+	var quad Quad
+	extremes := [4]int{math.MaxInt, math.MinInt, math.MinInt, math.MaxInt}
+	for _, p := range region {
+		if p.X+p.Y < extremes[0] {
+			extremes[0], quad[0] = p.X+p.Y, p
+		}
+		if p.X-p.Y > extremes[1] {
+			extremes[1], quad[1] = p.X-p.Y, p
+		}
+		if p.X+p.Y > extremes[2] {
+			extremes[2], quad[2] = p.X+p.Y, p
+		}
+		if p.X-p.Y < extremes[3] {
+			extremes[3], quad[3] = p.X-p.Y, p
+		}
+	}
+	for i, corner := range quad {
+		quad[i] = image.Pt(
+			bounds.Min.X+int(math.Round((float64(corner.X)+0.5)*scale)),
+			bounds.Min.Y+int(math.Round((float64(corner.Y)+0.5)*scale)),
+		)
+	}
+	return quad, true
+}
+
+// largestRegion returns the pixels of the largest connected region brighter than cutoff.
+func largestRegion(gray *image.Gray, cutoff uint8) []image.Point {
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	seen := make([]bool, w*h)
+	var largest []image.Point
+	for start := range seen {
+		if seen[start] || gray.Pix[start/w*gray.Stride+start%w] <= cutoff {
+			continue
+		}
+		var region []image.Point
+		stack := []int{start}
+		seen[start] = true
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			region = append(region, image.Pt(x, y))
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[0] >= w || n[1] < 0 || n[1] >= h {
+					continue
+				}
+				j := n[1]*w + n[0]
+				if !seen[j] && gray.Pix[n[1]*gray.Stride+n[0]] > cutoff {
+					seen[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		if len(region) > len(largest) {
+			largest = region
+		}
+	}
+	return largest
+}
+
+// Warp maps the quad of the image onto an upright rectangle as large as its longer sides.
+func Warp(img image.Image, quad Quad) image.Image {
+	warped, _ := warp(img, quad)
+	return warped
+}
+
+func warp(img image.Image, quad Quad) (image.Image, Mapping) {
+	distance := func(a, b image.Point) float64 {
+		return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+	}
+	width := int(math.Max(distance(quad[0], quad[1]), distance(quad[3], quad[2])))
+	height := int(math.Max(distance(quad[0], quad[3]), distance(quad[1], quad[2])))
+	if width < 2 || height < 2 {
+		return img, Identity
+	}
+
+	target := [4][2]float64{{0, 0}, {float64(width - 1), 0}, {float64(width - 1), float64(height - 1)}, {0, float64(height - 1)}}
+	var source [4][2]float64
+	for i, corner := range quad {
+		source[i] = [2]float64{float64(corner.X), float64(corner.Y)}
+	}
+	h, ok := homography(target, source)
+	if !ok {
+		return img, Identity
+	}
+	mapping := func(x, y float64) (float64, float64) {
+		d := h[6]*x + h[7]*y + 1
+		return (h[0]*x + h[1]*y + h[2]) / d, (h[3]*x + h[4]*y + h[5]) / d
+	}
+
+	src := imaging.Clone(img)
+	result := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx, sy := mapping(float64(x), float64(y))
+			result.SetNRGBA(x, y, bilinear(src, sx-float64(img.Bounds().Min.X), sy-float64(img.Bounds().Min.Y)))
+		}
+	}
+	return result, mapping
+}
+
+// bilinear samples the image between its pixels, outside it is white paper.
+func bilinear(img *image.NRGBA, x, y float64) color.NRGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	if x0 < 0 || y0 < 0 || x0+1 >= w || y0+1 >= h {
+		if x0 >= 0 && y0 >= 0 && x0 < w && y0 < h {
+			return img.NRGBAAt(x0, y0)
+		}
+		return color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	}
+	dx, dy := x-float64(x0), y-float64(y0)
+	var channels [4]uint8
+	for c := 0; c < 4; c++ {
+		at := func(px, py int) float64 { return float64(img.Pix[py*img.Stride+px*4+c]) }
+		top := at(x0, y0)*(1-dx) + at(x0+1, y0)*dx
+		bottom := at(x0, y0+1)*(1-dx) + at(x0+1, y0+1)*dx
+		channels[c] = uint8(math.Round(top*(1-dy) + bottom*dy))
+	}
+	return color.NRGBA{R: channels[0], G: channels[1], B: channels[2], A: channels[3]}
+}
+
+// homography solves the perspective transform that maps the from points onto the to points,
+// h[0..7] with h[8] = 1.
+func homography(from, to [4][2]float64) ([8]float64, bool) {
+	var m [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y, u, v := from[i][0], from[i][1], to[i][0], to[i][1]
+		m[2*i] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		m[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return [8]float64{}, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for row := 0; row < 8; row++ {
+			if row == col {
+				continue
+			}
+			factor := m[row][col] / m[col][col]
+			for k := col; k < 9; k++ {
+				m[row][k] -= factor * m[col][k]
+			}
+		}
+	}
+
+	var h [8]float64
+	for i := range h {
+		h[i] = m[i][8] / m[i][i]
+	}
+	return h, true
+}
@@ -0,0 +1,61 @@
+// Package preprocess prepares photos of receipts for Tesseract: it straightens the paper,
+// levels the text lines and binarizes against uneven light.
+package preprocess
+
+import (
+	"image"
+	"math"
+)
+
+// Options are the steps a pipeline variant takes before its own filters.
+type Options struct {
+	Perspective bool // find the receipt and warp it upright
+	Deskew      bool // rotate the text lines level
+}
+
+// Mapping takes a point of a prepared image back to the image it was prepared from, so what
+// Tesseract finds can be shown on the photo.
+type Mapping func(x, y float64) (float64, float64)
+
+// Identity is the mapping of an image that was left as it is.
+func Identity(x, y float64) (float64, float64) {
+	return x, y
+}
+
+// Then maps a point through next first, the step that came after m.
+func (m Mapping) Then(next Mapping) Mapping {
+	return func(x, y float64) (float64, float64) {
+		return m(next(x, y))
+	}
+}
+
+// Rect maps a rectangle, the result is the bounding box of its mapped corners.
+func (m Mapping) Rect(r image.Rectangle) image.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [4][2]int{{r.Min.X, r.Min.Y}, {r.Max.X, r.Min.Y}, {r.Max.X, r.Max.Y}, {r.Min.X, r.Max.Y}} {
+		x, y := m(float64(corner[0]), float64(corner[1]))
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+// Prepare applies the steps of the options in order: the perspective first, as it also cuts
+// the table away, then the skew of what is left. The mapping leads back to img.
+func Prepare(img image.Image, options Options) (image.Image, Mapping) {
+	mapping := Mapping(Identity)
+	if options.Perspective {
+		if quad, ok := FindReceipt(img); ok {
+			var warped Mapping
+			img, warped = warp(img, quad)
+			mapping = mapping.Then(warped)
+		}
+	}
+	if options.Deskew {
+		var rotated Mapping
+		img, _, rotated = deskew(img)
+		mapping = mapping.Then(rotated)
+	}
+	return img, mapping
+}
@@ -0,0 +1,304 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+var updateSamples = flag.Bool("update", false, "regenerate the sample receipts in testdata/samples")
+
+// sample is one receipt of testdata/samples with what is known about it.
+type sample struct {
+	File  string         `json:"file"`
+	Truth string         `json:"truth,omitempty"` // the ink of the receipt, black on white
+	Skew  float64        `json:"skew,omitempty"`  // degrees counter-clockwise
+	Quad  *[4][2]float64 `json:"quad,omitempty"`  // corners of the paper, clockwise from the top left
+}
+
+const samplesDir = "testdata/samples"
+
+var receiptLines = []string{
+	"TINEKS DOOEL SKOPJE",
+	"UL. PARTIZANSKA 12",
+	"EDB 4030996116744",
+	"",
+	"MLEKO 1L        65.00 B",
+	"LEB BEL         30.00 B",
+	"JABOLKA 1KG     89.00 B",
+	"KAFE 200G      245.00 A",
+	"",
+	"VKUPNO         429.00",
+	"DDV A 18%       37.37",
+	"DDV B 5%         8.81",
+	"14.03.2025  18:22:05",
+}
+
+// renderReceipt draws the receipt lines in black on white paper, three times the size of the
+// 7x13 bitmap font, about what a phone makes of receipt print.
+func renderReceipt() *image.NRGBA {
+	const scale = 3
+	small := image.NewNRGBA(image.Rect(0, 0, 200, 14*len(receiptLines)+20))
+	draw.Draw(small, small.Bounds(), image.White, image.Point{}, draw.Src)
+	drawer := font.Drawer{Dst: small, Src: image.Black, Face: basicfont.Face7x13}
+	for i, line := range receiptLines {
+		drawer.Dot = fixed.P(20, 20+14*i)
+		drawer.DrawString(line)
+	}
+	return imaging.Resize(small, small.Bounds().Dx()*scale, 0, imaging.NearestNeighbor)
+}
+
+func generateSamples(t *testing.T) []sample {
+	assert.NoError(t, os.MkdirAll(samplesDir, 0o755))
+	save := func(name string, img image.Image) {
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, img))
+		assert.NoError(t, os.WriteFile(filepath.Join(samplesDir, name), buf.Bytes(), 0o644))
+	}
+	receipt := renderReceipt()
+	var samples []sample
+
+	// tilted photos
+	for _, skew := range []float64{3, -7, 12} {
+		name := fmt.Sprintf("tilted%+.0f.png", skew)
+		save(name, imaging.Rotate(receipt, skew, color.White))
+		samples = append(samples, sample{File: name, Skew: skew})
+	}
+
+	// lit from the left, the right half is in shadow
+	truth := toGray(receipt)
+	lit := image.NewGray(truth.Bounds())
+	for y := 0; y < truth.Rect.Dy(); y++ {
+		for x := 0; x < truth.Rect.Dx(); x++ {
+			light := 240 - 170*float64(x)/float64(truth.Rect.Dx())
+			if truth.Pix[y*truth.Stride+x] < 128 {
+				light *= 0.35
+			}
+			lit.Pix[y*lit.Stride+x] = uint8(light)
+		}
+	}
+	save("uneven-light.png", lit)
+	save("uneven-light.truth.png", truth)
+	samples = append(samples, sample{File: "uneven-light.png", Truth: "uneven-light.truth.png"})
+
+	// taken at an angle on a dark table
+	quad := [4][2]float64{{130, 60}, {760, 110}, {820, 1010}, {90, 960}}
+	rect := [4][2]float64{{0, 0}, {float64(receipt.Rect.Dx() - 1), 0}, {float64(receipt.Rect.Dx() - 1), float64(receipt.Rect.Dy() - 1)}, {0, float64(receipt.Rect.Dy() - 1)}}
+	h, ok := homography(quad, rect)
+	assert.True(t, ok)
+	table := image.NewNRGBA(image.Rect(0, 0, 900, 1080))
+	for y := 0; y < 1080; y++ {
+		for x := 0; x < 900; x++ {
+			fx, fy := float64(x), float64(y)
+			d := h[6]*fx + h[7]*fy + 1
+			sx, sy := (h[0]*fx+h[1]*fy+h[2])/d, (h[3]*fx+h[4]*fy+h[5])/d
+			if sx < 0 || sy < 0 || sx > rect[2][0] || sy > rect[2][1] {
+				table.SetNRGBA(x, y, color.NRGBA{R: 70, G: 55, B: 45, A: 255})
+				continue
+			}
+			table.SetNRGBA(x, y, bilinear(receipt, sx, sy))
+		}
+	}
+	save("perspective.png", table)
+	samples = append(samples, sample{File: "perspective.png", Quad: &quad})
+
+	manifest, err := json.MarshalIndent(samples, "", "  ")
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(samplesDir, "samples.json"), append(manifest, '\n'), 0o644))
+	return samples
+}
+
+// loadSamples reads the sample set, run with -update to draw it again after changing it.
+func loadSamples(t *testing.T) []sample {
+	if *updateSamples {
+		return generateSamples(t)
+	}
+	data, err := os.ReadFile(filepath.Join(samplesDir, "samples.json"))
+	assert.NoError(t, err)
+	var samples []sample
+	assert.NoError(t, json.Unmarshal(data, &samples))
+	return samples
+}
+
+func openSample(t *testing.T, name string) image.Image {
+	img, err := imaging.Open(filepath.Join(samplesDir, name))
+	assert.NoError(t, err)
+	return img
+}
+
+func TestDeskewSamples(t *testing.T) {
+	for _, s := range loadSamples(t) {
+		if s.Skew == 0 {
+			continue
+		}
+		t.Run(s.File, func(t *testing.T) {
+			img := openSample(t, s.File)
+			before := math.Abs(EstimateSkew(img))
+			deskewed, corrected := Deskew(img)
+			after := math.Abs(EstimateSkew(deskewed))
+			t.Logf("skew %.1f°: corrected %.1f°, left %.1f° of %.1f°", s.Skew, corrected, after, before)
+
+			assert.InDelta(t, s.Skew, corrected, 0.3)
+			assert.Less(t, after, 0.3)
+		})
+	}
+}
+
+// pixelAccuracy is the share of pixels that are black or white as in the truth.
+func pixelAccuracy(binarized *image.Gray, truth *image.Gray) float64 {
+	same := 0
+	for i := range truth.Pix {
+		if (binarized.Pix[i] < 128) == (truth.Pix[i] < 128) {
+			same++
+		}
+	}
+	return float64(same) / float64(len(truth.Pix))
+}
+
+func TestBinarizeSamples(t *testing.T) {
+	for _, s := range loadSamples(t) {
+		if s.Truth == "" {
+			continue
+		}
+		t.Run(s.File, func(t *testing.T) {
+			img := openSample(t, s.File)
+			truth := toGray(openSample(t, s.Truth))
+			fixed := pixelAccuracy(Binarize(img, FixedThreshold), truth)
+			otsu := pixelAccuracy(Binarize(img, OtsuThreshold), truth)
+			sauvola := pixelAccuracy(Binarize(img, SauvolaThreshold), truth)
+			t.Logf("pixel accuracy: fixed %.3f, otsu %.3f, sauvola %.3f", fixed, otsu, sauvola)
+
+			assert.Greater(t, sauvola, fixed)
+			assert.Greater(t, sauvola, otsu)
+			assert.Greater(t, sauvola, 0.97)
+		})
+	}
+}
+
+func TestFindReceiptSamples(t *testing.T) {
+	for _, s := range loadSamples(t) {
+		if s.Quad == nil {
+			continue
+		}
+		t.Run(s.File, func(t *testing.T) {
+			img := openSample(t, s.File)
+			quad, ok := FindReceipt(img)
+			assert.True(t, ok)
+			tolerance := float64(img.Bounds().Dx()) / 50
+			for i, corner := range quad {
+				assert.InDelta(t, s.Quad[i][0], float64(corner.X), tolerance, "corner %d", i)
+				assert.InDelta(t, s.Quad[i][1], float64(corner.Y), tolerance, "corner %d", i)
+			}
+
+			// upright, the paper is as tall as it is on the table and no table is left
+			warped := toGray(Warp(img, quad))
+			assert.InDelta(t, 900, warped.Rect.Dy(), 30)
+			brightness := 0
+			for _, value := range warped.Pix {
+				brightness += int(value)
+			}
+			assert.Greater(t, brightness/len(warped.Pix), 200)
+		})
+	}
+}
+
+func TestFindReceiptWithoutTable(t *testing.T) {
+	_, ok := FindReceipt(renderReceipt())
+	assert.False(t, ok)
+}
+
+func TestBinarizeEvenlyLit(t *testing.T) {
+	receipt := renderReceipt()
+	truth := toGray(receipt)
+	for _, threshold := range []Threshold{FixedThreshold, OtsuThreshold, SauvolaThreshold} {
+		assert.Greater(t, pixelAccuracy(Binarize(receipt, threshold), truth), 0.99)
+	}
+}
+
+// exif builds the TIFF block of an EXIF with only the orientation.
+func exif(order binary.ByteOrder, orientation uint16) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString("Exif\x00\x00")
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(buf, order, uint16(42))
+	binary.Write(buf, order, uint32(8))
+	binary.Write(buf, order, uint16(1))
+	binary.Write(buf, order, [2]uint16{0x0112, 3}) // tag, SHORT
+	binary.Write(buf, order, uint32(1))
+	binary.Write(buf, order, [2]uint16{orientation, 0})
+	binary.Write(buf, order, uint32(0))
+	return buf.Bytes()
+}
+
+func TestOrientation(t *testing.T) {
+	assert.Equal(t, 6, Orientation(exif(binary.LittleEndian, 6)))
+	assert.Equal(t, 8, Orientation(exif(binary.BigEndian, 8)))
+	assert.Equal(t, 1, Orientation([]byte("not exif")))
+
+	block := exif(binary.BigEndian, 3)
+	jpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, byte((len(block) + 2) >> 8), byte(len(block) + 2)}, block...)
+	jpeg = append(jpeg, 0xFF, 0xDA, 0, 2)
+	assert.Equal(t, 3, Orientation(jpeg))
+
+	sideways := image.NewGray(image.Rect(0, 0, 4, 2))
+	assert.Equal(t, image.Rect(0, 0, 2, 4), Orient(sideways, 6).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 4, 2), Orient(sideways, 1).Bounds())
+}
+
+func TestPrepareMapsBackToPhoto(t *testing.T) {
+	for _, s := range loadSamples(t) {
+		if s.Quad == nil {
+			continue
+		}
+		img := openSample(t, s.File)
+		prepared, mapping := Prepare(img, Options{Perspective: true, Deskew: true})
+		tolerance := float64(img.Bounds().Dx()) / 50
+
+		width, height := float64(prepared.Bounds().Dx()-1), float64(prepared.Bounds().Dy()-1)
+		for i, corner := range [4][2]float64{{0, 0}, {width, 0}, {width, height}, {0, height}} {
+			x, y := mapping(corner[0], corner[1])
+			assert.InDelta(t, s.Quad[i][0], x, tolerance, "corner %d", i)
+			assert.InDelta(t, s.Quad[i][1], y, tolerance, "corner %d", i)
+		}
+	}
+}
+
+func TestRotationMapsBack(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 300, 200))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(40, 30, 50, 40), image.Black, image.Point{}, draw.Src)
+
+	rotated := imaging.Rotate(img, 10, color.White)
+	dot := image.Rectangle{}
+	for y := 0; y < rotated.Rect.Dy(); y++ {
+		for x := 0; x < rotated.Rect.Dx(); x++ {
+			if rotated.NRGBAAt(x, y).R < 64 {
+				dot = dot.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	back := rotation(img.Bounds(), rotated.Bounds(), 10).Rect(dot)
+	assert.InDelta(t, 45, (back.Min.X+back.Max.X)/2, 2)
+	assert.InDelta(t, 35, (back.Min.Y+back.Max.Y)/2, 2)
+}
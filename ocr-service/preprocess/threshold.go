@@ -0,0 +1,144 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Threshold is how a variant turns the image black and white before Tesseract reads it.
+type Threshold int
+
+const (
+	// NoThreshold leaves the gray levels to Tesseract.
+	NoThreshold Threshold = iota
+	// FixedThreshold cuts at 60% brightness, fine for evenly lit scans.
+	FixedThreshold
+	// OtsuThreshold picks one cutoff for the whole image from its histogram.
+	OtsuThreshold
+	// SauvolaThreshold picks a cutoff for every pixel from its neighbourhood, photos lit
+	// from one side keep the text of their dark half.
+	SauvolaThreshold
+)
+
+// Binarize applies the threshold to a grayscale copy of the image.
+func Binarize(img image.Image, threshold Threshold) *image.Gray {
+	gray := toGray(img)
+	switch threshold {
+	case FixedThreshold:
+		return binarizeAt(gray, 153)
+	case OtsuThreshold:
+		return binarizeAt(gray, Otsu(gray))
+	case SauvolaThreshold:
+		return Sauvola(gray, sauvolaWindow(gray.Bounds()), 0.2)
+	default:
+		return gray
+	}
+}
+
+// toGray returns the image as gray levels whose Pix holds exactly its pixels, row by row.
+func toGray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok && gray.Stride == gray.Rect.Dx() && len(gray.Pix) == gray.Rect.Dx()*gray.Rect.Dy() {
+		return gray
+	}
+	nrgba := imaging.Grayscale(img)
+	gray := image.NewGray(nrgba.Bounds())
+	for i := 0; i < len(gray.Pix); i++ {
+		gray.Pix[i] = nrgba.Pix[i*4]
+	}
+	return gray
+}
+
+// binarizeAt makes pixels above cutoff white and the others black.
+func binarizeAt(gray *image.Gray, cutoff uint8) *image.Gray {
+	result := image.NewGray(gray.Bounds())
+	for i, value := range gray.Pix {
+		if value > cutoff {
+			result.Pix[i] = 255
+		}
+	}
+	return result
+}
+
+// Otsu returns the cutoff that best separates the image into two classes of gray levels.
+func Otsu(gray *image.Gray) uint8 {
+	var histogram [256]int
+	for _, value := range gray.Pix {
+		histogram[value]++
+	}
+
+	total := len(gray.Pix)
+	sum := 0.0
+	for level, count := range histogram {
+		sum += float64(level * count)
+	}
+
+	var best uint8
+	bestVariance := -1.0
+	backgroundSum, backgroundCount := 0.0, 0
+	for level, count := range histogram {
+		backgroundCount += count
+		if backgroundCount == 0 {
+			continue
+		}
+		foregroundCount := total - backgroundCount
+		if foregroundCount == 0 {
+			break
+		}
+		backgroundSum += float64(level * count)
+		backgroundMean := backgroundSum / float64(backgroundCount)
+		foregroundMean := (sum - backgroundSum) / float64(foregroundCount)
+		variance := float64(backgroundCount) * float64(foregroundCount) * (backgroundMean - foregroundMean) * (backgroundMean - foregroundMean)
+		if variance > bestVariance {
+			best, bestVariance = uint8(level), variance
+		}
+	}
+	return best
+}
+
+// sauvolaWindow is about the height of a line of receipt text, a window that small follows
+// the light but not the letters.
+func sauvolaWindow(bounds image.Rectangle) int {
+	return max(15, min(bounds.Dx(), bounds.Dy())/30|1)
+}
+
+// Sauvola binarizes every pixel against the mean and spread of the window around it:
+// t = m * (1 + k * (s/128 - 1)). Sums over integral images keep it linear in the pixels.
+func Sauvola(gray *image.Gray, window int, k float64) *image.Gray {
+	bounds := gray.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	sums := make([]float64, (w+1)*(h+1))
+	squares := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		rowSum, rowSquares := 0.0, 0.0
+		for x := 0; x < w; x++ {
+			value := float64(gray.Pix[y*gray.Stride+x])
+			rowSum += value
+			rowSquares += value * value
+			sums[(y+1)*(w+1)+x+1] = sums[y*(w+1)+x+1] + rowSum
+			squares[(y+1)*(w+1)+x+1] = squares[y*(w+1)+x+1] + rowSquares
+		}
+	}
+	area := func(table []float64, x0, y0, x1, y1 int) float64 {
+		return table[y1*(w+1)+x1] - table[y0*(w+1)+x1] - table[y1*(w+1)+x0] + table[y0*(w+1)+x0]
+	}
+
+	result := image.NewGray(bounds)
+	half := window / 2
+	for y := 0; y < h; y++ {
+		y0, y1 := max(0, y-half), min(h, y+half+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := max(0, x-half), min(w, x+half+1)
+			count := float64((x1 - x0) * (y1 - y0))
+			mean := area(sums, x0, y0, x1, y1) / count
+			deviation := math.Sqrt(math.Max(0, area(squares, x0, y0, x1, y1)/count-mean*mean))
+			cutoff := mean * (1 + k*(deviation/128-1))
+			if float64(gray.Pix[y*gray.Stride+x]) > cutoff {
+				result.SetGray(bounds.Min.X+x, bounds.Min.Y+y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return result
+}